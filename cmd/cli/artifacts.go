@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	framework "github.com/pithomlabs/rea"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// runArtifacts implements the "artifacts" subcommand: prints the stage
+// outputs of an in-flight or finished workflow as JSON
+func runArtifacts(args []string) {
+	fs := flag.NewFlagSet("artifacts", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cli artifacts [flags] <workflow-id>")
+		fs.PrintDefaults()
+	}
+	restateURL := fs.String("restate-url", "http://localhost:8080", "Restate server ingress URL")
+
+	positional := parseInterspersed(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	ingressClient := framework.NewIngressClient(*restateURL, "")
+	getArtifacts := framework.IngressObject[restate.Void, types.TutorialArtifacts](ingressClient, "TutorialWorkflow", "GetArtifacts")
+	artifacts, err := getArtifacts.Call(ctx, positional[0], restate.Void{})
+	if err != nil {
+		log.Fatalf("Failed to fetch artifacts: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(artifacts); err != nil {
+		log.Fatalf("Failed to print artifacts: %v", err)
+	}
+}
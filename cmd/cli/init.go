@@ -0,0 +1,241 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/config"
+)
+
+// presetExtensions maps source file extensions to the preset for their language
+var presetExtensions = map[string]string{
+	".go":   "go",
+	".py":   "python",
+	".js":   "javascript",
+	".jsx":  "javascript",
+	".mjs":  "javascript",
+	".cjs":  "javascript",
+	".ts":   "typescript",
+	".tsx":  "typescript",
+	".java": "java",
+	".rb":   "ruby",
+	".rs":   "rust",
+}
+
+// languageCount is the number of source files of one preset's language
+type languageCount struct {
+	Preset string
+	Files  int
+}
+
+// runInit implements the "init" subcommand: inspects a repository's
+// languages and writes a starter cb2utorial.yaml
+func runInit(args []string) {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cli init [flags] [repo-dir]")
+		flags.PrintDefaults()
+	}
+	force := flags.Bool("force", false, "Overwrite an existing "+config.FileName)
+
+	positional := parseInterspersed(flags, args)
+	if len(positional) > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	repoDir := "."
+	if len(positional) == 1 {
+		repoDir = positional[0]
+	}
+
+	path := filepath.Join(repoDir, config.FileName)
+	if _, err := os.Stat(path); err == nil && !*force {
+		log.Fatalf("%s already exists (use --force to overwrite)", path)
+	}
+
+	languages, err := detectLanguages(repoDir)
+	if err != nil {
+		log.Fatalf("Failed to inspect %s: %v", repoDir, err)
+	}
+
+	content := scaffoldConfig(projectNameFor(repoDir), languages)
+	// The scaffold must pass the same validation as a hand-written file
+	if _, err := config.Parse(path, []byte(content)); err != nil {
+		log.Fatalf("Generated configuration is invalid: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", path, err)
+	}
+
+	log.Printf("Wrote %s", path)
+	for _, language := range languages {
+		log.Printf("  %s: %d files", language.Preset, language.Files)
+	}
+}
+
+// detectLanguages counts source files per preset, most common first.
+// Dependency, build and hidden directories are not inspected.
+func detectLanguages(repoDir string) ([]languageCount, error) {
+	counts := make(map[string]int)
+	err := filepath.WalkDir(repoDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != repoDir && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" || name == "target" || name == "dist" || name == "build") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if preset, ok := presetExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+			counts[preset]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	languages := make([]languageCount, 0, len(counts))
+	for preset, files := range counts {
+		languages = append(languages, languageCount{Preset: preset, Files: files})
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if languages[i].Files != languages[j].Files {
+			return languages[i].Files > languages[j].Files
+		}
+		return languages[i].Preset < languages[j].Preset
+	})
+	return languages, nil
+}
+
+// projectNameFor derives the project name from the repository directory
+func projectNameFor(repoDir string) string {
+	abs, err := filepath.Abs(repoDir)
+	if err != nil {
+		return "Project"
+	}
+	name := filepath.Base(abs)
+	if name == "." || name == "/" {
+		return "Project"
+	}
+	return name
+}
+
+// scaffoldConfig renders a commented cb2utorial.yaml. The dominant language
+// selects the preset; a mixed repository keeps the default preset and
+// includes every detected language.
+func scaffoldConfig(projectName string, languages []languageCount) string {
+	preset := config.DefaultPreset
+	var include []string
+	if len(languages) == 1 {
+		preset = languages[0].Preset
+	} else if len(languages) > 1 {
+		if languages[0].Files >= 4*languages[1].Files {
+			preset = languages[0].Preset
+		} else {
+			for _, language := range languages {
+				include = append(include, config.Presets[language.Preset].Include...)
+			}
+			include = dedupe(include)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# cb2utorial project configuration\n")
+	sb.WriteString("# Schema: run `cli schema` to print it. Command-line flags override these values.\n\n")
+	sb.WriteString(fmt.Sprintf("project: %q\n\n", projectName))
+
+	var detected []string
+	for _, language := range languages {
+		detected = append(detected, fmt.Sprintf("%s (%d files)", language.Preset, language.Files))
+	}
+	if len(detected) > 0 {
+		sb.WriteString("# Detected: " + strings.Join(detected, ", ") + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("preset: %s\n", preset))
+	if len(include) > 0 {
+		sb.WriteString("include:\n")
+		for _, pattern := range include {
+			sb.WriteString(fmt.Sprintf("  - %q\n", pattern))
+		}
+	} else {
+		sb.WriteString("# include: [\"*.go\"]        # replaces the preset's include patterns\n")
+	}
+	sb.WriteString("# exclude: [\"examples/*\"]  # replaces the preset's exclude patterns\n")
+	sb.WriteString(fmt.Sprintf("max_files: %d\n", config.DefaultMaxFiles))
+	sb.WriteString(fmt.Sprintf("chapters: %d\n\n", config.DefaultMaxAbstractions))
+
+	sb.WriteString(fmt.Sprintf("# Persona: %s, or describe the reader in your own words\n", strings.Join(config.PersonaNames(), ", ")))
+	sb.WriteString(fmt.Sprintf("audience: %s\n", config.DefaultPersona))
+	sb.WriteString("language: English\n")
+	sb.WriteString("# Also translate the tutorial, each language into <output dir>/<language>\n")
+	sb.WriteString("# languages: [Japanese, Spanish]\n\n")
+
+	sb.WriteString("# Model per stage; unset stages use default, then LLM_MODEL\n")
+	sb.WriteString("# models:\n")
+	sb.WriteString("#   default: openai/gpt-4o-mini\n")
+	sb.WriteString("#   chapters: openai/gpt-4\n\n")
+
+	sb.WriteString("# Concepts the tutorial must cover\n")
+	sb.WriteString("# pinned_abstractions:\n")
+	sb.WriteString("#   - name: Workflow\n")
+	sb.WriteString("#     description: How a request moves through the pipeline\n")
+	sb.WriteString("#     files: [\"workflow/*\"]\n")
+	sb.WriteString("# Concepts the tutorial must not cover\n")
+	sb.WriteString("# blocked_abstractions: [\"Logging\"]\n\n")
+
+	sb.WriteString("# House style, added to chapter prompts and checked after writing\n")
+	sb.WriteString("# style:\n")
+	sb.WriteString("#   guide_file: docs/STYLE.md\n")
+	sb.WriteString("#   sections: [What It Does, Key Code, Common Pitfalls, Try It Yourself]\n")
+	sb.WriteString("#   max_words: 1500\n")
+	sb.WriteString("#   banned_phrases: [simply, just]\n")
+	sb.WriteString("#   on_violation: regenerate  # or flag\n\n")
+
+	sb.WriteString("# Check that functions and types chapters mention exist: report, correct or off\n")
+	sb.WriteString("# verification: correct\n")
+	sb.WriteString("# Parse the go code blocks chapters write: report, regenerate or off\n")
+	sb.WriteString("# go_snippets: regenerate\n\n")
+
+	sb.WriteString("# Second LLM pass that scores each chapter and revises it below the threshold\n")
+	sb.WriteString("# critic:\n")
+	sb.WriteString("#   enabled: true\n")
+	sb.WriteString("#   threshold: 3.5\n")
+	sb.WriteString("#   max_revisions: 1\n\n")
+
+	sb.WriteString("# Prompt template overrides, relative to this file (start with: cli prompts export prompts)\n")
+	sb.WriteString("# prompts_dir: prompts\n\n")
+
+	sb.WriteString("output:\n")
+	sb.WriteString(fmt.Sprintf("  dir: %s\n", config.DefaultOutputDir))
+	sb.WriteString(fmt.Sprintf("  format: %s  # or %s\n", config.FormatMarkdown, config.FormatMarkdownIndex))
+	sb.WriteString("  # Link code citations to the source\n")
+	sb.WriteString("  # source_url: https://github.com/org/repo/blob/{commit}/{path}#L{start}-L{end}\n\n")
+
+	sb.WriteString("# Shell commands run by the CLI in this directory (needs --allow-hooks)\n")
+	sb.WriteString("# hooks:\n")
+	sb.WriteString("#   pre_generate: [\"go build ./...\"]\n")
+	sb.WriteString("#   post_generate: [\"ls $CB2UTORIAL_OUTPUT_DIR\"]\n")
+	return sb.String()
+}
+
+// dedupe removes repeated values, keeping the first occurrence
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
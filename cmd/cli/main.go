@@ -1,27 +1,82 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/prompts"
 	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	framework "github.com/pithomlabs/rea"
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "review":
+			runReview(os.Args[2:])
+			return
+		case "resume":
+			runResume(os.Args[2:])
+			return
+		case "artifacts":
+			runArtifacts(os.Args[2:])
+			return
+		case "init":
+			runInit(os.Args[2:])
+			return
+		case "prompts":
+			runPrompts(os.Args[2:])
+			return
+		case "schema":
+			os.Stdout.Write(config.SchemaJSON)
+			return
+		}
+	}
+
 	// Parse command-line flags
-	repoPath := flag.String("repo", "", "Path to local repository (required)")
-	outputDir := flag.String("output", "./tutorial", "Output directory for tutorial files")
+	repoPath := flag.String("repo", "", "Path to local repository, .zip/.tar.gz archive, module@version, or - for an archive on stdin (required)")
+	outputDir := flag.String("output", "", "Output directory for tutorial files (default "+config.DefaultOutputDir+")")
+	configPath := flag.String("config", "", "Project configuration file (default <repo>/"+config.FileName+")")
+	allowHooks := flag.Bool("allow-hooks", false, "Run the hooks of a "+config.FileName+" found in the repository (hooks of --config always run)")
 	projectName := flag.String("project", "", "Project name (optional, derived from repo if empty)")
-	maxFiles := flag.Int("max-files", 100, "Maximum number of files to process")
+	gitRef := flag.String("ref", "", "Git branch, tag or commit to read instead of the working tree")
+	maxFiles := flag.Int("max-files", 0, "Maximum number of files to process (default MAX_FILES or 100)")
+	preset := flag.String("preset", "", "File selection preset: "+strings.Join(config.PresetNames(), ", ")+" (default \"default\")")
+	include := flag.String("include", "", "Comma-separated include patterns (default INCLUDE_PATTERNS or the preset's)")
+	exclude := flag.String("exclude", "", "Comma-separated exclude patterns (default EXCLUDE_PATTERNS or the preset's)")
+	maxFileSize := flag.Int64("max-file-size", 0, "Skip files larger than this many bytes (default MAX_FILE_SIZE or 1MB)")
+	maxAbstractions := flag.Int("max-abstractions", 0, "Maximum number of abstractions, one chapter each (default MAX_ABSTRACTIONS or 10)")
+	audience := flag.String("audience", "", "Reader persona: "+strings.Join(config.PersonaNames(), ", ")+", or free text (default \""+config.DefaultPersona+"\")")
+	language := flag.String("language", "", "Language to write the tutorial in (default "+config.DefaultLanguage+")")
+	languages := flag.String("languages", "", "Comma-separated additional languages, each written to <output>/<language>")
+	sourceURL := flag.String("source-url", "", "Link template for code citations with {commit}, {path}, {start}, {end}")
+	verification := flag.String("verification", "", "Symbol check after each chapter: report, correct or off (default report)")
+	goSnippets := flag.String("go-snippets", "", "Go code block check after each chapter: report, regenerate or off (default report)")
+	critic := flag.Bool("critic", false, "Have a second LLM pass score each chapter and revise it below the threshold")
+	criticThreshold := flag.Float64("critic-threshold", 0, "Minimum average critic score, 1-5 (implies --critic; default 3.5)")
+	maxRevisions := flag.Int("max-revisions", 0, "Critic revisions per chapter (implies --critic; default 1)")
+	styleGuide := flag.String("style-guide", "", "Markdown style guide added to every chapter prompt (replaces style.guide)")
+	promptsDir := flag.String("prompts-dir", "", "Directory of prompt template overrides (<name>.tmpl)")
 	restateURL := flag.String("restate-url", "http://localhost:8080", "Restate server ingress URL")
+	review := flag.Bool("review", false, "Pause for human review of abstractions before writing chapters")
+	reviewTimeout := flag.Int("review-timeout", 0, "Minutes to wait for review before failing (default 24h)")
+	force := flag.Bool("force", false, "Regenerate everything, ignoring the previous run's manifest")
+	threshold := flag.Float64("regenerate-threshold", 0, "Fraction of changed files that triggers re-analysis (default 0.3)")
+	var redactRules stringList
+	flag.Var(&redactRules, "redact", "Custom secret pattern to redact: NAME=REGEX (repeatable)")
+	id := flag.String("id", "", "Workflow ID (optional, derived from repo, commit and config if empty)")
 
 	flag.Parse()
 
@@ -31,8 +86,20 @@ func main() {
 	}
 
 	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
+	loadEnv()
+
+	// The service reads archives by path, so stdin is spooled to a temp file
+	stdinArchive := ""
+	if *repoPath == "-" {
+		path, err := spoolStdin()
+		if err != nil {
+			log.Fatalf("Failed to read archive from stdin: %v", err)
+		}
+		stdinArchive = path
+		*repoPath = path
+		if *projectName == "" {
+			*projectName = "Project"
+		}
 	}
 
 	// Validate environment
@@ -40,74 +107,316 @@ func main() {
 		log.Fatal("OPENROUTER_API_KEY environment variable is required")
 	}
 
+	projectFile, err := loadProjectConfig(*configPath, *repoPath)
+	if err != nil {
+		log.Fatalf("Invalid project configuration: %v", err)
+	}
+	if projectFile != nil {
+		log.Printf("Using project configuration %s", config.FileName)
+	}
+
+	rules, err := parseRedactionRules(redactRules)
+	if err != nil {
+		log.Fatalf("Invalid --redact rule: %v", err)
+	}
+
 	// Create workflow input
 	input := types.TutorialWorkflowInput{
 		LocalRepoPath: *repoPath,
 		OutputDir:     *outputDir,
 		MaxFiles:      *maxFiles,
 		ProjectName:   *projectName,
+		GitRef:        *gitRef,
+
+		Preset:          *preset,
+		IncludePatterns: config.SplitPatterns(*include),
+		ExcludePatterns: config.SplitPatterns(*exclude),
+		MaxFileSize:     *maxFileSize,
+		MaxAbstractions: *maxAbstractions,
+
+		RedactionRules: rules,
+		Audience:       *audience,
+		Language:       *language,
+		Languages:      config.SplitPatterns(*languages),
+		PromptsDir:     *promptsDir,
+		SourceURL:      *sourceURL,
+		Verification:   *verification,
+		GoSnippets:     *goSnippets,
+
+		ReviewAbstractions:   *review,
+		ReviewTimeoutMinutes: *reviewTimeout,
+
+		ForceRegenerate:     *force,
+		RegenerateThreshold: *threshold,
 	}
 
-	log.Printf("Generating tutorial for: %s", *repoPath)
-	log.Printf("Output directory: %s", *outputDir)
-	log.Printf("Max files: %d", *maxFiles)
+	// The server reads the prompt overrides, so send an absolute path
+	if input.PromptsDir != "" {
+		absPrompts, err := filepath.Abs(input.PromptsDir)
+		if err != nil {
+			log.Fatalf("Invalid --prompts-dir: %v", err)
+		}
+		input.PromptsDir = absPrompts
+	}
 
-	// Generate workflow ID from repo path and timestamp
-	workflowID := fmt.Sprintf("tutorial-%d", time.Now().Unix())
+	// Flags win over cb2utorial.yaml, then environment variables, then the
+	// preset. The resolved values are sent with the input (and hashed into
+	// the ID).
+	config.ApplyFile(&input, projectFile)
+	if *styleGuide != "" {
+		guide, err := config.ReadStyleGuideFile(*styleGuide, "")
+		if err != nil {
+			log.Fatalf("Invalid --style-guide: %v", err)
+		}
+		if input.Style == nil {
+			input.Style = &types.StyleGuide{}
+		}
+		input.Style.Guide = guide
+	}
+	if *critic || *criticThreshold != 0 || *maxRevisions != 0 {
+		if input.Critic == nil {
+			input.Critic = &types.CriticSettings{}
+		}
+		if *criticThreshold != 0 {
+			input.Critic.Threshold = *criticThreshold
+		}
+		if *maxRevisions != 0 {
+			input.Critic.MaxRevisions = *maxRevisions
+		}
+	}
+	if err := config.ApplyEnv(&input); err != nil {
+		log.Fatalf("Invalid environment: %v", err)
+	}
+	if err := config.ApplyDefaults(&input); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := config.Validate(input); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 
-	// Invoke workflow via Restate HTTP ingress
-	// Endpoint format: POST /{WorkflowName}/{workflowId}/Run (matches Go method name)
-	url := fmt.Sprintf("%s/TutorialWorkflow/%s/Run", *restateURL, workflowID)
+	log.Printf("Generating tutorial for: %s", *repoPath)
+	if *gitRef != "" {
+		log.Printf("Git ref: %s", *gitRef)
+	}
+	log.Printf("Output directory: %s (%s)", input.OutputDir, input.OutputFormat)
+	log.Printf("Max files: %d, max file size: %d bytes, max abstractions: %d", input.MaxFiles, input.MaxFileSize, input.MaxAbstractions)
+	log.Printf("Audience: %s", config.ResolvePersona(input.Audience).Name)
+	log.Printf("Language: %s", config.LanguageOrDefault(input.Language))
+	if len(input.Languages) > 0 {
+		log.Printf("Translations: %s", strings.Join(input.Languages, ", "))
+	}
+	log.Printf("Include: %s", strings.Join(input.IncludePatterns, ","))
+	log.Printf("Exclude: %s", strings.Join(input.ExcludePatterns, ","))
 
-	// Serialize input
-	inputJSON, err := json.Marshal(input)
-	if err != nil {
-		log.Fatalf("Failed to serialize input: %v", err)
+	// Hooks run on the user's machine only; the server never executes them.
+	// A config discovered in the repository may come from an untrusted clone,
+	// so its hooks need --allow-hooks.
+	var hooks config.Hooks
+	if projectFile != nil {
+		hooks = projectFile.Hooks
+		if *configPath == "" && !*allowHooks {
+			skipHooks(hooks)
+			hooks = config.Hooks{}
+		}
+	}
+	if err := runHooks("pre_generate", hooks.PreGenerate, hookDir(*repoPath), nil); err != nil {
+		log.Fatalf("Aborting: %v", err)
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(inputJSON))
-	if err != nil {
-		log.Fatalf("Failed to create request: %v", err)
+	// Derive a deterministic workflow ID unless one was given explicitly
+	workflowID := *id
+	if workflowID == "" {
+		var err error
+		workflowID, err = deriveWorkflowID(input)
+		if err != nil {
+			log.Fatalf("Failed to derive workflow ID: %v", err)
+		}
 	}
+	log.Printf("Workflow ID: %s", workflowID)
 
-	req.Header.Set("Content-Type", "application/json")
+	// Invoke workflow via Restate ingress using the rea framework client.
+	// Submitting an existing workflow ID is a no-op, and attaching returns the
+	// output of the existing run instead of regenerating it.
+	ingressClient := framework.NewIngressClient(*restateURL, "")
+	tutorialClient := framework.IngressWorkflow[types.TutorialWorkflowInput, types.TutorialWorkflowOutput](
+		ingressClient, "TutorialWorkflow", "Run",
+	)
 
-	// Send request
-	log.Printf("Invoking workflow at: %s", url)
-	log.Printf("Payload: %s", string(inputJSON))
-	log.Println("Invoking TutorialWorkflow...")
-	client := &http.Client{
-		Timeout: 30 * time.Minute, // Long timeout for LLM calls
+	// A run paused for review may wait up to the review timeout, so the CLI
+	// waits that long on top of the generation time
+	attachTimeout := 30 * time.Minute // Long timeout for LLM calls
+	if *review {
+		attachTimeout += config.ReviewTimeout(*reviewTimeout)
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), attachTimeout)
+	defer cancel()
 
-	resp, err := client.Do(req)
+	log.Println("Submitting TutorialWorkflow...")
+	invocationID, err := tutorialClient.Submit(ctx, workflowID, input)
 	if err != nil {
-		log.Fatalf("Failed to invoke workflow: %v", err)
+		log.Fatalf("Failed to submit workflow: %v", err)
 	}
-	defer resp.Body.Close()
+	log.Printf("Invocation ID: %s", invocationID)
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
+	if *review {
+		log.Printf("Review required: run `cli review show %s` once abstractions are ready", workflowID)
+	}
+	log.Println("Waiting for TutorialWorkflow to complete...")
+	result, err := tutorialClient.Attach(ctx, workflowID)
 	if err != nil {
-		log.Fatalf("Failed to read response: %v", err)
+		log.Printf("Completed stages are saved; retry with `cli resume %s`", workflowID)
+		log.Fatalf("Workflow failed: %v", err)
+	}
+	printResult(result)
+
+	// Hooks run in the repository, so pass an absolute output path
+	absOutput, err := filepath.Abs(input.OutputDir)
+	if err != nil {
+		absOutput = input.OutputDir
+	}
+	outputEnv := []string{"CB2UTORIAL_OUTPUT_DIR=" + absOutput}
+	if err := runHooks("post_generate", hooks.PostGenerate, hookDir(*repoPath), outputEnv); err != nil {
+		log.Fatalf("Tutorial was generated, but %v", err)
 	}
 
-	// Check status
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		log.Fatalf("Workflow failed with status %d: %s", resp.StatusCode, string(body))
+	// Kept on failure so the run can be resumed
+	if stdinArchive != "" {
+		os.Remove(stdinArchive)
+	}
+}
+
+// loadEnv loads the .env file if present
+func loadEnv() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
 	}
+}
 
-	// Parse result
-	var result types.WriteMarkdownFilesOutput
-	if err := json.Unmarshal(body, &result); err != nil {
-		log.Printf("Warning: Could not parse result: %v", err)
-		log.Printf("Response: %s", string(body))
-	} else {
-		log.Println("\n✅ Tutorial generated successfully!")
-		log.Printf("Files written (%d):", len(result.FilesWritten))
-		for _, file := range result.FilesWritten {
+// printResult prints the summary of a finished workflow
+func printResult(result types.TutorialWorkflowOutput) {
+	log.Println("\n✅ Tutorial generated successfully!")
+	if result.Commit != "" {
+		log.Printf("Commit: %s", result.Commit)
+	}
+	log.Printf("Files written (%d):", len(result.FilesWritten))
+	for _, file := range result.FilesWritten {
+		log.Printf("  - %s", file)
+	}
+	for _, language := range sortedKeys(result.Variants) {
+		log.Printf("%s files written (%d):", language, len(result.Variants[language]))
+		for _, file := range result.Variants[language] {
 			log.Printf("  - %s", file)
 		}
 	}
+	if report := result.Report; report.FilesRead > 0 {
+		log.Printf("Files read: %d", report.FilesRead)
+		if len(report.SkipTotals) > 0 {
+			log.Printf("Files skipped: %s", utils.FormatSkipTotals(report.SkipTotals))
+		}
+		// Pattern exclusions are expected; list only the surprising skips
+		for _, skipped := range report.Skipped {
+			if skipped.Reason != utils.SkipExcluded {
+				log.Printf("  - %s (%s)", skipped.Path, skipped.Reason)
+			}
+		}
+	}
+	if len(result.Report.Prompts) > 0 {
+		versions := make([]string, len(result.Report.Prompts))
+		for i, p := range result.Report.Prompts {
+			versions[i] = p.Name + "@" + p.Version
+			if p.Source != prompts.SourceEmbedded {
+				versions[i] += " (" + p.Source + ")"
+			}
+		}
+		log.Printf("Prompts: %s", strings.Join(versions, ", "))
+	}
+	if len(result.Report.Style) > 0 {
+		log.Printf("Style guide violations (%d):", len(result.Report.Style))
+		for _, issue := range result.Report.Style {
+			log.Printf("  - chapter %d (%s): %s", issue.ChapterNumber, issue.Title, issue.Detail)
+		}
+	}
+	if len(result.Report.Citations) > 0 {
+		log.Printf("Code citations that did not match the source (%d):", len(result.Report.Citations))
+		for _, issue := range result.Report.Citations {
+			if issue.Status == utils.CitationCorrected {
+				log.Printf("  - chapter %d (%s): %s corrected to %s:%d-%d", issue.ChapterNumber, issue.Title, issue.Label, issue.Path, issue.StartLine, issue.EndLine)
+			} else {
+				log.Printf("  - chapter %d (%s): %s not found in the source", issue.ChapterNumber, issue.Title, issue.Label)
+			}
+		}
+	}
+	if len(result.Report.Unverified) > 0 {
+		log.Printf("Symbols not found in the repository (%d):", len(result.Report.Unverified))
+		for _, issue := range result.Report.Unverified {
+			hint := ""
+			if len(issue.Suggestions) > 0 {
+				hint = "; did you mean " + strings.Join(issue.Suggestions, ", ") + "?"
+			}
+			if issue.Uncorrected {
+				hint += " (correction failed: it kept dropping code blocks)"
+			}
+			log.Printf("  - chapter %d (%s): %s in %s%s", issue.ChapterNumber, issue.Title, issue.Name, issue.Where, hint)
+		}
+	}
+	if len(result.Report.Snippets) > 0 {
+		log.Printf("Go code blocks that do not parse (%d):", len(result.Report.Snippets))
+		for _, issue := range result.Report.Snippets {
+			log.Printf("  - chapter %d (%s): block %d, line %d: %s", issue.ChapterNumber, issue.Title, issue.Block, issue.Line, issue.Error)
+		}
+	}
+	if len(result.Report.Untranslated) > 0 {
+		log.Printf("Chapters left untranslated (%d):", len(result.Report.Untranslated))
+		for _, issue := range result.Report.Untranslated {
+			log.Printf("  - %s chapter %d (%s): translations kept dropping code blocks", issue.Language, issue.ChapterNumber, issue.Title)
+		}
+	}
+	if len(result.Report.Scores) > 0 {
+		log.Printf("Critic scores (accuracy/clarity/coverage/novelty):")
+		for _, score := range result.Report.Scores {
+			final := score.Critiques[len(score.Critiques)-1]
+			status := ""
+			if !score.Passed {
+				status = " - below threshold"
+			}
+			if score.RevisionFailed {
+				status += " - revision failed (it kept dropping code blocks)"
+			}
+			log.Printf("  - chapter %d (%s): %.2f (%d/%d/%d/%d), %d revisions%s", score.ChapterNumber, score.Title,
+				final.Score, final.Accuracy, final.Clarity, final.Coverage, final.Novelty, score.Revisions, status)
+		}
+	}
+	if len(result.Report.Redactions) > 0 {
+		log.Printf("Secrets redacted (%d):", len(result.Report.Redactions))
+		for _, r := range result.Report.Redactions {
+			log.Printf("  - %s:%d (%s)", r.Path, r.Line, r.Rule)
+		}
+	}
+}
+
+// sortedKeys returns the languages of a variants map in a stable order
+func sortedKeys(variants map[string][]string) []string {
+	keys := make([]string, 0, len(variants))
+	for key := range variants {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseRedactionRules parses NAME=REGEX flags, compiling each pattern so
+// mistakes surface before the workflow is submitted
+func parseRedactionRules(values []string) ([]types.RedactionRule, error) {
+	var rules []types.RedactionRule
+	for _, value := range values {
+		name, pattern, ok := strings.Cut(value, "=")
+		if !ok || name == "" || pattern == "" {
+			return nil, fmt.Errorf("expected NAME=REGEX, got %q", value)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		rules = append(rules, types.RedactionRule{Name: name, Pattern: pattern})
+	}
+	return rules, nil
 }
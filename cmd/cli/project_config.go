@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/pithomlabs/cb2utorial/config"
+)
+
+// loadProjectConfig loads --config if given, otherwise cb2utorial.yaml from
+// the repository root. Returns nil when there is no config file.
+func loadProjectConfig(configPath string, repoPath string) (*config.File, error) {
+	if configPath != "" {
+		return config.Load(configPath)
+	}
+	if info, err := os.Stat(repoPath); err != nil || !info.IsDir() {
+		return nil, nil // Archives and module specs carry no config file
+	}
+	return config.Discover(repoPath)
+}
+
+// hookDir is where hooks run: the repository if it is a directory
+func hookDir(repoPath string) string {
+	if info, err := os.Stat(repoPath); err == nil && info.IsDir() {
+		return repoPath
+	}
+	return "."
+}
+
+// skipHooks logs the hooks of a discovered config that are not run
+func skipHooks(hooks config.Hooks) {
+	for _, command := range hooks.PreGenerate {
+		log.Printf("Skipping pre_generate hook (pass --allow-hooks to run it): %s", command)
+	}
+	for _, command := range hooks.PostGenerate {
+		log.Printf("Skipping post_generate hook (pass --allow-hooks to run it): %s", command)
+	}
+}
+
+// runHooks runs each command with sh -c, stopping at the first failure
+func runHooks(stage string, commands []string, dir string, env []string) error {
+	for _, command := range commands {
+		log.Printf("Running %s hook: %s", stage, command)
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", stage, command, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/prompts"
+)
+
+// runPrompts implements the "prompts" subcommand: "lint" renders every
+// template against sample data, "export" writes the built-in templates to a
+// directory as a starting point for overrides
+func runPrompts(args []string) {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: cli prompts lint [--dir DIR]")
+		fmt.Fprintln(os.Stderr, "       cli prompts export [--force] <dir>")
+	}
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "lint":
+		runPromptsLint(args[1:])
+	case "export":
+		runPromptsExport(args[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// runPromptsLint renders each template, exiting non-zero if any fails
+func runPromptsLint(args []string) {
+	flags := flag.NewFlagSet("prompts lint", flag.ExitOnError)
+	dir := flags.String("dir", "", "Template override directory (default prompts_dir from ./"+config.FileName+")")
+	configPath := flags.String("config", "", "Project configuration file to read prompts_dir from")
+	flags.Parse(args)
+
+	if *dir == "" {
+		projectFile, err := loadProjectConfig(*configPath, ".")
+		if err != nil {
+			log.Fatalf("Failed to load project configuration: %v", err)
+		}
+		if projectFile != nil {
+			*dir = projectFile.PromptsDir
+		}
+	}
+
+	failures := lintPrompts(*dir)
+	if failures > 0 {
+		log.Fatalf("%d prompt template(s) failed", failures)
+	}
+}
+
+// lintPrompts prints one line per template and returns the failure count.
+// Files in dir that match no template are failures too, since they would be
+// silently ignored.
+func lintPrompts(dir string) int {
+	failures := 0
+	for _, name := range prompts.Names {
+		t, err := prompts.Load(dir, name)
+		if err == nil {
+			err = t.Lint()
+		}
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", name, err)
+			failures++
+			continue
+		}
+		fmt.Printf("ok   %s@%s (%s, %s)\n", t.Name, t.Version, t.Source, t.Digest)
+	}
+
+	if dir == "" {
+		return failures
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Printf("FAIL %s: %v\n", dir, err)
+		return failures + 1
+	}
+	known := make(map[string]bool, len(prompts.Names))
+	for _, name := range prompts.Names {
+		known[name+prompts.Extension] = true
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), prompts.Extension) && !known[entry.Name()] {
+			fmt.Printf("FAIL %s: not a known template (expected one of %s)\n", filepath.Join(dir, entry.Name()), strings.Join(prompts.Names, ", "))
+			failures++
+		}
+	}
+	return failures
+}
+
+// runPromptsExport writes the embedded templates to a directory
+func runPromptsExport(args []string) {
+	flags := flag.NewFlagSet("prompts export", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: cli prompts export [--force] <dir>")
+		flags.PrintDefaults()
+	}
+	force := flags.Bool("force", false, "Overwrite existing templates")
+
+	positional := parseInterspersed(flags, args)
+	if len(positional) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	dir := positional[0]
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", dir, err)
+	}
+	names := append([]string(nil), prompts.Names...)
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name+prompts.Extension)
+		if _, err := os.Stat(path); err == nil && !*force {
+			log.Fatalf("%s already exists (use --force to overwrite)", path)
+		}
+		text, err := prompts.Embedded(name)
+		if err != nil {
+			log.Fatalf("Failed to read template %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, text, 0644); err != nil {
+			log.Fatalf("Failed to write %s: %v", path, err)
+		}
+		log.Printf("Wrote %s", path)
+	}
+}
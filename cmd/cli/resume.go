@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	framework "github.com/pithomlabs/rea"
+	restate "github.com/restatedev/sdk-go"
+)
+
+const resumeUsage = `Usage: cli resume [flags] <run-id>
+
+Starts a new workflow seeded from the artifact of a previous run, skipping
+stages that already completed. Without --from the run continues at the first
+incomplete stage; chapters written before a failure are kept.
+
+Stages: files, abstractions, relationships, order, chapters, write
+
+Flags:
+`
+
+// runResume implements the "resume" subcommand
+func runResume(args []string) {
+	fs := flag.NewFlagSet("resume", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, resumeUsage)
+		fs.PrintDefaults()
+	}
+	from := fs.String("from", "", "Stage to restart from (default: first incomplete stage)")
+	outputDir := fs.String("output", "", "Output directory (default: same as the original run)")
+	restateURL := fs.String("restate-url", "http://localhost:8080", "Restate server ingress URL")
+	id := fs.String("id", "", "Workflow ID for the resumed run (optional, derived if empty)")
+
+	positional := parseInterspersed(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	runID := positional[0]
+
+	if *from != "" && !isStage(*from) {
+		log.Fatalf("Unknown stage %q (expected one of %s)", *from, strings.Join(types.Stages, ", "))
+	}
+
+	ingressClient := framework.NewIngressClient(*restateURL, "")
+
+	// The artifact lives on the server; ask the previous run for its progress.
+	// The server loads and validates the artifact itself when the run starts.
+	lookupCtx, lookupCancel := context.WithTimeout(context.Background(), time.Minute)
+	defer lookupCancel()
+	getArtifacts := framework.IngressObject[restate.Void, types.TutorialArtifacts](ingressClient, "TutorialWorkflow", "GetArtifacts")
+	artifacts, err := getArtifacts.Call(lookupCtx, runID, restate.Void{})
+	if err != nil {
+		log.Fatalf("Failed to fetch artifacts of run %s: %v", runID, err)
+	}
+	if len(artifacts.CompletedStages) == 0 && artifacts.Revision == 0 {
+		log.Printf("Run %s has no progress in workflow state; the server will load its run artifact", runID)
+	} else {
+		log.Printf("Run %s completed stages: %v", runID, artifacts.CompletedStages)
+		if n := len(artifacts.Chapters); n > 0 && !slices.Contains(artifacts.CompletedStages, types.StageChapters) {
+			log.Printf("Run %s wrote %d of %d chapters", runID, n, len(artifacts.ChapterOrder))
+		}
+	}
+
+	input := types.TutorialWorkflowInput{
+		ResumeRunID: runID,
+		ResumeFrom:  *from,
+		OutputDir:   *outputDir,
+	}
+
+	// Same artifact revision + stage → same workflow ID, so repeats attach
+	workflowID := *id
+	if workflowID == "" {
+		hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d", runID, *from, *outputDir, artifacts.Revision)))
+		workflowID = "resume-" + hex.EncodeToString(hash[:])[:16]
+	}
+	log.Printf("Workflow ID: %s", workflowID)
+
+	tutorialClient := framework.IngressWorkflow[types.TutorialWorkflowInput, types.TutorialWorkflowOutput](
+		ingressClient, "TutorialWorkflow", "Run",
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute) // Long timeout for LLM calls
+	defer cancel()
+
+	if _, err := tutorialClient.Submit(ctx, workflowID, input); err != nil {
+		log.Fatalf("Failed to submit workflow: %v", err)
+	}
+
+	log.Println("Waiting for resumed TutorialWorkflow to complete...")
+	result, err := tutorialClient.Attach(ctx, workflowID)
+	if err != nil {
+		log.Fatalf("Workflow failed: %v", err)
+	}
+	printResult(result)
+}
+
+// isStage reports whether name is a known pipeline stage
+func isStage(name string) bool {
+	for _, stage := range types.Stages {
+		if stage == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	framework "github.com/pithomlabs/rea"
+	restate "github.com/restatedev/sdk-go"
+)
+
+const reviewUsage = `Usage: cli review <command> [flags] <workflow-id>
+
+Commands:
+  show      Print the abstractions and chapter order awaiting review
+  approve   Approve the proposal as-is
+  reject    Reject the proposal and fail the workflow (--reason)
+  edit      Apply edits and continue:
+              --rename 2="New Name"       rename abstraction 2
+              --describe 2="New text"     replace description of abstraction 2
+              --drop 3                    drop abstraction 3
+              --merge 1,4="Merged Name"   merge 4 into 1 (name optional)
+              --order 0,2,1               set chapter order (all remaining indices)
+`
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ", ") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// runReview implements the "review" subcommands
+func runReview(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, reviewUsage)
+		os.Exit(2)
+	}
+	command := args[0]
+
+	fs := flag.NewFlagSet("review "+command, flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, reviewUsage) }
+	restateURL := fs.String("restate-url", "http://localhost:8080", "Restate server ingress URL")
+	reason := fs.String("reason", "", "Reason for rejection")
+	var renames, describes, drops, merges stringList
+	fs.Var(&renames, "rename", "Rename an abstraction: INDEX=NAME (repeatable)")
+	fs.Var(&describes, "describe", "Replace a description: INDEX=TEXT (repeatable)")
+	fs.Var(&drops, "drop", "Drop an abstraction: INDEX (repeatable)")
+	fs.Var(&merges, "merge", "Merge abstractions: I,J[,K...][=NAME] (repeatable)")
+	order := fs.String("order", "", "New chapter order: comma-separated indices")
+
+	positional := parseInterspersed(fs, args[1:])
+	if len(positional) != 1 {
+		fmt.Fprint(os.Stderr, reviewUsage)
+		os.Exit(2)
+	}
+	workflowID := positional[0]
+
+	ingressClient := framework.NewIngressClient(*restateURL, "")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if command == "show" {
+		getReview := framework.IngressObject[restate.Void, types.ReviewProposal](ingressClient, "TutorialWorkflow", "GetReview")
+		proposal, err := getReview.Call(ctx, workflowID, restate.Void{})
+		if err != nil {
+			log.Fatalf("Failed to fetch review: %v", err)
+		}
+		printProposal(proposal)
+		return
+	}
+
+	decision := types.ReviewDecision{Reason: *reason}
+	switch command {
+	case "approve":
+		decision.Action = types.ReviewActionApprove
+	case "reject":
+		decision.Action = types.ReviewActionReject
+	case "edit":
+		edits, err := buildEdits(renames, describes, drops, merges, *order)
+		if err != nil {
+			log.Fatalf("Invalid edit: %v", err)
+		}
+		if len(edits) == 0 {
+			log.Fatal("edit requires at least one of --rename, --describe, --drop, --merge, --order")
+		}
+		decision.Action = types.ReviewActionEdit
+		decision.Edits = edits
+	default:
+		fmt.Fprint(os.Stderr, reviewUsage)
+		os.Exit(2)
+	}
+
+	submitReview := framework.IngressObject[types.ReviewDecision, restate.Void](ingressClient, "TutorialWorkflow", "SubmitReview")
+	if _, err := submitReview.Call(ctx, workflowID, decision); err != nil {
+		log.Fatalf("Failed to submit review: %v", err)
+	}
+	log.Printf("✅ Review submitted (%s) for workflow %s", decision.Action, workflowID)
+}
+
+// parseInterspersed parses flags that may appear before or after positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// buildEdits converts CLI flags into review edits
+func buildEdits(renames, describes, drops, merges stringList, order string) ([]types.ReviewEdit, error) {
+	var edits []types.ReviewEdit
+
+	for _, r := range renames {
+		idx, name, err := splitIndexValue(r)
+		if err != nil {
+			return nil, fmt.Errorf("--rename %q: %w", r, err)
+		}
+		edits = append(edits, types.ReviewEdit{Op: types.ReviewEditRename, Index: idx, Name: name})
+	}
+
+	for _, d := range describes {
+		idx, text, err := splitIndexValue(d)
+		if err != nil {
+			return nil, fmt.Errorf("--describe %q: %w", d, err)
+		}
+		edits = append(edits, types.ReviewEdit{Op: types.ReviewEditRename, Index: idx, Description: text})
+	}
+
+	for _, m := range merges {
+		list, name, _ := strings.Cut(m, "=")
+		indices, err := parseIndexList(list)
+		if err != nil {
+			return nil, fmt.Errorf("--merge %q: %w", m, err)
+		}
+		edits = append(edits, types.ReviewEdit{Op: types.ReviewEditMerge, Indices: indices, Name: strings.TrimSpace(name)})
+	}
+
+	for _, d := range drops {
+		idx, err := strconv.Atoi(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("--drop %q: %w", d, err)
+		}
+		edits = append(edits, types.ReviewEdit{Op: types.ReviewEditDrop, Index: idx})
+	}
+
+	// Reorder last so it sees the final set of abstractions
+	if order != "" {
+		indices, err := parseIndexList(order)
+		if err != nil {
+			return nil, fmt.Errorf("--order %q: %w", order, err)
+		}
+		edits = append(edits, types.ReviewEdit{Op: types.ReviewEditReorder, Indices: indices})
+	}
+
+	return edits, nil
+}
+
+// splitIndexValue parses "INDEX=VALUE"
+func splitIndexValue(s string) (int, string, error) {
+	idxStr, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(value) == "" {
+		return 0, "", fmt.Errorf("expected INDEX=VALUE")
+	}
+	idx, err := strconv.Atoi(strings.TrimSpace(idxStr))
+	if err != nil {
+		return 0, "", err
+	}
+	return idx, strings.TrimSpace(value), nil
+}
+
+// parseIndexList parses "0,2,1"
+func parseIndexList(s string) ([]int, error) {
+	var indices []int
+	for _, part := range strings.Split(s, ",") {
+		idx, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		indices = append(indices, idx)
+	}
+	return indices, nil
+}
+
+// printProposal renders a review proposal for the terminal
+func printProposal(proposal types.ReviewProposal) {
+	if proposal.Status == "" {
+		fmt.Println("No review has been requested for this workflow (yet).")
+		return
+	}
+
+	fmt.Printf("Status: %s\n\n", proposal.Status)
+	fmt.Println("Abstractions:")
+	for _, abs := range proposal.Abstractions {
+		var files []string
+		for _, ref := range abs.Files {
+			files = append(files, utils.DescribeFileRef(ref))
+		}
+		fmt.Printf("  [%d] %s (files: %s)\n      %s\n", abs.Index, abs.Name, strings.Join(files, ", "), abs.Description)
+	}
+
+	fmt.Println("\nChapter order:")
+	for i, idx := range proposal.ChapterOrder {
+		if idx >= 0 && idx < len(proposal.Abstractions) {
+			fmt.Printf("  %d. [%d] %s\n", i+1, idx, proposal.Abstractions[idx].Name)
+		}
+	}
+
+	if len(proposal.Relationships.Details) > 0 {
+		fmt.Println("\nRelationships:")
+		for _, rel := range proposal.Relationships.Details {
+			fmt.Printf("  %d → %d: %s\n", rel.FromIndex, rel.ToIndex, rel.Label)
+		}
+	}
+}
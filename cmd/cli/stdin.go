@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// spoolStdin copies an archive piped to stdin into the temp directory so the
+// FileReader service can open it by path. The file is named after its content
+// hash, so piping the same archive again derives the same workflow ID.
+func spoolStdin() (string, error) {
+	reader := bufio.NewReader(os.Stdin)
+
+	// Sniff the format; the service picks the reader by extension
+	magic, err := reader.Peek(4)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	var ext string
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		ext = ".zip"
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		ext = ".tar.gz"
+	default:
+		return "", fmt.Errorf("stdin must be a .zip or .tar.gz archive")
+	}
+
+	tmp, err := os.CreateTemp("", "cb2utorial-stdin-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), reader); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	path := filepath.Join(os.TempDir(), "cb2utorial-stdin-"+hex.EncodeToString(hash.Sum(nil))[:16]+ext)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to save stdin archive: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pithomlabs/cb2utorial/prompts"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+)
+
+// deriveWorkflowID builds a deterministic workflow ID from the repository
+// location, the version being read (HEAD, the resolved --ref, or an archive's
+// content hash) and the generation config. Submitting the same request twice
+// yields the same ID, so Restate attaches to the existing run.
+func deriveWorkflowID(input types.TutorialWorkflowInput) (string, error) {
+	source, err := utils.ResolveSource(input.LocalRepoPath)
+	if err != nil {
+		return "", err
+	}
+	absRepo, err := filepath.Abs(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve repo path: %w", err)
+	}
+
+	// Archives are identified by their content instead of a commit
+	var commit string
+	if utils.IsArchive(absRepo) {
+		commit, err = fileHash(absRepo)
+	} else {
+		commit, err = utils.ResolveHead(absRepo)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve source version: %w", err)
+	}
+
+	// A moving ref (e.g. a branch) gets a new ID once it points elsewhere
+	if input.GitRef != "" {
+		repo, err := utils.OpenGitRepo(absRepo)
+		if err != nil {
+			return "", err
+		}
+		if commit, err = repo.ResolveRevision(input.GitRef); err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", input.GitRef, err)
+		}
+	}
+
+	// The repo path is hashed in absolute form so ./repo and /abs/repo match
+	config := input
+	config.LocalRepoPath = absRepo
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize config: %w", err)
+	}
+
+	// Editing a prompt template must produce a new run, not the cached one
+	versions, err := prompts.Versions(input.PromptsDir)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(absRepo))
+	hash.Write([]byte{0})
+	hash.Write([]byte(commit))
+	hash.Write([]byte{0})
+	hash.Write(configJSON)
+	for _, version := range versions {
+		hash.Write([]byte{0})
+		hash.Write([]byte(version.Name + "@" + version.Digest))
+	}
+
+	return "tutorial-" + hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// fileHash returns the SHA-256 of a file's content
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
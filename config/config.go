@@ -0,0 +1,322 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+)
+
+// Built-in defaults used when neither the input, the environment nor a
+// preset provides a value
+const (
+	DefaultPreset          = "default"
+	DefaultMaxFileSize     = int64(1 << 20) // 1MB
+	DefaultMaxFiles        = 100
+	DefaultMaxAbstractions = 10
+	DefaultOutputDir       = "./tutorial"
+	DefaultLanguage        = "English"
+
+	// MaxAbstractionsLimit bounds the chapter count (one chapter per abstraction)
+	MaxAbstractionsLimit = 30
+
+	// DefaultReviewTimeout bounds how long a run waits for a reviewer
+	DefaultReviewTimeout = 24 * time.Hour
+)
+
+// ReviewTimeout returns how long a run waits for review, given
+// TutorialWorkflowInput.ReviewTimeoutMinutes (0 means the default)
+func ReviewTimeout(minutes int) time.Duration {
+	if minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return DefaultReviewTimeout
+}
+
+// Preset is a language-specific set of include/exclude patterns
+type Preset struct {
+	Include []string
+	Exclude []string
+}
+
+// Presets are selected with TutorialWorkflowInput.Preset (--preset)
+var Presets = map[string]Preset{
+	DefaultPreset: {
+		Include: []string{"*.go", "*.py", "*.js", "*.ts", "*.java", "*.rb", "*.md"},
+		Exclude: []string{"*_test.go", "vendor/*", "node_modules/*", ".git/*", "*.min.js"},
+	},
+	"go": {
+		Include: []string{"*.go", "*.md", "go.mod"},
+		Exclude: []string{"*_test.go", "vendor/*", "testdata/*", ".git/*"},
+	},
+	"python": {
+		Include: []string{"*.py", "*.md", "pyproject.toml"},
+		Exclude: []string{"test_*.py", "*_test.py", "tests/*", "venv/*", ".venv/*", "*__pycache__*", ".git/*"},
+	},
+	"javascript": {
+		Include: []string{"*.js", "*.jsx", "*.mjs", "*.cjs", "*.md", "package.json"},
+		Exclude: []string{"node_modules/*", "dist/*", "build/*", "*.min.js", "*.test.js", "*.spec.js", ".git/*"},
+	},
+	"typescript": {
+		Include: []string{"*.ts", "*.tsx", "*.md", "package.json"},
+		Exclude: []string{"node_modules/*", "dist/*", "build/*", "*.d.ts", "*.test.ts", "*.spec.ts", ".git/*"},
+	},
+	"java": {
+		Include: []string{"*.java", "*.md", "pom.xml", "build.gradle"},
+		Exclude: []string{"src/test/*", "target/*", "build/*", ".git/*"},
+	},
+	"ruby": {
+		Include: []string{"*.rb", "*.md", "Gemfile"},
+		Exclude: []string{"spec/*", "test/*", "vendor/*", ".git/*"},
+	},
+	"rust": {
+		Include: []string{"*.rs", "*.md", "Cargo.toml"},
+		Exclude: []string{"target/*", "tests/*", "benches/*", ".git/*"},
+	},
+}
+
+// PresetNames returns the available preset names, sorted
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyEnv fills unset file selection fields from INCLUDE_PATTERNS,
+// EXCLUDE_PATTERNS, MAX_FILE_SIZE, MAX_FILES and MAX_ABSTRACTIONS.
+// Call it where the environment belongs to the user (the CLI), not inside
+// the workflow, so a run never depends on the server's environment.
+func ApplyEnv(input *types.TutorialWorkflowInput) error {
+	if len(input.IncludePatterns) == 0 {
+		input.IncludePatterns = SplitPatterns(os.Getenv("INCLUDE_PATTERNS"))
+	}
+	if len(input.ExcludePatterns) == 0 {
+		input.ExcludePatterns = SplitPatterns(os.Getenv("EXCLUDE_PATTERNS"))
+	}
+
+	if input.MaxFileSize == 0 {
+		value, err := envInt("MAX_FILE_SIZE")
+		if err != nil {
+			return err
+		}
+		input.MaxFileSize = int64(value)
+	}
+	if input.MaxFiles == 0 {
+		value, err := envInt("MAX_FILES")
+		if err != nil {
+			return err
+		}
+		input.MaxFiles = value
+	}
+	if input.MaxAbstractions == 0 {
+		value, err := envInt("MAX_ABSTRACTIONS")
+		if err != nil {
+			return err
+		}
+		input.MaxAbstractions = value
+	}
+	return nil
+}
+
+// ApplyDefaults fills fields that are still unset from the selected preset
+// and the built-in defaults. Explicit patterns replace the preset's.
+func ApplyDefaults(input *types.TutorialWorkflowInput) error {
+	presetName := input.Preset
+	if presetName == "" {
+		presetName = DefaultPreset
+	}
+	preset, ok := Presets[presetName]
+	if !ok {
+		return fmt.Errorf("unknown preset %q (expected one of %s)", presetName, strings.Join(PresetNames(), ", "))
+	}
+
+	if len(input.IncludePatterns) == 0 {
+		input.IncludePatterns = preset.Include
+	}
+	if len(input.ExcludePatterns) == 0 {
+		input.ExcludePatterns = preset.Exclude
+	}
+	if input.MaxFileSize == 0 {
+		input.MaxFileSize = DefaultMaxFileSize
+	}
+	if input.MaxFiles == 0 {
+		input.MaxFiles = DefaultMaxFiles
+	}
+	if input.MaxAbstractions == 0 {
+		input.MaxAbstractions = DefaultMaxAbstractions
+	}
+	if input.OutputDir == "" {
+		input.OutputDir = DefaultOutputDir
+	}
+	if input.OutputFormat == "" {
+		input.OutputFormat = FormatMarkdown
+	}
+	return nil
+}
+
+// Validate checks patterns and limits after defaults have been applied
+func Validate(input types.TutorialWorkflowInput) error {
+	for _, pattern := range input.IncludePatterns {
+		if _, err := glob.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+	}
+	for _, pattern := range input.ExcludePatterns {
+		if _, err := glob.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+
+	if input.MaxFileSize < 0 {
+		return fmt.Errorf("max_file_size must not be negative, got %d", input.MaxFileSize)
+	}
+	if input.MaxFiles < 0 {
+		return fmt.Errorf("max_files must not be negative, got %d", input.MaxFiles)
+	}
+	if input.MaxAbstractions < 1 || input.MaxAbstractions > MaxAbstractionsLimit {
+		return fmt.Errorf("max_abstractions must be between 1 and %d, got %d", MaxAbstractionsLimit, input.MaxAbstractions)
+	}
+	if input.OutputFormat != FormatMarkdown && input.OutputFormat != FormatMarkdownIndex {
+		return fmt.Errorf("output_format must be %s or %s, got %q", FormatMarkdown, FormatMarkdownIndex, input.OutputFormat)
+	}
+	if len(input.PinnedAbstractions) > input.MaxAbstractions {
+		return fmt.Errorf("%d pinned_abstractions exceed max_abstractions (%d)", len(input.PinnedAbstractions), input.MaxAbstractions)
+	}
+	if input.SourceURL != "" {
+		if err := utils.ValidateSourceURL(input.SourceURL); err != nil {
+			return err
+		}
+	}
+	if err := validateStyle(input.Style); err != nil {
+		return err
+	}
+	if err := validateCritic(input.Critic); err != nil {
+		return err
+	}
+	if mode := VerificationMode(input.Verification); mode != VerificationReport && mode != VerificationCorrect && mode != VerificationOff {
+		return fmt.Errorf("verification must be %s, %s or %s, got %q", VerificationReport, VerificationCorrect, VerificationOff, input.Verification)
+	}
+	if mode := GoSnippetsMode(input.GoSnippets); mode != GoSnippetsReport && mode != GoSnippetsRegenerate && mode != GoSnippetsOff {
+		return fmt.Errorf("go_snippets must be %s, %s or %s, got %q", GoSnippetsReport, GoSnippetsRegenerate, GoSnippetsOff, input.GoSnippets)
+	}
+	seenLanguages := map[string]bool{utils.VariantDir(LanguageOrDefault(input.Language)): true}
+	for _, language := range input.Languages {
+		dir := utils.VariantDir(language)
+		if dir == "" {
+			return fmt.Errorf("invalid language %q", language)
+		}
+		if seenLanguages[dir] || (IsDefaultLanguage(language) && IsDefaultLanguage(input.Language)) {
+			return fmt.Errorf("language %q is listed twice or is the primary language", language)
+		}
+		seenLanguages[dir] = true
+	}
+	pinnedNames := make(map[string]bool, len(input.PinnedAbstractions))
+	for i, pinned := range input.PinnedAbstractions {
+		if pinned.Name == "" {
+			return fmt.Errorf("pinned_abstractions[%d] has no name", i)
+		}
+		key := utils.ConceptKey(pinned.Name)
+		if pinnedNames[key] {
+			return fmt.Errorf("pinned_abstractions[%d]: %q is pinned twice", i, pinned.Name)
+		}
+		pinnedNames[key] = true
+		if utils.IsBlockedConcept(pinned.Name, input.BlockedAbstractions) {
+			return fmt.Errorf("pinned_abstractions[%d]: %q is also blocked", i, pinned.Name)
+		}
+		for _, pattern := range pinned.Files {
+			if _, err := glob.Compile(pattern); err != nil {
+				return fmt.Errorf("pinned_abstractions[%d]: invalid file pattern %q: %w", i, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Symbol verification modes: what happens to references a chapter makes to
+// functions or types the repository does not declare
+const (
+	VerificationReport  = "report"  // List them in the run report
+	VerificationCorrect = "correct" // Ask the LLM to fix them, then report what remains
+	VerificationOff     = "off"     // Skip the check
+)
+
+// VerificationMode returns mode, or VerificationReport if it is empty
+func VerificationMode(mode string) string {
+	if mode == "" {
+		return VerificationReport
+	}
+	return mode
+}
+
+// Go snippet check modes: what happens to a chapter whose Go code blocks do
+// not parse
+const (
+	GoSnippetsReport     = "report"     // List them in the run report
+	GoSnippetsRegenerate = "regenerate" // Rewrite the chapter with the parse errors as feedback, then report
+	GoSnippetsOff        = "off"        // Skip the check
+)
+
+// GoSnippetsMode returns mode, or GoSnippetsReport if it is empty
+func GoSnippetsMode(mode string) string {
+	if mode == "" {
+		return GoSnippetsReport
+	}
+	return mode
+}
+
+// SnippetRegenerations is how many times a chapter with unparseable Go code
+// may be rewritten
+func SnippetRegenerations(mode string) int {
+	if mode != GoSnippetsRegenerate {
+		return 0
+	}
+	return DefaultMaxRegenerations
+}
+
+// LanguageOrDefault returns language, or DefaultLanguage if it is empty
+func LanguageOrDefault(language string) string {
+	if strings.TrimSpace(language) == "" {
+		return DefaultLanguage
+	}
+	return language
+}
+
+// IsDefaultLanguage reports whether a tutorial in language needs no
+// translation: empty, "English", "en" or a regional English such as "en-US"
+func IsDefaultLanguage(language string) bool {
+	language = strings.ToLower(strings.TrimSpace(language))
+	return language == "" || language == strings.ToLower(DefaultLanguage) || language == "en" || strings.HasPrefix(language, "en-") || strings.HasPrefix(language, "en_")
+}
+
+// SplitPatterns parses a comma-separated pattern list (env vars and flags)
+func SplitPatterns(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// envInt reads an integer environment variable; unset means 0
+func envInt(name string) (int, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: must be an integer", name, value)
+	}
+	return n, nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// Critic defaults: chapters scoring below DefaultCriticThreshold on average
+// are revised once
+const (
+	DefaultCriticThreshold    = 3.5
+	DefaultCriticMaxRevisions = 1
+)
+
+// Rubric score range
+const (
+	MinCriticScore = 1
+	MaxCriticScore = 5
+)
+
+// CriticConfig is the "critic" section of cb2utorial.yaml
+type CriticConfig struct {
+	Enabled      bool    `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	Threshold    float64 `yaml:"threshold,omitempty" json:"threshold,omitempty"`
+	MaxRevisions int     `yaml:"max_revisions,omitempty" json:"max_revisions,omitempty"`
+}
+
+// CriticSettings converts the section to workflow settings, or nil if the
+// critic is not enabled
+func (c CriticConfig) CriticSettings() *types.CriticSettings {
+	if !c.Enabled {
+		return nil
+	}
+	return &types.CriticSettings{Threshold: c.Threshold, MaxRevisions: c.MaxRevisions}
+}
+
+// CriticThreshold returns the minimum average score a chapter must reach
+func CriticThreshold(critic *types.CriticSettings) float64 {
+	if critic == nil || critic.Threshold == 0 {
+		return DefaultCriticThreshold
+	}
+	return critic.Threshold
+}
+
+// CriticMaxRevisions returns how often a chapter below the threshold is
+// revised
+func CriticMaxRevisions(critic *types.CriticSettings) int {
+	if critic == nil || critic.MaxRevisions == 0 {
+		return DefaultCriticMaxRevisions
+	}
+	return critic.MaxRevisions
+}
+
+// validateCritic checks the critic's threshold and revision limit
+func validateCritic(critic *types.CriticSettings) error {
+	if critic == nil {
+		return nil
+	}
+	if critic.Threshold != 0 && (critic.Threshold < MinCriticScore || critic.Threshold > MaxCriticScore) {
+		return fmt.Errorf("critic.threshold must be between %d and %d, got %g", MinCriticScore, MaxCriticScore, critic.Threshold)
+	}
+	if critic.MaxRevisions < 0 || critic.MaxRevisions > maxRegenerationsLimit {
+		return fmt.Errorf("critic.max_revisions must be between 0 and %d, got %d", maxRegenerationsLimit, critic.MaxRevisions)
+	}
+	return nil
+}
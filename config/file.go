@@ -0,0 +1,199 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the repo-local configuration file
+const FileName = "cb2utorial.yaml"
+
+// Output formats
+const (
+	FormatMarkdown      = "markdown"
+	FormatMarkdownIndex = "markdown-index"
+)
+
+// File is the content of cb2utorial.yaml (see cb2utorial.schema.json)
+type File struct {
+	Project             string                    `yaml:"project,omitempty" json:"project,omitempty"`
+	Preset              string                    `yaml:"preset,omitempty" json:"preset,omitempty"`
+	Include             []string                  `yaml:"include,omitempty" json:"include,omitempty"`
+	Exclude             []string                  `yaml:"exclude,omitempty" json:"exclude,omitempty"`
+	MaxFileSize         int64                     `yaml:"max_file_size,omitempty" json:"max_file_size,omitempty"`
+	MaxFiles            int                       `yaml:"max_files,omitempty" json:"max_files,omitempty"`
+	Chapters            int                       `yaml:"chapters,omitempty" json:"chapters,omitempty"`
+	Models              types.ModelRouting        `yaml:"models,omitempty" json:"models,omitempty"`
+	Audience            string                    `yaml:"audience,omitempty" json:"audience,omitempty"`
+	Language            string                    `yaml:"language,omitempty" json:"language,omitempty"`
+	Languages           []string                  `yaml:"languages,omitempty" json:"languages,omitempty"`
+	PinnedAbstractions  []types.PinnedAbstraction `yaml:"pinned_abstractions,omitempty" json:"pinned_abstractions,omitempty"`
+	BlockedAbstractions []string                  `yaml:"blocked_abstractions,omitempty" json:"blocked_abstractions,omitempty"`
+	PromptsDir          string                    `yaml:"prompts_dir,omitempty" json:"prompts_dir,omitempty"`
+	Verification        string                    `yaml:"verification,omitempty" json:"verification,omitempty"`
+	GoSnippets          string                    `yaml:"go_snippets,omitempty" json:"go_snippets,omitempty"`
+	Style               StyleConfig               `yaml:"style,omitempty" json:"style,omitempty"`
+	Critic              CriticConfig              `yaml:"critic,omitempty" json:"critic,omitempty"`
+	Output              OutputConfig              `yaml:"output,omitempty" json:"output,omitempty"`
+	Hooks               Hooks                     `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+}
+
+// OutputConfig is the "output" section
+type OutputConfig struct {
+	Dir       string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Format    string `yaml:"format,omitempty" json:"format,omitempty"`
+	SourceURL string `yaml:"source_url,omitempty" json:"source_url,omitempty"`
+}
+
+// Hooks are shell commands the CLI runs around generation
+type Hooks struct {
+	PreGenerate  []string `yaml:"pre_generate,omitempty" json:"pre_generate,omitempty"`
+	PostGenerate []string `yaml:"post_generate,omitempty" json:"post_generate,omitempty"`
+}
+
+// Load reads and validates a config file. A relative prompts_dir or
+// output.dir is resolved against the file's directory, and style.guide_file
+// is read.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	file, err := Parse(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if file.PromptsDir, err = resolveConfigPath(path, file.PromptsDir); err != nil {
+		return nil, fmt.Errorf("failed to resolve prompts_dir: %w", err)
+	}
+	if file.Output.Dir, err = resolveConfigPath(path, file.Output.Dir); err != nil {
+		return nil, fmt.Errorf("failed to resolve output.dir: %w", err)
+	}
+	if err := resolveStyleGuideFile(file, path); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// resolveConfigPath makes a relative path from the config file absolute,
+// relative to the file's directory. Empty and absolute paths are unchanged.
+func resolveConfigPath(configPath string, p string) (string, error) {
+	if p == "" || filepath.IsAbs(p) {
+		return p, nil
+	}
+	dir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, p), nil
+}
+
+// Parse validates data against the schema and decodes it. name is used in
+// error messages.
+func Parse(name string, data []byte) (*File, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s is not valid YAML: %w", name, err)
+	}
+	if len(doc.Content) == 0 {
+		return &File{}, nil // Empty file
+	}
+	if err := validateYAML(name, &doc); err != nil {
+		return nil, err
+	}
+
+	var file File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return &file, nil
+}
+
+// Discover loads FileName from the repository root. Returns nil if the
+// repository has none or is not a directory (archives, module specs).
+func Discover(repoPath string) (*File, error) {
+	path := filepath.Join(repoPath, FileName)
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	return Load(path)
+}
+
+// ApplyFile fills unset input fields from a config file. Flags are applied
+// before and therefore take precedence.
+func ApplyFile(input *types.TutorialWorkflowInput, file *File) {
+	if file == nil {
+		return
+	}
+
+	if input.ProjectName == "" {
+		input.ProjectName = file.Project
+	}
+	if input.Preset == "" {
+		input.Preset = file.Preset
+	}
+	if len(input.IncludePatterns) == 0 {
+		input.IncludePatterns = file.Include
+	}
+	if len(input.ExcludePatterns) == 0 {
+		input.ExcludePatterns = file.Exclude
+	}
+	if input.MaxFileSize == 0 {
+		input.MaxFileSize = file.MaxFileSize
+	}
+	if input.MaxFiles == 0 {
+		input.MaxFiles = file.MaxFiles
+	}
+	if input.MaxAbstractions == 0 {
+		input.MaxAbstractions = file.Chapters
+	}
+	if input.Models == (types.ModelRouting{}) {
+		input.Models = file.Models
+	}
+	if input.Audience == "" {
+		input.Audience = file.Audience
+	}
+	if input.Language == "" {
+		input.Language = file.Language
+	}
+	if len(input.Languages) == 0 {
+		input.Languages = file.Languages
+	}
+	if len(input.PinnedAbstractions) == 0 {
+		input.PinnedAbstractions = file.PinnedAbstractions
+	}
+	if len(input.BlockedAbstractions) == 0 {
+		input.BlockedAbstractions = file.BlockedAbstractions
+	}
+	if input.Verification == "" {
+		input.Verification = file.Verification
+	}
+	if input.GoSnippets == "" {
+		input.GoSnippets = file.GoSnippets
+	}
+	if input.Style == nil {
+		input.Style = file.Style.StyleGuide()
+	}
+	if input.Critic == nil {
+		input.Critic = file.Critic.CriticSettings()
+	}
+	if input.PromptsDir == "" {
+		input.PromptsDir = file.PromptsDir
+	}
+	if input.OutputDir == "" {
+		input.OutputDir = file.Output.Dir
+	}
+	if input.OutputFormat == "" {
+		input.OutputFormat = file.Output.Format
+	}
+	if input.SourceURL == "" {
+		input.SourceURL = file.Output.SourceURL
+	}
+}
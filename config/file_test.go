@@ -0,0 +1,189 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		problems []string // Substrings of the reported problems, in order; none = valid
+	}{
+		{
+			name: "valid",
+			yaml: "project: Demo\npreset: go\nchapters: 8\noutput:\n  dir: docs\n  format: markdown-index\n",
+		},
+		{
+			name: "empty file",
+			yaml: "",
+		},
+		{
+			name:     "unknown key with a close match",
+			yaml:     "project: Demo\nchapter: 8\n",
+			problems: []string{`2:1: chapter: unknown field (did you mean "chapters"?)`},
+		},
+		{
+			name:     "unknown key without a close match",
+			yaml:     "colour: blue\n",
+			problems: []string{"1:1: colour: unknown field (expected one of audience, blocked_abstractions, chapters,"},
+		},
+		{
+			name:     "unknown nested key",
+			yaml:     "output:\n  dri: docs\n",
+			problems: []string{`2:3: output.dri: unknown field (did you mean "dir"?)`},
+		},
+		{
+			name:     "string instead of integer",
+			yaml:     "max_files: lots\n",
+			problems: []string{`1:12: max_files: expected an integer, got string "lots"`},
+		},
+		{
+			name:     "scalar instead of list",
+			yaml:     "include: '*.go'\n",
+			problems: []string{`1:10: include: expected a list, got string "*.go"`},
+		},
+		{
+			name:     "enum value with a close match",
+			yaml:     "preset: pyhton\n",
+			problems: []string{`1:9: preset: "pyhton" is not one of default, go, python, javascript, typescript, java, ruby, rust (did you mean "python"?)`},
+		},
+		{
+			name:     "below minimum",
+			yaml:     "max_files: 0\n",
+			problems: []string{"1:12: max_files: must be at least 1, got 0"},
+		},
+		{
+			name:     "above maximum",
+			yaml:     "chapters: 31\n",
+			problems: []string{"1:11: chapters: must be at most 30, got 31"},
+		},
+		{
+			name:     "empty string",
+			yaml:     "project: ''\n",
+			problems: []string{"1:10: project: must not be empty"},
+		},
+		{
+			name:     "every problem is reported",
+			yaml:     "chapter: 8\noutput:\n  format: html\n",
+			problems: []string{"1:1: chapter: unknown field", `3:11: output.format: "html" is not one of markdown, markdown-index`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(FileName, []byte(tt.yaml))
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				return
+			}
+			verr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("Parse error = %v, want a *ValidationError", err)
+			}
+			if len(verr.Problems) != len(tt.problems) {
+				t.Fatalf("problems = %q, want %d", verr.Problems, len(tt.problems))
+			}
+			for i, want := range tt.problems {
+				if !strings.Contains(verr.Problems[i], want) {
+					t.Errorf("problem %d = %q, want it to contain %q", i, verr.Problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadResolvesPaths(t *testing.T) {
+	dir := t.TempDir()
+	absolute := filepath.Join(dir, "elsewhere")
+
+	tests := []struct {
+		name       string
+		yaml       string
+		promptsDir string
+		outputDir  string
+	}{
+		{
+			name:       "relative to the config file",
+			yaml:       "prompts_dir: prompts\noutput:\n  dir: ../docs\n",
+			promptsDir: filepath.Join(dir, "repo", "prompts"),
+			outputDir:  filepath.Join(dir, "docs"),
+		},
+		{
+			name:       "absolute paths are kept",
+			yaml:       "prompts_dir: " + absolute + "\noutput:\n  dir: " + absolute + "\n",
+			promptsDir: absolute,
+			outputDir:  absolute,
+		},
+		{
+			name: "unset paths stay unset",
+			yaml: "project: Demo\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "repo", FileName)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if file.PromptsDir != tt.promptsDir {
+				t.Errorf("prompts_dir = %q, want %q", file.PromptsDir, tt.promptsDir)
+			}
+			if file.Output.Dir != tt.outputDir {
+				t.Errorf("output.dir = %q, want %q", file.Output.Dir, tt.outputDir)
+			}
+		})
+	}
+}
+
+func TestInputPrecedence(t *testing.T) {
+	file, err := Parse(FileName, []byte("chapters: 8\nmax_files: 50\ninclude: ['*.go']\noutput:\n  dir: docs\n"))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	t.Setenv("MAX_FILES", "10")
+	t.Setenv("MAX_FILE_SIZE", "2048")
+	t.Setenv("INCLUDE_PATTERNS", "*.py")
+	t.Setenv("EXCLUDE_PATTERNS", "")
+	t.Setenv("MAX_ABSTRACTIONS", "")
+
+	// Flags first, then the config file, the environment and the defaults
+	input := types.TutorialWorkflowInput{MaxAbstractions: 4}
+	ApplyFile(&input, file)
+	if err := ApplyEnv(&input); err != nil {
+		t.Fatalf("ApplyEnv: %v", err)
+	}
+	if err := ApplyDefaults(&input); err != nil {
+		t.Fatalf("ApplyDefaults: %v", err)
+	}
+
+	want := types.TutorialWorkflowInput{
+		MaxAbstractions: 4,                              // Flag
+		MaxFiles:        50,                             // Config file
+		IncludePatterns: []string{"*.go"},               // Config file
+		OutputDir:       "docs",                         // Config file
+		MaxFileSize:     2048,                           // Environment
+		ExcludePatterns: Presets[DefaultPreset].Exclude, // Preset
+		OutputFormat:    FormatMarkdown,                 // Default
+	}
+	if !reflect.DeepEqual(input, want) {
+		t.Errorf("input = %+v, want %+v", input, want)
+	}
+}
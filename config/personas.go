@@ -0,0 +1,95 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultPersona is used when no audience is set
+const DefaultPersona = "newcomer"
+
+// Persona describes a tutorial audience and how each LLM stage adapts to it
+type Persona struct {
+	Name        string // Key accepted as TutorialWorkflowInput.Audience
+	Description string // Who the reader is
+	Selection   string // Which abstractions matter to this reader
+	Ordering    string // How chapters should be sequenced
+	Writing     string // Depth, tone and emphasis of each chapter
+}
+
+// Personas are selected with TutorialWorkflowInput.Audience (--audience)
+var Personas = map[string]Persona{
+	DefaultPersona: {
+		Name:        DefaultPersona,
+		Description: "Developers new to this codebase who want to understand it quickly.",
+		Selection:   "Favor the concepts a newcomer must understand first: entry points, core data types and the main control flow.",
+		Ordering: `- Start with foundational concepts or user-facing entry points
+- Progress to implementation details
+- Ensure dependencies are explained before they're used
+- Make it pedagogically sound`,
+		Writing: "Assume general programming knowledge but no familiarity with this project. Use analogies, explain jargon and build up from the basics.",
+	},
+	"contributor": {
+		Name:        "contributor",
+		Description: "Experienced developers who will change this codebase and need its internals, conventions and extension points.",
+		Selection:   "Favor internal architecture, extension points, invariants and the conventions a contributor must follow; skip trivia a seasoned developer already knows.",
+		Ordering: `- Start with the architecture and module boundaries
+- Then the core internals and their invariants
+- End with extension points and how to add features safely`,
+		Writing: "Be concise and precise. Skip basic explanations; focus on design decisions, invariants, edge cases and where to make changes.",
+	},
+	"operator": {
+		Name:        "operator",
+		Description: "SREs and operators who deploy, configure, monitor and troubleshoot this system in production.",
+		Selection:   "Favor configuration, deployment, runtime behavior, failure handling, retries, persistence, logging and resource usage over internal algorithms.",
+		Ordering: `- Start with how the system is deployed and configured
+- Then its runtime behavior and dependencies
+- End with failure modes, observability and troubleshooting`,
+		Writing: "Write like an operations handbook: configuration knobs, environment variables, what happens on failure, what to monitor and how to recover. Show commands and config over internal code.",
+	},
+	"api-consumer": {
+		Name:        "api-consumer",
+		Description: "Developers integrating with this project through its public API, CLI or library interface, without changing its internals.",
+		Selection:   "Favor the public API surface: exported types and functions, request/response formats, CLI commands and error behavior. Internal helpers matter only where they affect callers.",
+		Ordering: `- Start with installation and a minimal end-to-end usage
+- Then the main API concepts and common tasks
+- End with advanced usage, errors and limits`,
+		Writing: "Focus on how to use the project: signatures, parameters, return values, errors and complete usage examples. Mention internals only when they explain observable behavior.",
+	},
+	"stakeholder": {
+		Name:        "stakeholder",
+		Description: "Non-technical stakeholders such as product managers who need to understand what the system does and why, not how to code it.",
+		Selection:   "Favor capabilities, user-visible workflows, data flows and integrations; ignore low-level implementation details.",
+		Ordering: `- Start with what the system is for and who uses it
+- Then its main capabilities and workflows
+- End with dependencies, risks and limitations`,
+		Writing: "Use plain language and no jargon. Prefer diagrams and analogies over code; show code only when essential and explain it in plain words.",
+	},
+}
+
+// PersonaNames returns the available persona names, sorted
+func PersonaNames() []string {
+	names := make([]string, 0, len(Personas))
+	for name := range Personas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolvePersona returns the persona for an audience setting. A persona name
+// selects that persona; any other text describes a custom audience, which
+// gets the default persona's guidance.
+func ResolvePersona(audience string) Persona {
+	audience = strings.TrimSpace(audience)
+	if audience == "" {
+		return Personas[DefaultPersona]
+	}
+	if persona, ok := Personas[strings.ToLower(audience)]; ok {
+		return persona
+	}
+	persona := Personas[DefaultPersona]
+	persona.Name = audience
+	persona.Description = audience
+	return persona
+}
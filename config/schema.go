@@ -0,0 +1,260 @@
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaJSON is the published JSON schema for cb2utorial.yaml
+//
+//go:embed cb2utorial.schema.json
+var SchemaJSON []byte
+
+// schema is the subset of JSON Schema the config file uses
+type schema struct {
+	Type                 string             `json:"type"`
+	Description          string             `json:"description"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	Enum                 []string           `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+}
+
+// rootSchema is parsed once from SchemaJSON
+var rootSchema = mustParseSchema(SchemaJSON)
+
+// mustParseSchema panics on an invalid embedded schema (a build-time mistake)
+func mustParseSchema(data []byte) *schema {
+	var s schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		panic(fmt.Sprintf("invalid embedded config schema: %v", err))
+	}
+	return &s
+}
+
+// ValidationError lists every problem found in a config file
+type ValidationError struct {
+	File     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s is invalid:\n  %s", e.File, strings.Join(e.Problems, "\n  "))
+}
+
+// validateYAML checks a parsed YAML document against the schema. Problems
+// carry line:column positions and the dotted path of the offending field.
+func validateYAML(file string, doc *yaml.Node) error {
+	v := &validator{file: file}
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	v.validate(rootSchema, doc, "")
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{File: file, Problems: v.problems}
+}
+
+type validator struct {
+	file     string
+	problems []string
+}
+
+// fail records a problem at a node
+func (v *validator) fail(node *yaml.Node, path string, format string, args ...interface{}) {
+	location := fmt.Sprintf("%d:%d", node.Line, node.Column)
+	if path == "" {
+		path = "(root)"
+	}
+	v.problems = append(v.problems, fmt.Sprintf("%s: %s: %s", location, path, fmt.Sprintf(format, args...)))
+}
+
+// validate checks node against s and recurses into objects and arrays
+func (v *validator) validate(s *schema, node *yaml.Node, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch s.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			v.fail(node, path, "expected a mapping, got %s", describeNode(node))
+			return
+		}
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field := joinPath(path, key.Value)
+			seen[key.Value] = true
+
+			prop, ok := s.Properties[key.Value]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					v.fail(key, field, "unknown field%s", suggest(key.Value, s.Properties))
+				}
+				continue
+			}
+			v.validate(prop, value, field)
+		}
+		for _, name := range s.Required {
+			if !seen[name] {
+				v.fail(node, path, "missing required field %q", name)
+			}
+		}
+
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			v.fail(node, path, "expected a list, got %s", describeNode(node))
+			return
+		}
+		if s.Items != nil {
+			for i, item := range node.Content {
+				v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+
+	case "string":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+			v.fail(node, path, "expected a string, got %s", describeNode(node))
+			return
+		}
+		if s.MinLength != nil && len(node.Value) < *s.MinLength {
+			v.fail(node, path, "must not be empty")
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, node.Value) {
+			v.fail(node, path, "%q is not one of %s%s", node.Value, strings.Join(s.Enum, ", "), suggestFrom(node.Value, s.Enum))
+		}
+
+	case "integer", "number":
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && !(s.Type == "number" && node.Tag == "!!float")) {
+			v.fail(node, path, "expected %s, got %s", article(s.Type), describeNode(node))
+			return
+		}
+		n, err := strconv.ParseFloat(node.Value, 64)
+		if err != nil {
+			v.fail(node, path, "invalid number %q", node.Value)
+			return
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			v.fail(node, path, "must be at least %v, got %s", *s.Minimum, node.Value)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			v.fail(node, path, "must be at most %v, got %s", *s.Maximum, node.Value)
+		}
+
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			v.fail(node, path, "expected true or false, got %s", describeNode(node))
+		}
+	}
+}
+
+// describeNode names a node's YAML type for error messages
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case "!!str":
+			return fmt.Sprintf("string %q", node.Value)
+		case "!!int":
+			return "integer " + node.Value
+		case "!!float":
+			return "number " + node.Value
+		case "!!bool":
+			return "boolean " + node.Value
+		case "!!null":
+			return "nothing (null)"
+		}
+		return node.Value
+	}
+	return "an unsupported value"
+}
+
+// article prefixes a type name with "a" or "an"
+func article(typeName string) string {
+	if strings.ContainsAny(typeName[:1], "aeiou") {
+		return "an " + typeName
+	}
+	return "a " + typeName
+}
+
+// joinPath extends a dotted field path
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// suggest returns a "did you mean" hint for an unknown field
+func suggest(name string, properties map[string]*schema) string {
+	candidates := make([]string, 0, len(properties))
+	for candidate := range properties {
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+	if hint := suggestFrom(name, candidates); hint != "" {
+		return hint
+	}
+	return " (expected one of " + strings.Join(candidates, ", ") + ")"
+}
+
+// suggestFrom returns " (did you mean X?)" for a close candidate, or ""
+func suggestFrom(value string, candidates []string) string {
+	best, bestDistance := "", 3 // Suggest only within two edits
+	for _, candidate := range candidates {
+		if d := editDistance(strings.ToLower(value), candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
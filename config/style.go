@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// What happens to a chapter that breaks the style guide
+const (
+	OnViolationFlag       = "flag"       // Keep it and list the problems in the run report
+	OnViolationRegenerate = "regenerate" // Rewrite it with the problems as feedback, then flag
+)
+
+// DefaultMaxRegenerations bounds rewrites per chapter in regenerate mode
+const DefaultMaxRegenerations = 2
+
+// maxRegenerationsLimit keeps a strict guide from multiplying LLM cost
+const maxRegenerationsLimit = 5
+
+// ChapterSection is one "##" heading of the chapter outline
+type ChapterSection struct {
+	Heading string
+	Hint    string // What the section contains, shown in the prompt
+}
+
+// DefaultSections is the outline used when the style guide sets none
+var DefaultSections = []ChapterSection{
+	{Heading: "What It Does", Hint: "[Clear explanation of the abstraction's purpose]"},
+	{Heading: "Key Code", Hint: "[Show relevant code snippets with explanations]"},
+	{Heading: "How It Works", Hint: "[Step-by-step explanation of the implementation]"},
+	{Heading: "Key Takeaways", Hint: "- [Important point 1]\n- [Important point 2]\n- [Important point 3]"},
+}
+
+// StyleConfig is the "style" section of cb2utorial.yaml
+type StyleConfig struct {
+	Guide            string   `yaml:"guide,omitempty" json:"guide,omitempty"`
+	GuideFile        string   `yaml:"guide_file,omitempty" json:"guide_file,omitempty"`
+	Tone             string   `yaml:"tone,omitempty" json:"tone,omitempty"`
+	Sections         []string `yaml:"sections,omitempty" json:"sections,omitempty"`
+	MaxWords         int      `yaml:"max_words,omitempty" json:"max_words,omitempty"`
+	BannedPhrases    []string `yaml:"banned_phrases,omitempty" json:"banned_phrases,omitempty"`
+	OnViolation      string   `yaml:"on_violation,omitempty" json:"on_violation,omitempty"`
+	MaxRegenerations int      `yaml:"max_regenerations,omitempty" json:"max_regenerations,omitempty"`
+}
+
+// StyleGuide converts the section to a workflow style guide, or nil if it
+// is empty. GuideFile must already have been read into Guide.
+func (c StyleConfig) StyleGuide() *types.StyleGuide {
+	guide := types.StyleGuide{
+		Guide:            c.Guide,
+		Tone:             c.Tone,
+		Sections:         c.Sections,
+		MaxWords:         c.MaxWords,
+		BannedPhrases:    c.BannedPhrases,
+		OnViolation:      c.OnViolation,
+		MaxRegenerations: c.MaxRegenerations,
+	}
+	if guide.Guide == "" && guide.Tone == "" && len(guide.Sections) == 0 && guide.MaxWords == 0 && len(guide.BannedPhrases) == 0 {
+		return nil
+	}
+	return &guide
+}
+
+// ReadStyleGuideFile appends the content of a guide file to inline guidance
+func ReadStyleGuideFile(path string, inline string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read style guide: %w", err)
+	}
+	text := strings.TrimSpace(string(data))
+	if inline = strings.TrimSpace(inline); inline != "" {
+		text = inline + "\n\n" + text
+	}
+	return text, nil
+}
+
+// resolveStyleGuideFile reads style.guide_file, relative to the config file
+func resolveStyleGuideFile(file *File, configPath string) error {
+	if file.Style.GuideFile == "" {
+		return nil
+	}
+	path := file.Style.GuideFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(configPath), path)
+	}
+	guide, err := ReadStyleGuideFile(path, file.Style.Guide)
+	if err != nil {
+		return err
+	}
+	file.Style.Guide = guide
+	file.Style.GuideFile = ""
+	return nil
+}
+
+// ChapterSections returns the outline a style guide asks for
+func ChapterSections(style *types.StyleGuide) []ChapterSection {
+	if style == nil || len(style.Sections) == 0 {
+		return DefaultSections
+	}
+	sections := make([]ChapterSection, len(style.Sections))
+	for i, heading := range style.Sections {
+		sections[i] = ChapterSection{Heading: heading}
+		for _, def := range DefaultSections {
+			if strings.EqualFold(def.Heading, heading) {
+				sections[i].Hint = def.Hint
+			}
+		}
+	}
+	return sections
+}
+
+// MaxRegenerations returns how often a non-conforming chapter is rewritten
+func MaxRegenerations(style *types.StyleGuide) int {
+	if style == nil || style.OnViolation != OnViolationRegenerate {
+		return 0
+	}
+	if style.MaxRegenerations == 0 {
+		return DefaultMaxRegenerations
+	}
+	return style.MaxRegenerations
+}
+
+// validateStyle checks a style guide's limits and enumerations
+func validateStyle(style *types.StyleGuide) error {
+	if style == nil {
+		return nil
+	}
+	if style.OnViolation != "" && style.OnViolation != OnViolationFlag && style.OnViolation != OnViolationRegenerate {
+		return fmt.Errorf("style.on_violation must be %s or %s, got %q", OnViolationFlag, OnViolationRegenerate, style.OnViolation)
+	}
+	if style.MaxWords < 0 {
+		return fmt.Errorf("style.max_words must not be negative, got %d", style.MaxWords)
+	}
+	if style.MaxRegenerations < 0 || style.MaxRegenerations > maxRegenerationsLimit {
+		return fmt.Errorf("style.max_regenerations must be between 0 and %d, got %d", maxRegenerationsLimit, style.MaxRegenerations)
+	}
+	seen := make(map[string]bool, len(style.Sections))
+	for _, heading := range style.Sections {
+		key := strings.ToLower(strings.TrimSpace(heading))
+		if key == "" {
+			return fmt.Errorf("style.sections must not contain an empty heading")
+		}
+		if seen[key] {
+			return fmt.Errorf("style.sections lists %q twice", heading)
+		}
+		seen[key] = true
+	}
+	for _, phrase := range style.BannedPhrases {
+		if strings.TrimSpace(phrase) == "" {
+			return fmt.Errorf("style.banned_phrases must not contain an empty phrase")
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ require (
 	github.com/pithomlabs/rea v0.1.0
 	github.com/restatedev/sdk-go v0.22.0
 	github.com/revrost/go-openrouter v1.0.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -27,5 +28,4 @@ require (
 	go.opentelemetry.io/otel/metric v1.38.0 // indirect
 	go.opentelemetry.io/otel/trace v1.38.0 // indirect
 	google.golang.org/protobuf v1.36.10 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -35,6 +35,18 @@ func NewClient() (*Client, error) {
 	}, nil
 }
 
+// WithModel returns a client using model instead of LLM_MODEL.
+// An empty model keeps the current one.
+func (c *Client) WithModel(model string) *Client {
+	if model == "" {
+		return c
+	}
+	return &Client{
+		client: c.client,
+		model:  model,
+	}
+}
+
 // CallLLM sends a prompt to the LLM and returns the text response
 // systemPrompt is optional (can be empty string)
 func (c *Client) CallLLM(ctx context.Context, prompt string, systemPrompt string) (string, error) {
@@ -4,9 +4,11 @@ import (
 	"context"
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/pithomlabs/cb2utorial/services"
+	"github.com/pithomlabs/cb2utorial/utils"
 	"github.com/pithomlabs/cb2utorial/workflow"
 	restate "github.com/restatedev/sdk-go"
 	"github.com/restatedev/sdk-go/server"
@@ -18,11 +20,25 @@ func main() {
 		log.Println("No .env file found, using environment variables")
 	}
 
+	// "clean" prunes the content store and exits; run it where the server runs
+	if len(os.Args) > 1 && os.Args[1] == "clean" {
+		pruneContent()
+		return
+	}
+
 	// Validate required environment variables
 	if os.Getenv("OPENROUTER_API_KEY") == "" {
 		log.Fatal("OPENROUTER_API_KEY environment variable is required")
 	}
 
+	// Every service reads file contents from the content store
+	if err := utils.CheckContentStore(); err != nil {
+		log.Fatalf("Invalid CONTENT_STORE_DIR: %v", err)
+	}
+	log.Printf("Content store: %s", utils.ContentStoreDir())
+	log.Printf("Run artifacts: %s", utils.ArtifactDir())
+	pruneContent()
+
 	// Create Restate server and bind all services using method chaining
 	// Note: Bind() returns *Restate for chaining, not an error
 	server := server.NewRestate().
@@ -31,6 +47,9 @@ func main() {
 		Bind(restate.Reflect(services.RelationshipAnalyzerService{})).
 		Bind(restate.Reflect(services.ChapterOrdererService{})).
 		Bind(restate.Reflect(services.ChapterWriterService{})).
+		Bind(restate.Reflect(services.ChapterCriticService{})).
+		Bind(restate.Reflect(services.TranslatorService{})).
+		Bind(restate.Reflect(services.VerifierService{})).
 		Bind(restate.Reflect(services.FileWriterService{})).
 		Bind(restate.Reflect(workflow.TutorialWorkflow{}))
 
@@ -41,6 +60,9 @@ func main() {
 	log.Println("  - RelationshipAnalyzer")
 	log.Println("  - ChapterOrderer")
 	log.Println("  - ChapterWriter")
+	log.Println("  - ChapterCritic")
+	log.Println("  - Translator")
+	log.Println("  - Verifier")
 	log.Println("  - FileWriter")
 	log.Println("Workflows registered:")
 	log.Println("  - TutorialWorkflow")
@@ -50,3 +72,19 @@ func main() {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
+
+// contentGracePeriod is how long unreferenced blobs are kept, so runs that
+// have not saved their first artifact yet keep their file contents
+const contentGracePeriod = 24 * time.Hour
+
+// pruneContent removes blobs no run artifact references from the content store
+func pruneContent() {
+	removed, err := utils.PruneContent(contentGracePeriod)
+	if err != nil {
+		log.Printf("Failed to prune content store: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Pruned %d unreferenced blobs from the content store", removed)
+	}
+}
@@ -0,0 +1,227 @@
+package prompts
+
+import (
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// AbstractionsData renders the "abstractions" template
+type AbstractionsData struct {
+	ProjectName string
+	Audience    string // Persona description
+	Selection   string // Persona selection focus
+	Files       string // File contents, already truncated
+	FileListing string
+	Pinned      []types.PinnedAbstraction
+	Blocked     []string
+	Remaining   int // Abstractions left for the LLM to choose
+}
+
+// RelationshipsData renders the "relationships" template
+type RelationshipsData struct {
+	ProjectName  string
+	Abstractions []types.Abstraction
+	CodeContext  string // Code samples per abstraction
+}
+
+// OrderData renders the "order" template
+type OrderData struct {
+	ProjectName   string
+	Audience      string // Persona description
+	Ordering      string // Persona teaching strategy
+	Abstractions  []types.Abstraction
+	Summary       string
+	Relationships []RelationshipLine
+}
+
+// RelationshipLine is a relationship with the names of both ends
+type RelationshipLine struct {
+	From     int
+	FromName string
+	To       int
+	ToName   string
+	Label    string
+}
+
+// ChapterData renders the "chapter" template
+type ChapterData struct {
+	ProjectName      string
+	Audience         string // Persona description
+	Guidance         string // Persona writing guidance
+	Language         string
+	Name             string
+	Description      string
+	Files            string // Referenced code, already truncated
+	PreviousChapters []types.ChapterSummary
+	Sections         []config.ChapterSection // Outline after the title
+	Style            *types.StyleGuide       // Nil without a style guide
+	Violations       []types.StyleViolation  // Problems of the previous draft
+	SnippetErrors    []types.SnippetError    // Go code of the previous draft that did not parse
+}
+
+// TranslateAnalysisData renders the "translate_analysis" template
+type TranslateAnalysisData struct {
+	ProjectName string
+	Language    string
+	Analysis    string // YAML of the texts to translate
+}
+
+// TranslateChapterData renders the "translate_chapter" template
+type TranslateChapterData struct {
+	Language    string
+	Title       string
+	Placeholder string // Example code block placeholder
+	Chapter     string
+}
+
+// CorrectReferencesData renders the "correct_references" template
+type CorrectReferencesData struct {
+	ProjectName string
+	Unverified  []types.SymbolReference
+	Placeholder string // Example cited code placeholder
+	Chapter     string
+}
+
+// CritiqueChapterData renders the "critique_chapter" template
+type CritiqueChapterData struct {
+	ProjectName      string
+	Name             string
+	Description      string
+	Files            string // Referenced code, already truncated
+	PreviousChapters []types.ChapterSummary
+	Chapter          string
+	MinScore         int
+	MaxScore         int
+}
+
+// ReviseChapterData renders the "revise_chapter" template
+type ReviseChapterData struct {
+	ProjectName string
+	Name        string
+	Files       string // Referenced code, already truncated
+	Critique    types.Critique
+	Placeholder string // Example cited code placeholder
+	Chapter     string
+	MinScore    int
+	MaxScore    int
+}
+
+// SummarizeChapterData renders the "summarize_chapter" template
+type SummarizeChapterData struct {
+	ProjectName string
+	Chapter     string
+	MaxConcepts int
+}
+
+// Sample returns representative data for the named template, used by
+// "prompts lint" to render every field
+func Sample(name string) (any, error) {
+	style := &types.StyleGuide{
+		Guide:         "Address the reader as \"you\".",
+		Tone:          "Friendly and direct",
+		Sections:      []string{"What It Does", "Common Pitfalls"},
+		MaxWords:      1200,
+		BannedPhrases: []string{"simply"},
+	}
+	abstractions := []types.Abstraction{
+		{Index: 0, Name: "Workflow", Description: "Orchestrates the pipeline"},
+		{Index: 1, Name: "Service", Description: "Performs one step"},
+	}
+	previous := []types.ChapterSummary{
+		{ChapterNumber: 1, Name: "Service", Summary: "Explains how a service performs one step.", Concepts: []string{"Service", "handler"}},
+	}
+	switch name {
+	case Abstractions:
+		return AbstractionsData{
+			ProjectName: "sample",
+			Audience:    "Developers new to the codebase",
+			Selection:   "Entry points and core types",
+			Files:       "--- File: main.go ---\npackage main\n",
+			FileListing: "- main.go\n",
+			Pinned:      []types.PinnedAbstraction{{Name: "Workflow", Description: "Orchestrates the pipeline"}},
+			Blocked:     []string{"Logging"},
+			Remaining:   4,
+		}, nil
+	case Relationships:
+		return RelationshipsData{
+			ProjectName:  "sample",
+			Abstractions: abstractions,
+			CodeContext:  "### Abstraction 0: Workflow\n",
+		}, nil
+	case Order:
+		return OrderData{
+			ProjectName:   "sample",
+			Audience:      "Developers new to the codebase",
+			Ordering:      "Start with entry points",
+			Abstractions:  abstractions,
+			Summary:       "A sample project",
+			Relationships: []RelationshipLine{{From: 0, FromName: "Workflow", To: 1, ToName: "Service", Label: "calls"}},
+		}, nil
+	case Chapter:
+		return ChapterData{
+			ProjectName:      "sample",
+			Audience:         "Developers new to the codebase",
+			Guidance:         "Explain the basics first",
+			Language:         "English",
+			Name:             "Workflow",
+			Description:      "Orchestrates the pipeline",
+			Files:            "### File: main.go\n```\npackage main\n```\n",
+			PreviousChapters: previous,
+			Sections:         config.ChapterSections(style),
+			Style:            style,
+			Violations:       []types.StyleViolation{{Rule: "missing-section", Detail: `missing section "## Common Pitfalls"`}},
+			SnippetErrors:    []types.SnippetError{{Block: 2, Line: 3, Error: "expected ')', found '{'"}},
+		}, nil
+	case TranslateAnalysis:
+		return TranslateAnalysisData{
+			ProjectName: "sample",
+			Language:    "Japanese",
+			Analysis:    "summary: A sample project\n",
+		}, nil
+	case TranslateChapter:
+		return TranslateChapterData{
+			Language:    "Japanese",
+			Title:       "ワークフロー",
+			Placeholder: "@@CODE_BLOCK_0@@",
+			Chapter:     "# Workflow\n\n@@CODE_BLOCK_0@@\n",
+		}, nil
+	case CorrectReferences:
+		return CorrectReferencesData{
+			ProjectName: "sample",
+			Unverified:  []types.SymbolReference{{Name: "store.Fetch", Where: "code", Suggestions: []string{"store.Get"}}},
+			Placeholder: "@@CODE_BLOCK_0@@",
+			Chapter:     "# Workflow\n\nCall `store.Fetch()` first.\n\n@@CODE_BLOCK_0@@\n",
+		}, nil
+	case CritiqueChapter:
+		return CritiqueChapterData{
+			ProjectName:      "sample",
+			Name:             "Workflow",
+			Description:      "Orchestrates the pipeline",
+			Files:            "### File: main.go\n```\npackage main\n```\n",
+			PreviousChapters: previous,
+			Chapter:          "# Workflow\n",
+			MinScore:         config.MinCriticScore,
+			MaxScore:         config.MaxCriticScore,
+		}, nil
+	case ReviseChapter:
+		return ReviseChapterData{
+			ProjectName: "sample",
+			Name:        "Workflow",
+			Files:       "### File: main.go\n```\npackage main\n```\n",
+			Critique:    types.Critique{Accuracy: 2, Clarity: 4, Coverage: 3, Novelty: 5, Score: 3.5, Feedback: []string{"Explain Run"}},
+			Placeholder: "@@CODE_BLOCK_0@@",
+			Chapter:     "# Workflow\n\n@@CODE_BLOCK_0@@\n",
+			MinScore:    config.MinCriticScore,
+			MaxScore:    config.MaxCriticScore,
+		}, nil
+	case SummarizeChapter:
+		return SummarizeChapterData{
+			ProjectName: "sample",
+			Chapter:     "# Workflow\n\nThe workflow runs each stage durably.\n",
+			MaxConcepts: 8,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown prompt template %q", name)
+}
@@ -0,0 +1,191 @@
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// Template names, one per LLM call. An override directory holds files named
+// <name>.tmpl.
+const (
+	Abstractions      = "abstractions"
+	Relationships     = "relationships"
+	Order             = "order"
+	Chapter           = "chapter"
+	TranslateAnalysis = "translate_analysis"
+	TranslateChapter  = "translate_chapter"
+	CorrectReferences = "correct_references"
+	CritiqueChapter   = "critique_chapter"
+	ReviseChapter     = "revise_chapter"
+	SummarizeChapter  = "summarize_chapter"
+)
+
+// Names lists every template in pipeline order
+var Names = []string{Abstractions, Relationships, Order, Chapter, TranslateAnalysis, TranslateChapter, CorrectReferences, CritiqueChapter, ReviseChapter, SummarizeChapter}
+
+// Extension is the file extension of template files
+const Extension = ".tmpl"
+
+// SourceEmbedded marks a template compiled into the binary
+const SourceEmbedded = "embedded"
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+// versionHeader is the required first action of every template, e.g.
+// {{- /* version: 3 */ -}}
+var versionHeader = regexp.MustCompile(`^\{\{-?\s*/\*\s*version:\s*(\S+)\s*\*/\s*-?\}\}`)
+
+// Template is a parsed prompt: the main template renders the user prompt and
+// its "system" template the system prompt
+type Template struct {
+	types.PromptVersion
+	tmpl *template.Template
+}
+
+// Load returns the named template from dir, falling back to the embedded
+// one when dir is empty or has no <name>.tmpl
+func Load(dir string, name string) (*Template, error) {
+	if !isKnown(name) {
+		return nil, fmt.Errorf("unknown prompt template %q", name)
+	}
+
+	source := SourceEmbedded
+	text, err := embedded.ReadFile("templates/" + name + Extension)
+	if dir != "" {
+		path := filepath.Join(dir, name+Extension)
+		override, readErr := os.ReadFile(path)
+		switch {
+		case readErr == nil:
+			source, text, err = path, override, nil
+		case !errors.Is(readErr, fs.ErrNotExist):
+			return nil, fmt.Errorf("failed to read prompt template: %w", readErr)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded prompt template %s: %w", name, err)
+	}
+	return parse(name, source, string(text))
+}
+
+// LoadAll loads every template, checking that each one parses
+func LoadAll(dir string) ([]*Template, error) {
+	templates := make([]*Template, 0, len(Names))
+	for _, name := range Names {
+		t, err := Load(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// Versions loads every template and returns their versions, for run reports
+func Versions(dir string) ([]types.PromptVersion, error) {
+	templates, err := LoadAll(dir)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]types.PromptVersion, len(templates))
+	for i, t := range templates {
+		versions[i] = t.PromptVersion
+	}
+	return versions, nil
+}
+
+// Render loads the named template and renders the user and system prompts.
+// Errors are caused by a broken override and will not go away on retry.
+func Render(dir string, name string, data any) (prompt string, system string, err error) {
+	t, err := Load(dir, name)
+	if err != nil {
+		return "", "", err
+	}
+	return t.Render(data)
+}
+
+// Render executes the template against data
+func (t *Template) Render(data any) (prompt string, system string, err error) {
+	var promptBuf, systemBuf bytes.Buffer
+	if err := t.tmpl.Execute(&promptBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render prompt %s (%s): %w", t.Name, t.Source, err)
+	}
+	if err := t.tmpl.ExecuteTemplate(&systemBuf, "system", data); err != nil {
+		return "", "", fmt.Errorf("failed to render system prompt %s (%s): %w", t.Name, t.Source, err)
+	}
+	return promptBuf.String(), strings.TrimSpace(systemBuf.String()), nil
+}
+
+// Lint renders t with its sample data, requiring non-empty prompts, so a
+// template that parses but fails at render time is caught before a run
+func (t *Template) Lint() error {
+	data, err := Sample(t.Name)
+	if err != nil {
+		return err
+	}
+	prompt, system, err := t.Render(data)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return fmt.Errorf("renders an empty prompt")
+	}
+	if system == "" {
+		return fmt.Errorf("renders an empty system prompt")
+	}
+	return nil
+}
+
+// Embedded returns the text of an embedded template, for exporting
+func Embedded(name string) ([]byte, error) {
+	return embedded.ReadFile("templates/" + name + Extension)
+}
+
+// parse checks the version header and the system template
+func parse(name string, source string, text string) (*Template, error) {
+	match := versionHeader.FindStringSubmatch(text)
+	if match == nil {
+		return nil, fmt.Errorf("prompt template %s (%s) must start with a version header such as {{- /* version: 1 */ -}}", name, source)
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s (%s): %w", name, source, err)
+	}
+	if tmpl.Lookup("system") == nil {
+		return nil, fmt.Errorf("prompt template %s (%s) does not define a \"system\" template", name, source)
+	}
+
+	digest := sha256.Sum256([]byte(text))
+	return &Template{
+		PromptVersion: types.PromptVersion{
+			Name:    name,
+			Version: match[1],
+			Source:  source,
+			Digest:  hex.EncodeToString(digest[:])[:12],
+		},
+		tmpl: tmpl,
+	}, nil
+}
+
+// isKnown reports whether name is one of Names
+func isKnown(name string) bool {
+	for _, known := range Names {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
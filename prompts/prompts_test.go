@@ -0,0 +1,195 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeOverride writes <name>.tmpl into dir
+func writeOverride(t *testing.T, dir string, name string, text string) string {
+	t.Helper()
+	path := filepath.Join(dir, name+Extension)
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// embeddedText returns the built-in text of a template
+func embeddedText(t *testing.T, name string) string {
+	t.Helper()
+	text, err := Embedded(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(text)
+}
+
+func TestLoadOverride(t *testing.T) {
+	builtIn, err := Load("", Chapter)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		overrides map[string]string // Template name → text written to the override dir
+		noDir     bool
+		source    string // "" for the override file
+		builtIn   bool   // Digest matches the built-in template
+	}{
+		{
+			name:    "no override directory",
+			noDir:   true,
+			source:  SourceEmbedded,
+			builtIn: true,
+		},
+		{
+			name:      "missing override falls back to the built-in template",
+			overrides: map[string]string{Order: "{{- /* version: 9 */ -}}{{define \"system\"}}s{{end}}order"},
+			source:    SourceEmbedded,
+			builtIn:   true,
+		},
+		{
+			name:      "override replaces the built-in template",
+			overrides: map[string]string{Chapter: "{{- /* version: 9 */ -}}{{define \"system\"}}s{{end}}{{.Name}}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := ""
+			if !tt.noDir {
+				dir = t.TempDir()
+				for name, text := range tt.overrides {
+					writeOverride(t, dir, name, text)
+				}
+			}
+
+			got, err := Load(dir, Chapter)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			source := tt.source
+			if source == "" {
+				source = filepath.Join(dir, Chapter+Extension)
+			}
+			if got.Source != source {
+				t.Errorf("source = %q, want %q", got.Source, source)
+			}
+			if same := got.Digest == builtIn.Digest; same != tt.builtIn {
+				t.Errorf("digest %s same as built-in %s = %v, want %v", got.Digest, builtIn.Digest, same, tt.builtIn)
+			}
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{
+			name: "copy of the built-in template",
+			text: embeddedText(t, SummarizeChapter),
+		},
+		{
+			name:    "missing version header",
+			text:    "{{define \"system\"}}s{{end}}Summarize {{.Chapter}}",
+			wantErr: "must start with a version header",
+		},
+		{
+			name:    "syntax error",
+			text:    "{{- /* version: 2 */ -}}{{define \"system\"}}s{{end}}Summarize {{.Chapter",
+			wantErr: "invalid prompt template",
+		},
+		{
+			name:    "no system template",
+			text:    "{{- /* version: 2 */ -}}Summarize {{.Chapter}}",
+			wantErr: `does not define a "system" template`,
+		},
+		{
+			name:    "unknown field",
+			text:    "{{- /* version: 2 */ -}}{{define \"system\"}}s{{end}}Summarize {{.Chapters}}",
+			wantErr: "failed to render prompt",
+		},
+		{
+			name:    "empty system prompt",
+			text:    "{{- /* version: 2 */ -}}{{define \"system\"}} {{end}}Summarize {{.Chapter}}",
+			wantErr: "empty system prompt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeOverride(t, dir, SummarizeChapter, tt.text)
+
+			tmpl, err := Load(dir, SummarizeChapter)
+			if err == nil {
+				err = tmpl.Lint()
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("lint: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("lint error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuiltInTemplatesLint(t *testing.T) {
+	templates, err := LoadAll("")
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	for _, tmpl := range templates {
+		if err := tmpl.Lint(); err != nil {
+			t.Errorf("%s: %v", tmpl.Name, err)
+		}
+	}
+}
+
+func TestVersionsDigestTracksOverrides(t *testing.T) {
+	digest := func(t *testing.T, dir string) string {
+		t.Helper()
+		versions, err := Versions(dir)
+		if err != nil {
+			t.Fatalf("Versions: %v", err)
+		}
+		for _, v := range versions {
+			if v.Name == Chapter {
+				return v.Digest
+			}
+		}
+		t.Fatalf("Versions has no %s template", Chapter)
+		return ""
+	}
+
+	builtIn := digest(t, "")
+	dir := t.TempDir()
+	text := embeddedText(t, Chapter)
+
+	writeOverride(t, dir, Chapter, text)
+	if got := digest(t, dir); got != builtIn {
+		t.Errorf("unedited override digest = %s, want the built-in %s", got, builtIn)
+	}
+
+	writeOverride(t, dir, Chapter, text+"\nKeep it short.")
+	edited := digest(t, dir)
+	if edited == builtIn {
+		t.Errorf("edited override digest = %s, want it to differ from the built-in one", edited)
+	}
+
+	writeOverride(t, dir, Chapter, text+"\nKeep it very short.")
+	if got := digest(t, dir); got == edited {
+		t.Errorf("digest did not change when the override changed again")
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/pithomlabs/cb2utorial/config"
 	"github.com/pithomlabs/cb2utorial/llm"
+	"github.com/pithomlabs/cb2utorial/prompts"
 	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
 	restate "github.com/restatedev/sdk-go"
 	"gopkg.in/yaml.v3"
 )
@@ -26,11 +29,22 @@ func (s AbstractionAnalyzerService) AnalyzeAbstractions(ctx restate.Context, inp
 	if len(input.Files) == 0 {
 		return types.AnalyzeAbstractionsOutput{}, fmt.Errorf("no files provided")
 	}
+	// Callers that bypass config.ApplyDefaults get the default limit rather
+	// than a limit of zero, which would discard every abstraction
+	if input.MaxAbstractions <= 0 {
+		input.MaxAbstractions = config.DefaultMaxAbstractions
+	}
+
+	// Only hashes travel between services; read the contents locally
+	files, err := loadContents(input.Files)
+	if err != nil {
+		return types.AnalyzeAbstractionsOutput{}, err
+	}
 
-	// Build file context with indices
+	// Build file context
 	var contextBuilder strings.Builder
-	for _, file := range input.Files {
-		contextBuilder.WriteString(fmt.Sprintf("--- File Index %d: %s ---\n", file.Index, file.Path))
+	for _, file := range files {
+		contextBuilder.WriteString(fmt.Sprintf("--- File: %s ---\n", file.Path))
 		// Truncate very long files for context
 		content := file.Content
 		if len(content) > 5000 {
@@ -43,72 +57,73 @@ func (s AbstractionAnalyzerService) AnalyzeAbstractions(ctx restate.Context, inp
 	// Build file listing for reference
 	var fileListBuilder strings.Builder
 	for _, file := range input.Files {
-		fileListBuilder.WriteString(fmt.Sprintf("- %d # %s\n", file.Index, file.Path))
+		fileListBuilder.WriteString(fmt.Sprintf("- %s\n", file.Path))
 	}
 
-	// Create LLM prompt
-	prompt := fmt.Sprintf(`You are analyzing the codebase for project "%s".
-
-FILES:
-%s
-
-FILE LISTING (for reference):
-%s
-
-Your task: Identify the 5-10 core abstractions/concepts in this codebase.
-
-For each abstraction, provide:
-- name: A clear, concise name
-- description: Beginner-friendly explanation (1-2 sentences)
-- files: List of file INDICES (numbers only) related to this abstraction
-
-Output YAML format:
-"""yaml
-- name: "CoreAbstraction"
-  description: "What this abstraction represents and why it matters"
-  files: [0, 3, 5]
-- name: "AnotherConcept"
-  description: "Another key concept"
-  files: [1, 2]
-"""
+	// Pinned abstractions are kept verbatim and count toward the limit
+	if len(input.Pinned) > input.MaxAbstractions {
+		return types.AnalyzeAbstractionsOutput{}, restate.TerminalError(fmt.Errorf("%d pinned abstractions exceed the limit of %d", len(input.Pinned), input.MaxAbstractions), 400)
+	}
+	pinned := make([]types.Abstraction, len(input.Pinned))
+	for i, p := range input.Pinned {
+		indices, err := utils.MatchFileIndices(p.Files, input.Files)
+		if err != nil {
+			return types.AnalyzeAbstractionsOutput{}, restate.TerminalError(fmt.Errorf("pinned abstraction %q: %w", p.Name, err), 400)
+		}
+		if len(p.Files) > 0 && len(indices) == 0 {
+			return types.AnalyzeAbstractionsOutput{}, restate.TerminalError(fmt.Errorf("pinned abstraction %q: files %s match none of the files read", p.Name, strings.Join(p.Files, ", ")), 400)
+		}
+		pinned[i] = types.Abstraction{
+			Name:        p.Name,
+			Description: p.Description,
+			FileIndices: indices,
+			Files:       utils.FileRefsFromIndices(indices, input.Files),
+		}
+	}
+	if len(pinned) == input.MaxAbstractions && allHaveFiles(pinned) {
+		// Nothing left for the LLM to choose
+		return types.AnalyzeAbstractionsOutput{Abstractions: numberAbstractions(pinned)}, nil
+	}
 
-Focus on the most important abstractions that a newcomer should understand.
-Return ONLY the YAML, no other text.
-`, input.ProjectName, contextBuilder.String(), fileListBuilder.String())
+	// Create LLM prompt; the LLM only assigns files to pinned concepts
+	persona := config.ResolvePersona(input.Audience)
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.Abstractions, prompts.AbstractionsData{
+		ProjectName: input.ProjectName,
+		Audience:    persona.Description,
+		Selection:   persona.Selection,
+		Files:       contextBuilder.String(),
+		FileListing: fileListBuilder.String(),
+		Pinned:      input.Pinned,
+		Blocked:     input.Blocked,
+		Remaining:   input.MaxAbstractions - len(pinned),
+	})
+	if err != nil {
+		return types.AnalyzeAbstractionsOutput{}, restate.TerminalError(err, 400)
+	}
 
 	// Call LLM
 	client, err := llm.NewClient()
 	if err != nil {
 		return types.AnalyzeAbstractionsOutput{}, fmt.Errorf("failed to create LLM client: %w", err)
 	}
+	client = client.WithModel(input.Model)
 
-	response, err := client.CallLLM(context.Background(), prompt, "You are a code analysis expert helping developers understand unfamiliar codebases.")
+	response, err := client.CallLLM(context.Background(), prompt, systemPrompt)
 	if err != nil {
 		return types.AnalyzeAbstractionsOutput{}, fmt.Errorf("LLM call failed: %w", err)
 	}
 
 	// Parse YAML response
 	type yamlAbstraction struct {
-		Name        string `yaml:"name"`
-		Description string `yaml:"description"`
-		Files       []int  `yaml:"files"`
+		Name        string        `yaml:"name"`
+		Description string        `yaml:"description"`
+		Files       []interface{} `yaml:"files"` // Paths; older prompts returned indices
 	}
 
 	var yamlAbstractions []yamlAbstraction
 
 	// Extract YAML block if wrapped in code fence
-	yamlContent := response
-	if strings.Contains(response, "```yaml") {
-		parts := strings.Split(response, "```yaml")
-		if len(parts) > 1 {
-			yamlContent = strings.Split(parts[1], "```")[0]
-		}
-	} else if strings.Contains(response, "```") {
-		parts := strings.Split(response, "```")
-		if len(parts) > 1 {
-			yamlContent = parts[1]
-		}
-	}
+	yamlContent := utils.ExtractYAML(response)
 
 	err = yaml.Unmarshal([]byte(yamlContent), &yamlAbstractions)
 	if err != nil {
@@ -116,36 +131,104 @@ Return ONLY the YAML, no other text.
 	}
 
 	// Validate and convert to output format
-	if len(yamlAbstractions) == 0 {
+	if len(yamlAbstractions) == 0 && len(pinned) == 0 {
 		return types.AnalyzeAbstractionsOutput{}, fmt.Errorf("no abstractions identified")
 	}
 
-	if len(yamlAbstractions) > input.MaxAbstractions {
-		yamlAbstractions = yamlAbstractions[:input.MaxAbstractions]
+	pinnedByKey := make(map[string]int, len(pinned))
+	for i, p := range pinned {
+		pinnedByKey[utils.ConceptKey(p.Name)] = i
 	}
 
-	abstractions := make([]types.Abstraction, len(yamlAbstractions))
-	for i, ya := range yamlAbstractions {
-		// Validate file indices
-		for _, fileIdx := range ya.Files {
-			if fileIdx < 0 || fileIdx >= len(input.Files) {
-				return types.AnalyzeAbstractionsOutput{}, fmt.Errorf("invalid file index %d in abstraction %s", fileIdx, ya.Name)
+	abstractions := pinned
+	for _, ya := range yamlAbstractions {
+		refs, err := parseFileRefs(ya.Files, input.Files)
+		if err != nil {
+			return types.AnalyzeAbstractionsOutput{}, fmt.Errorf("abstraction %s: %w", ya.Name, err)
+		}
+		resolved, _ := utils.ResolveAbstractionFiles(types.Abstraction{Name: ya.Name, Files: refs}, input.Files)
+		if len(refs) > 0 && len(resolved.Files) == 0 {
+			return types.AnalyzeAbstractionsOutput{}, fmt.Errorf("abstraction %s: none of the files %v exist", ya.Name, ya.Files)
+		}
+
+		// A required concept keeps its name and description; the LLM's
+		// files are used only when the user gave none
+		if i, ok := pinnedByKey[utils.ConceptKey(ya.Name)]; ok {
+			if len(abstractions[i].Files) == 0 {
+				abstractions[i].Files = resolved.Files
+				abstractions[i].FileIndices = resolved.FileIndices
+			}
+			if abstractions[i].Description == "" {
+				abstractions[i].Description = ya.Description
 			}
+			continue
+		}
+		if utils.IsBlockedConcept(ya.Name, input.Blocked) || len(abstractions) >= input.MaxAbstractions {
+			continue
 		}
 
-		abstractions[i] = types.Abstraction{
-			Index:       i,
+		abstractions = append(abstractions, types.Abstraction{
 			Name:        ya.Name,
 			Description: ya.Description,
-			FileIndices: ya.Files,
-		}
+			FileIndices: resolved.FileIndices,
+			Files:       resolved.Files,
+		})
 	}
 
 	return types.AnalyzeAbstractionsOutput{
-		Abstractions: abstractions,
+		Abstractions: numberAbstractions(abstractions),
 	}, nil
 }
 
+// allHaveFiles reports whether every abstraction references at least one file
+func allHaveFiles(abstractions []types.Abstraction) bool {
+	for _, abs := range abstractions {
+		if len(abs.FileIndices) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// numberAbstractions sets each abstraction's Index to its position
+func numberAbstractions(abstractions []types.Abstraction) []types.Abstraction {
+	for i := range abstractions {
+		abstractions[i].Index = i
+	}
+	return abstractions
+}
+
+// parseFileRefs converts the LLM's file entries to path references. Entries
+// are paths, optionally with ":start-end" or "#Symbol"; bare indices and
+// "0 # path" entries are still accepted.
+func parseFileRefs(values []interface{}, files []types.FileContent) ([]types.FileRef, error) {
+	var refs []types.FileRef
+	for _, value := range values {
+		if s, ok := value.(string); !ok || isIndexEntry(s) {
+			idx, err := extractIndex(value)
+			if err != nil {
+				return nil, err
+			}
+			if idx < 0 || idx >= len(files) {
+				return nil, fmt.Errorf("invalid file index %d", idx)
+			}
+			refs = append(refs, types.FileRef{Path: files[idx].Path})
+			continue
+		}
+		if ref := utils.ParseFileRef(value.(string)); ref.Path != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// isIndexEntry reports whether s is an index ("3") or "3 # path" entry
+func isIndexEntry(s string) bool {
+	head, _, _ := strings.Cut(s, "#")
+	_, err := strconv.Atoi(strings.TrimSpace(head))
+	return err == nil
+}
+
 // extractIndex handles both int and "0 # Name" formats
 func extractIndex(value interface{}) (int, error) {
 	switch v := value.(type) {
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/llm"
+	"github.com/pithomlabs/cb2utorial/prompts"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	restate "github.com/restatedev/sdk-go"
+	"gopkg.in/yaml.v3"
+)
+
+// ChapterCriticService scores written chapters against a rubric and revises
+// them according to the critique
+type ChapterCriticService struct{}
+
+// ServiceName returns the service name for registration
+func (s ChapterCriticService) ServiceName() string {
+	return "ChapterCritic"
+}
+
+// CritiqueChapter scores a chapter for accuracy against the source, clarity,
+// coverage of the abstraction's files and novelty over earlier chapters
+func (s ChapterCriticService) CritiqueChapter(ctx restate.Context, input types.CritiqueChapterInput) (types.Critique, error) {
+	// Validate input
+	if input.Chapter.Content == "" {
+		return types.Critique{}, restate.TerminalError(fmt.Errorf("chapter %d has no content", input.Chapter.ChapterNumber), 400)
+	}
+
+	fileContext, err := referencedCode(input.Abstraction, input.Files)
+	if err != nil {
+		return types.Critique{}, err
+	}
+
+	// Create LLM prompt
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.CritiqueChapter, prompts.CritiqueChapterData{
+		ProjectName:      input.ProjectName,
+		Name:             input.Abstraction.Name,
+		Description:      input.Abstraction.Description,
+		Files:            fileContext,
+		PreviousChapters: input.PreviousChapters,
+		Chapter:          input.Chapter.Content,
+		MinScore:         config.MinCriticScore,
+		MaxScore:         config.MaxCriticScore,
+	})
+	if err != nil {
+		return types.Critique{}, restate.TerminalError(err, 400)
+	}
+
+	// Call LLM
+	client, err := llm.NewClient()
+	if err != nil {
+		return types.Critique{}, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	client = client.WithModel(input.Model)
+
+	response, err := client.CallLLM(context.Background(), prompt, systemPrompt)
+	if err != nil {
+		return types.Critique{}, fmt.Errorf("LLM call failed: %w", err)
+	}
+
+	// Extract YAML block
+	yamlContent := utils.ExtractYAML(response)
+
+	var critique struct {
+		Accuracy int      `yaml:"accuracy"`
+		Clarity  int      `yaml:"clarity"`
+		Coverage int      `yaml:"coverage"`
+		Novelty  int      `yaml:"novelty"`
+		Feedback []string `yaml:"feedback"`
+	}
+	if err := yaml.Unmarshal([]byte(yamlContent), &critique); err != nil {
+		return types.Critique{}, fmt.Errorf("failed to parse YAML response: %w\nResponse: %s", err, response)
+	}
+
+	// A missing or out-of-range score is an incomplete answer and is retried
+	scores := map[string]int{"accuracy": critique.Accuracy, "clarity": critique.Clarity, "coverage": critique.Coverage, "novelty": critique.Novelty}
+	for name, score := range scores {
+		if score < config.MinCriticScore || score > config.MaxCriticScore {
+			return types.Critique{}, fmt.Errorf("critique of chapter %d: %s score %d is not between %d and %d",
+				input.Chapter.ChapterNumber, name, score, config.MinCriticScore, config.MaxCriticScore)
+		}
+	}
+
+	var feedback []string
+	for _, item := range critique.Feedback {
+		if item = strings.TrimSpace(item); item != "" {
+			feedback = append(feedback, item)
+		}
+	}
+
+	return types.Critique{
+		Accuracy: critique.Accuracy,
+		Clarity:  critique.Clarity,
+		Coverage: critique.Coverage,
+		Novelty:  critique.Novelty,
+		Score:    float64(critique.Accuracy+critique.Clarity+critique.Coverage+critique.Novelty) / 4,
+		Feedback: feedback,
+	}, nil
+}
+
+// ReviseChapter rewrites a chapter to address a critique. Cited code blocks
+// are replaced by placeholders so source code cannot change.
+func (s ChapterCriticService) ReviseChapter(ctx restate.Context, input types.ReviseChapterInput) (types.WriteChapterOutput, error) {
+	// Validate input
+	if input.Chapter.Content == "" {
+		return types.WriteChapterOutput{}, restate.TerminalError(fmt.Errorf("chapter %d has no content", input.Chapter.ChapterNumber), 400)
+	}
+
+	fileContext, err := referencedCode(input.Abstraction, input.Files)
+	if err != nil {
+		return types.WriteChapterOutput{}, err
+	}
+	protected, blocks := protectCitedBlocks(input.Chapter.Content)
+
+	// Create LLM prompt
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.ReviseChapter, prompts.ReviseChapterData{
+		ProjectName: input.ProjectName,
+		Name:        input.Abstraction.Name,
+		Files:       fileContext,
+		Critique:    input.Critique,
+		Placeholder: codePlaceholder(0),
+		Chapter:     protected,
+		MinScore:    config.MinCriticScore,
+		MaxScore:    config.MaxCriticScore,
+	})
+	if err != nil {
+		return types.WriteChapterOutput{}, restate.TerminalError(err, 400)
+	}
+
+	// Call LLM
+	client, err := llm.NewClient()
+	if err != nil {
+		return types.WriteChapterOutput{}, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	client = client.WithModel(input.Model)
+
+	// The chapter is returned unrevised if every answer dropped cited code.
+	// Code the LLM changed or added is cited like the writer's.
+	response, ok, err := callKeepingCode(client, prompt, systemPrompt, blocks)
+	if err != nil {
+		return types.WriteChapterOutput{}, err
+	}
+	if !ok {
+		chapter := input.Chapter
+		chapter.Unrevised = true
+		return chapter, nil
+	}
+	chapter, err := citeRewrite(response, blocks, input.Chapter, input.Files, input.SourceURL)
+	if err != nil {
+		return types.WriteChapterOutput{}, err
+	}
+	chapter.Unrevised = false
+	return chapter, nil
+}
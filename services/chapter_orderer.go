@@ -3,10 +3,12 @@ package services
 import (
 	"context"
 	"fmt"
-	"strings"
 
+	"github.com/pithomlabs/cb2utorial/config"
 	"github.com/pithomlabs/cb2utorial/llm"
+	"github.com/pithomlabs/cb2utorial/prompts"
 	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
 	restate "github.com/restatedev/sdk-go"
 	"gopkg.in/yaml.v3"
 )
@@ -26,61 +28,40 @@ func (s ChapterOrdererService) OrderChapters(ctx restate.Context, input types.Or
 		return types.OrderChaptersOutput{}, fmt.Errorf("no abstractions provided")
 	}
 
-	// Build abstraction listing
-	var abstractionListBuilder strings.Builder
-	for _, abs := range input.Abstractions {
-		abstractionListBuilder.WriteString(fmt.Sprintf("- %d # %s: %s\n", abs.Index, abs.Name, abs.Description))
-	}
-
-	// Build relationship context
-	var relationshipBuilder strings.Builder
-	relationshipBuilder.WriteString(fmt.Sprintf("Project Summary: %s\n\n", input.Relationships.Summary))
-	relationshipBuilder.WriteString("Relationships:\n")
+	// Name both ends of each relationship
+	relationships := make([]prompts.RelationshipLine, 0, len(input.Relationships.Details))
 	for _, rel := range input.Relationships.Details {
-		fromName := input.Abstractions[rel.FromIndex].Name
-		toName := input.Abstractions[rel.ToIndex].Name
-		relationshipBuilder.WriteString(fmt.Sprintf("- %d (%s) → %d (%s): %s\n",
-			rel.FromIndex, fromName, rel.ToIndex, toName, rel.Label))
+		relationships = append(relationships, prompts.RelationshipLine{
+			From:     rel.FromIndex,
+			FromName: input.Abstractions[rel.FromIndex].Name,
+			To:       rel.ToIndex,
+			ToName:   input.Abstractions[rel.ToIndex].Name,
+			Label:    rel.Label,
+		})
 	}
 
 	// Create LLM prompt
-	prompt := fmt.Sprintf(`You are creating a tutorial for the "%s" project.
-
-ABSTRACTIONS:
-%s
-
-CONTEXT:
-%s
-
-Your task: Determine the best order to explain these abstractions to a beginner.
-
-Teaching strategy:
-- Start with foundational concepts or user-facing entry points
-- Progress to implementation details
-- Ensure dependencies are explained before they're used
-- Make it pedagogically sound
-
-Return a YAML list of abstraction INDICES in teaching order.
-Each entry should be: "index # Name" for clarity.
-
-Example output:
-"""yaml
-- 2 # EntryPoint
-- 0 # Foundation  
-- 1 # Implementation
-"""
-
-IMPORTANT: Include ALL abstractions exactly once.
-Return ONLY the YAML list, no other text.
-`, input.ProjectName, abstractionListBuilder.String(), relationshipBuilder.String())
+	persona := config.ResolvePersona(input.Audience)
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.Order, prompts.OrderData{
+		ProjectName:   input.ProjectName,
+		Audience:      persona.Description,
+		Ordering:      persona.Ordering,
+		Abstractions:  input.Abstractions,
+		Summary:       input.Relationships.Summary,
+		Relationships: relationships,
+	})
+	if err != nil {
+		return types.OrderChaptersOutput{}, restate.TerminalError(err, 400)
+	}
 
 	// Call LLM
 	client, err := llm.NewClient()
 	if err != nil {
 		return types.OrderChaptersOutput{}, fmt.Errorf("failed to create LLM client: %w", err)
 	}
+	client = client.WithModel(input.Model)
 
-	response, err := client.CallLLM(context.Background(), prompt, "You are an expert technical educator.")
+	response, err := client.CallLLM(context.Background(), prompt, systemPrompt)
 	if err != nil {
 		return types.OrderChaptersOutput{}, fmt.Errorf("LLM call failed: %w", err)
 	}
@@ -89,18 +70,7 @@ Return ONLY the YAML list, no other text.
 	var yamlIndices []interface{} // Can be int or "0 # Name"
 
 	// Extract YAML block
-	yamlContent := response
-	if strings.Contains(response, "```yaml") {
-		parts := strings.Split(response, "```yaml")
-		if len(parts) > 1 {
-			yamlContent = strings.Split(parts[1], "```")[0]
-		}
-	} else if strings.Contains(response, "```") {
-		parts := strings.Split(response, "```")
-		if len(parts) > 1 {
-			yamlContent = parts[1]
-		}
-	}
+	yamlContent := utils.ExtractYAML(response)
 
 	err = yaml.Unmarshal([]byte(yamlContent), &yamlIndices)
 	if err != nil {
@@ -5,9 +5,13 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pithomlabs/cb2utorial/config"
 	"github.com/pithomlabs/cb2utorial/llm"
+	"github.com/pithomlabs/cb2utorial/prompts"
 	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
 	restate "github.com/restatedev/sdk-go"
+	"gopkg.in/yaml.v3"
 )
 
 // ChapterWriterService generates markdown tutorial chapters
@@ -26,124 +30,158 @@ func (s ChapterWriterService) WriteChapter(ctx restate.Context, input types.Writ
 	}
 
 	// Build context of related files
-	var fileContextBuilder strings.Builder
-	fileContextBuilder.WriteString("Related code files:\n\n")
-
-	for _, fileIdx := range input.Abstraction.FileIndices {
-		if fileIdx >= len(input.Files) {
-			continue // Skip invalid indices
-		}
-
-		file := input.Files[fileIdx]
-		fileContextBuilder.WriteString(fmt.Sprintf("### File: %s\n", file.Path))
-		fileContextBuilder.WriteString("```\n")
-
-		// Truncate very long files
-		content := file.Content
-		const maxContentLength = 8000
-		if len(content) > maxContentLength {
-			content = content[:maxContentLength] + "\n... (truncated for brevity)"
-		}
-
-		fileContextBuilder.WriteString(content)
-		fileContextBuilder.WriteString("\n```\n\n")
-	}
-
-	// Build context of previous chapters
-	var previousChaptersContext string
-	if len(input.PreviousChapters) > 0 {
-		var prevBuilder strings.Builder
-		prevBuilder.WriteString("\n\nPREVIOUSLY COVERED CONCEPTS (for reference, don't repeat):\n")
-		for _, prev := range input.PreviousChapters {
-			prevBuilder.WriteString(fmt.Sprintf("- %s: %s\n", prev.Name, prev.Summary))
-		}
-		previousChaptersContext = prevBuilder.String()
+	fileContext, err := referencedCode(input.Abstraction, input.Files)
+	if err != nil {
+		return types.WriteChapterOutput{}, err
 	}
 
 	// Create LLM prompt
-	prompt := fmt.Sprintf(`You are writing a tutorial chapter for the "%s" project.
-
-TARGET AUDIENCE: Developers new to this codebase who want to understand it quickly.
-
-ABSTRACTION TO EXPLAIN:
-Name: %s
-Description: %s
-
-%s
-
-%s
-
-Your task: Write a comprehensive, beginner-friendly tutorial chapter explaining this abstraction.
-
-REQUIREMENTS:
-1. Use clear, simple language
-2. Include code examples from the provided files
-3. Use analogies or real-world examples where helpful
-4. Explain WHY this abstraction exists, not just WHAT it does
-5. Break down complex concepts into digestible parts
-6. Format as markdown
-
-STRUCTURE YOUR CHAPTER:
-# %s
-
-[Brief introduction - what is this and why does it matter?]
-
-## What It Does
+	persona := config.ResolvePersona(input.Audience)
+	language := input.Language
+	if language == "" {
+		language = config.DefaultLanguage
+	}
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.Chapter, prompts.ChapterData{
+		ProjectName:      input.ProjectName,
+		Audience:         persona.Description,
+		Guidance:         persona.Writing,
+		Language:         language,
+		Name:             input.Abstraction.Name,
+		Description:      input.Abstraction.Description,
+		Files:            fileContext,
+		PreviousChapters: input.PreviousChapters,
+		Sections:         config.ChapterSections(input.Style),
+		Style:            input.Style,
+		Violations:       input.StyleViolations,
+		SnippetErrors:    input.SnippetErrors,
+	})
+	if err != nil {
+		return types.WriteChapterOutput{}, restate.TerminalError(err, 400)
+	}
 
-[Clear explanation of the abstraction's purpose]
+	// Call LLM
+	client, err := llm.NewClient()
+	if err != nil {
+		return types.WriteChapterOutput{}, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	client = client.WithModel(input.Model)
 
-## Key Code
+	response, err := client.CallLLM(context.Background(), prompt, systemPrompt)
+	if err != nil {
+		return types.WriteChapterOutput{}, fmt.Errorf("LLM call failed: %w", err)
+	}
 
-[Show relevant code snippets with explanations]
+	content := unwrapMarkdown(response)
 
-## How It Works
+	// Trace quoted code to its source lines; code found nowhere is flagged
+	content, citations, err := citeCode(content, input.Files, input.SourceURL)
+	if err != nil {
+		return types.WriteChapterOutput{}, err
+	}
 
-[Step-by-step explanation of the implementation]
+	return types.WriteChapterOutput{
+		ChapterNumber: input.ChapterNumber,
+		Title:         input.Abstraction.Name,
+		Content:       content,
+		Citations:     citations,
+	}, nil
+}
 
-## Key Takeaways
+// maxConcepts bounds the concepts recorded per chapter
+const maxConcepts = 8
 
-- [Important point 1]
-- [Important point 2]
-- [Important point 3]
+// SummarizeChapter summarizes a written chapter and lists the concepts it
+// introduces, so later chapters can build on it without repeating it
+func (s ChapterWriterService) SummarizeChapter(ctx restate.Context, input types.SummarizeChapterInput) (types.ChapterSummary, error) {
+	// Validate input
+	if input.Chapter.Content == "" {
+		return types.ChapterSummary{}, restate.TerminalError(fmt.Errorf("chapter %d has no content", input.Chapter.ChapterNumber), 400)
+	}
 
-OUTPUT: Return ONLY the markdown content, no meta-commentary.
-`,
-		input.ProjectName,
-		input.Abstraction.Name,
-		input.Abstraction.Description,
-		fileContextBuilder.String(),
-		previousChaptersContext,
-		input.Abstraction.Name,
-	)
+	// Create LLM prompt
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.SummarizeChapter, prompts.SummarizeChapterData{
+		ProjectName: input.ProjectName,
+		Chapter:     input.Chapter.Content,
+		MaxConcepts: maxConcepts,
+	})
+	if err != nil {
+		return types.ChapterSummary{}, restate.TerminalError(err, 400)
+	}
 
 	// Call LLM
 	client, err := llm.NewClient()
 	if err != nil {
-		return types.WriteChapterOutput{}, fmt.Errorf("failed to create LLM client: %w", err)
+		return types.ChapterSummary{}, fmt.Errorf("failed to create LLM client: %w", err)
 	}
-
-	systemPrompt := "You are an expert technical educator who excels at explaining complex code in simple terms."
+	client = client.WithModel(input.Model)
 
 	response, err := client.CallLLM(context.Background(), prompt, systemPrompt)
 	if err != nil {
-		return types.WriteChapterOutput{}, fmt.Errorf("LLM call failed: %w", err)
+		return types.ChapterSummary{}, fmt.Errorf("LLM call failed: %w", err)
 	}
 
-	// Clean up response (remove any markdown code fences if LLM wrapped the output)
-	content := strings.TrimSpace(response)
-	if strings.HasPrefix(content, "```markdown") {
-		content = strings.TrimPrefix(content, "```markdown")
-		content = strings.TrimSuffix(content, "```")
-		content = strings.TrimSpace(content)
-	} else if strings.HasPrefix(content, "```") {
-		content = strings.TrimPrefix(content, "```")
-		content = strings.TrimSuffix(content, "```")
-		content = strings.TrimSpace(content)
+	// Extract YAML block
+	yamlContent := utils.ExtractYAML(response)
+
+	var parsed struct {
+		Summary  string   `yaml:"summary"`
+		Concepts []string `yaml:"concepts"`
+	}
+	if err := yaml.Unmarshal([]byte(yamlContent), &parsed); err != nil {
+		return types.ChapterSummary{}, fmt.Errorf("failed to parse YAML response: %w\nResponse: %s", err, response)
+	}
+	summary := strings.Join(strings.Fields(parsed.Summary), " ")
+	if summary == "" {
+		return types.ChapterSummary{}, fmt.Errorf("summary of chapter %d is empty", input.Chapter.ChapterNumber)
 	}
 
-	return types.WriteChapterOutput{
-		ChapterNumber: input.ChapterNumber,
-		Title:         input.Abstraction.Name,
-		Content:       content,
+	// Drop blank and repeated concepts
+	var concepts []string
+	seen := make(map[string]bool)
+	for _, concept := range parsed.Concepts {
+		concept = strings.TrimSpace(concept)
+		key := strings.ToLower(concept)
+		if concept == "" || seen[key] || len(concepts) == maxConcepts {
+			continue
+		}
+		seen[key] = true
+		concepts = append(concepts, concept)
+	}
+
+	return types.ChapterSummary{
+		ChapterNumber: input.Chapter.ChapterNumber,
+		Name:          input.Chapter.Title,
+		Summary:       summary,
+		Concepts:      concepts,
 	}, nil
 }
+
+// referencedCode renders the code an abstraction references, one section per
+// file, narrowed to the referenced lines and truncated
+func referencedCode(abstraction types.Abstraction, files []types.FileContent) (string, error) {
+	var builder strings.Builder
+
+	referenced, err := utils.LookupFiles(abstraction, files)
+	if err != nil {
+		return "", restate.TerminalError(err, 400)
+	}
+	for _, rf := range referenced {
+		builder.WriteString(fmt.Sprintf("### File: %s\n", utils.DescribeFileRef(rf.Ref)))
+		builder.WriteString("```\n")
+
+		// Narrow to the referenced lines, then truncate very long files
+		file, err := loadContent(rf.File)
+		if err != nil {
+			return "", err
+		}
+		content := utils.SliceLines(file.Content, rf.Ref.StartLine, rf.Ref.EndLine)
+		const maxContentLength = 8000
+		if len(content) > maxContentLength {
+			content = content[:maxContentLength] + "\n... (truncated for brevity)"
+		}
+
+		builder.WriteString(content)
+		builder.WriteString("\n```\n\n")
+	}
+	return builder.String(), nil
+}
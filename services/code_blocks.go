@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/llm"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+)
+
+// fencedCodeBlock matches a fenced markdown code block
+var fencedCodeBlock = regexp.MustCompile("(?ms)^[ \\t]*```.*?^[ \\t]*```[ \\t]*$")
+
+// maxPlaceholderAttempts bounds the LLM calls made when responses drop code
+// placeholders
+const maxPlaceholderAttempts = 3
+
+// codePlaceholder stands in for the i-th protected code block
+func codePlaceholder(i int) string {
+	return fmt.Sprintf("@@CODE_BLOCK_%d@@", i)
+}
+
+// protectCodeBlocks replaces every code block with a placeholder and
+// returns the blocks in order
+func protectCodeBlocks(content string) (string, []string) {
+	var blocks []string
+	protected := fencedCodeBlock.ReplaceAllStringFunc(content, func(block string) string {
+		blocks = append(blocks, block)
+		return codePlaceholder(len(blocks) - 1)
+	})
+	return protected, blocks
+}
+
+// protectCitedBlocks replaces the code blocks followed by a source citation
+// or a not-found note with placeholders, each together with its note, and
+// returns them in order. These are the blocks the chapter's citations
+// describe.
+func protectCitedBlocks(content string) (string, []string) {
+	var builder strings.Builder
+	var blocks []string
+	last := 0
+	for _, match := range fencedCodeBlock.FindAllStringIndex(content, -1) {
+		rest := strings.TrimLeft(content[match[1]:], " \t\r\n")
+		line := rest[:strings.IndexByte(rest+"\n", '\n')]
+		if !utils.IsCitationLine(line) && !utils.IsNotFoundLine(line) {
+			continue
+		}
+		end := len(content) - len(rest) + len(line)
+		builder.WriteString(content[last:match[0]])
+		builder.WriteString(codePlaceholder(len(blocks)))
+		blocks = append(blocks, content[match[0]:end])
+		last = end
+	}
+	builder.WriteString(content[last:])
+	return builder.String(), blocks
+}
+
+// restoreCodeBlocks puts the protected code blocks back in place of their
+// placeholders
+func restoreCodeBlocks(content string, blocks []string) string {
+	for i, block := range blocks {
+		content = strings.Replace(content, codePlaceholder(i), block, 1)
+	}
+	return content
+}
+
+// keepsPlaceholders reports whether content still has every placeholder
+func keepsPlaceholders(content string, blocks []string) bool {
+	for i := range blocks {
+		if !strings.Contains(content, codePlaceholder(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// unwrapMarkdown removes the code fence an LLM may wrap a markdown answer in
+func unwrapMarkdown(response string) string {
+	content := strings.TrimSpace(response)
+	if strings.HasPrefix(content, "```markdown") {
+		content = strings.TrimPrefix(content, "```markdown")
+		content = strings.TrimSuffix(content, "```")
+		content = strings.TrimSpace(content)
+	} else if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(content, "```")
+		content = strings.TrimSpace(content)
+	}
+	return content
+}
+
+// callKeepingCode asks the LLM to rewrite markdown whose code blocks were
+// replaced by placeholders and returns the answer, placeholders still in
+// place. A response that drops a placeholder would lose code, so it is
+// asked again; after maxPlaceholderAttempts such answers it reports false
+// and the caller keeps the original content.
+func callKeepingCode(client *llm.Client, prompt string, systemPrompt string, blocks []string) (string, bool, error) {
+	for attempt := 0; attempt < maxPlaceholderAttempts; attempt++ {
+		response, err := client.CallLLM(context.Background(), prompt, systemPrompt)
+		if err != nil {
+			return "", false, fmt.Errorf("LLM call failed: %w", err)
+		}
+		if content := unwrapMarkdown(response); keepsPlaceholders(content, blocks) {
+			return content, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// citeCode traces the chapter's quoted code to its source lines, linking
+// citations with the sourceURL template if set
+func citeCode(content string, refs []types.FileContent, sourceURL string) (string, []types.Citation, error) {
+	files, err := loadContents(refs)
+	if err != nil {
+		return "", nil, err
+	}
+	var link func(path string, start int, end int) string
+	if sourceURL != "" {
+		link = func(path string, start int, end int) string {
+			return utils.SourceURL(sourceURL, "", path, start, end)
+		}
+	}
+	content, citations := utils.CiteCodeBlocks(content, files, link)
+	return content, citations, nil
+}
+
+// citeRewrite cites the code a rewrite of chapter changed or added, then
+// restores the protected blocks, whose citations are kept
+func citeRewrite(content string, blocks []string, chapter types.WriteChapterOutput, refs []types.FileContent, sourceURL string) (types.WriteChapterOutput, error) {
+	content, citations, err := citeCode(content, refs, sourceURL)
+	if err != nil {
+		return chapter, err
+	}
+	chapter.Content = restoreCodeBlocks(content, blocks)
+	chapter.Citations = append(append([]types.Citation{}, chapter.Citations...), citations...)
+	return chapter, nil
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+	"os"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// loadContents fills in file contents from the content store. A missing
+// blob will not appear on retry, so it fails the call terminally.
+func loadContents(files []types.FileContent) ([]types.FileContent, error) {
+	loaded, err := utils.LoadContents(files)
+	return loaded, contentError(err)
+}
+
+// loadContent is loadContents for a single file
+func loadContent(file types.FileContent) (types.FileContent, error) {
+	loaded, err := utils.LoadContent(file)
+	return loaded, contentError(err)
+}
+
+// contentError marks content store misses as terminal
+func contentError(err error) error {
+	if err != nil && errors.Is(err, os.ErrNotExist) {
+		return restate.TerminalError(err, 404)
+	}
+	return err
+}
@@ -8,7 +8,10 @@ import (
 	restate "github.com/restatedev/sdk-go"
 )
 
-// FileReaderService reads files from a local directory
+// maxSkippedPerReason caps how many skipped files are listed per reason
+const maxSkippedPerReason = 100
+
+// FileReaderService reads files from a local directory, git ref or archive
 type FileReaderService struct{}
 
 // ServiceName returns the service name for registration
@@ -16,36 +19,93 @@ func (s FileReaderService) ServiceName() string {
 	return "FileReader"
 }
 
-// ReadFiles traverses the local repository and returns indexed file list
+// ReadFiles traverses the local repository (or a git ref within it), a
+// .zip/.tar.gz archive or a Go module in the module cache, and returns
+// indexed file list
 func (s FileReaderService) ReadFiles(ctx restate.Context, input types.ReadFilesInput) (types.ReadFilesOutput, error) {
 	// Validate input
 	if input.RepoPath == "" {
 		return types.ReadFilesOutput{}, fmt.Errorf("repo_path is required")
 	}
 
-	// Walk directory with configured options
-	fileInfos, err := utils.WalkDirectory(utils.WalkDirectoryOptions{
-		RootPath:        input.RepoPath,
+	// Compile detectors up front so a bad custom rule fails before any reading
+	redactor, err := utils.NewRedactor(input.RedactionRules)
+	if err != nil {
+		return types.ReadFilesOutput{}, restate.TerminalError(err, 400)
+	}
+
+	// Module specs (path@version) resolve to the local module cache
+	rootPath, err := utils.ResolveSource(input.RepoPath)
+	if err != nil {
+		return types.ReadFilesOutput{}, restate.TerminalError(err, 404)
+	}
+
+	// Every skip is counted; only the first few per reason are listed, so
+	// an excluded node_modules doesn't bloat the output
+	var skipped []types.SkippedFile
+	skipTotals := make(map[string]int)
+	walkOptions := utils.WalkDirectoryOptions{
+		RootPath:        rootPath,
 		IncludePatterns: input.IncludePatterns,
 		ExcludePatterns: input.ExcludePatterns,
 		MaxFileSize:     input.MaxFileSize,
 		MaxFiles:        input.MaxFiles,
-	})
-	if err != nil {
-		return types.ReadFilesOutput{}, fmt.Errorf("failed to walk directory: %w", err)
+		OnSkip: func(path, reason string) {
+			skipTotals[reason]++
+			if skipTotals[reason] <= maxSkippedPerReason {
+				skipped = append(skipped, types.SkippedFile{Path: path, Reason: reason})
+			}
+		},
 	}
 
-	// Convert to indexed FileContent list
+	var fileInfos []utils.FileInfo
+	var commit string
+	switch {
+	case input.GitRef != "":
+		// Read blobs from the object store so the result is pinned to a commit
+		fileInfos, commit, err = utils.WalkGitRevision(walkOptions, input.GitRef)
+		if err != nil {
+			return types.ReadFilesOutput{}, restate.TerminalError(fmt.Errorf("failed to read git ref %s: %w", input.GitRef, err), 400)
+		}
+	case utils.IsArchive(rootPath):
+		// Malformed or unsafe archives won't improve on retry
+		fileInfos, err = utils.WalkArchive(walkOptions)
+		if err != nil {
+			return types.ReadFilesOutput{}, restate.TerminalError(fmt.Errorf("failed to read archive: %w", err), 400)
+		}
+	default:
+		// Walk directory with configured options
+		fileInfos, err = utils.WalkDirectory(walkOptions)
+		if err != nil {
+			return types.ReadFilesOutput{}, fmt.Errorf("failed to walk directory: %w", err)
+		}
+	}
+
+	// Convert to indexed FileContent list, redacting secrets before the
+	// content is sent anywhere
+	var redactions []types.Redaction
 	files := make([]types.FileContent, len(fileInfos))
 	for i, info := range fileInfos {
+		content, found := redactor.Redact(info.RelativePath, info.Content)
+		redactions = append(redactions, found...)
+
+		// Contents go to the content store; services pass only the hash
+		hash, err := utils.PutContent(content)
+		if err != nil {
+			return types.ReadFilesOutput{}, err
+		}
 		files[i] = types.FileContent{
-			Index:   i,
-			Path:    info.RelativePath,
-			Content: info.Content,
+			Index: i,
+			Path:  info.RelativePath,
+			Hash:  hash,
 		}
 	}
 
 	return types.ReadFilesOutput{
-		Files: files,
+		Files:      files,
+		Commit:     commit,
+		Skipped:    skipped,
+		SkipTotals: skipTotals,
+		Redactions: redactions,
 	}, nil
 }
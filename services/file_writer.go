@@ -1,10 +1,11 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
-	"os"
 	"path/filepath"
 
+	"github.com/pithomlabs/cb2utorial/config"
 	"github.com/pithomlabs/cb2utorial/types"
 	"github.com/pithomlabs/cb2utorial/utils"
 	restate "github.com/restatedev/sdk-go"
@@ -24,34 +25,71 @@ func (s FileWriterService) WriteMarkdownFiles(ctx restate.Context, input types.W
 	if input.OutputDir == "" {
 		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("output_dir is required")
 	}
-
-	// Create output directory if it doesn't exist
-	err := os.MkdirAll(input.OutputDir, 0755)
-	if err != nil {
-		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("failed to create output directory: %w", err)
+	if input.RunID == "" {
+		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("run_id is required")
 	}
 
-	// Write each chapter to a file
-	var filesWritten []string
+	// Build the chapter files, then publish them via a staging dir
+	files := tutorialFiles("", input.ProjectName, input.Summary, input.Format, input.Chapters)
+	primaryCount := len(files)
 
-	for _, chapter := range input.Chapters {
-		// Sanitize title for filename
-		sanitizedTitle := utils.SanitizeFilename(chapter.Title)
-
-		// Format filename with chapter number
-		filename := fmt.Sprintf("%02d_%s.md", chapter.ChapterNumber, sanitizedTitle)
-		filePath := filepath.Join(input.OutputDir, filename)
+	// Language variants share the layout, in a subdirectory each
+	variantCounts := make([]int, len(input.Variants))
+	for i, variant := range input.Variants {
+		variantFiles := tutorialFiles(utils.VariantDir(variant.Language), input.ProjectName, variant.Relationships.Summary, input.Format, variant.Chapters)
+		variantCounts[i] = len(variantFiles)
+		files = append(files, variantFiles...)
+	}
 
-		// Write markdown content
-		err := os.WriteFile(filePath, []byte(chapter.Content), 0644)
+	// The manifest is published with the chapters so all of them change together
+	if input.Manifest != nil {
+		manifestJSON, err := json.MarshalIndent(input.Manifest, "", "  ")
 		if err != nil {
-			return types.WriteMarkdownFilesOutput{}, fmt.Errorf("failed to write file %s: %w", filename, err)
+			return types.WriteMarkdownFilesOutput{}, fmt.Errorf("failed to serialize manifest: %w", err)
 		}
+		files = append(files, utils.OutputFile{Name: utils.ManifestFilename, Content: manifestJSON})
+	}
+
+	filesWritten, err := utils.PublishFiles(input.OutputDir, input.RunID, files, input.Stale)
+	if err != nil {
+		return types.WriteMarkdownFilesOutput{}, err
+	}
 
-		filesWritten = append(filesWritten, filePath)
+	// Report tutorial files only, split by language
+	var variantsWritten map[string][]string
+	offset := primaryCount
+	for i, variant := range input.Variants {
+		if variantsWritten == nil {
+			variantsWritten = make(map[string][]string, len(input.Variants))
+		}
+		variantsWritten[variant.Language] = filesWritten[offset : offset+variantCounts[i]]
+		offset += variantCounts[i]
 	}
+	filesWritten = filesWritten[:primaryCount]
 
 	return types.WriteMarkdownFilesOutput{
-		FilesWritten: filesWritten,
+		FilesWritten:    filesWritten,
+		VariantsWritten: variantsWritten,
 	}, nil
 }
+
+// tutorialFiles builds the chapter files, plus index.md for the
+// markdown-index format, under dir ("" for the output directory itself)
+func tutorialFiles(dir string, projectName string, summary string, format string, chapters []types.WriteChapterOutput) []utils.OutputFile {
+	files := make([]utils.OutputFile, 0, len(chapters)+1)
+	for _, chapter := range chapters {
+		files = append(files, utils.OutputFile{
+			Name:    filepath.Join(dir, utils.ChapterFilename(chapter.ChapterNumber, chapter.Title)),
+			Content: []byte(chapter.Content),
+		})
+	}
+
+	// Table of contents linking the chapters
+	if format == config.FormatMarkdownIndex {
+		files = append(files, utils.OutputFile{
+			Name:    filepath.Join(dir, utils.IndexFilename),
+			Content: []byte(utils.BuildIndex(projectName, summary, chapters)),
+		})
+	}
+	return files
+}
@@ -6,7 +6,9 @@ import (
 	"strings"
 
 	"github.com/pithomlabs/cb2utorial/llm"
+	"github.com/pithomlabs/cb2utorial/prompts"
 	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
 	restate "github.com/restatedev/sdk-go"
 	"gopkg.in/yaml.v3"
 )
@@ -26,70 +28,47 @@ func (s RelationshipAnalyzerService) AnalyzeRelationships(ctx restate.Context, i
 		return types.RelationshipData{}, fmt.Errorf("no abstractions provided")
 	}
 
-	// Build abstraction listing
-	var abstractionListBuilder strings.Builder
-	for _, abs := range input.Abstractions {
-		abstractionListBuilder.WriteString(fmt.Sprintf("- %d # %s: %s\n", abs.Index, abs.Name, abs.Description))
-	}
-
 	// Build code context for each abstraction (sample only)
 	var codeContextBuilder strings.Builder
 	for _, abs := range input.Abstractions {
 		codeContextBuilder.WriteString(fmt.Sprintf("\n### Abstraction %d: %s\n", abs.Index, abs.Name))
 		codeContextBuilder.WriteString("Related files:\n")
-		for _, fileIdx := range abs.FileIndices {
-			if fileIdx < len(input.Files) {
-				file := input.Files[fileIdx]
-				// Show first 500 chars as sample
-				sample := file.Content
-				if len(sample) > 500 {
-					sample = sample[:500] + "..."
-				}
-				codeContextBuilder.WriteString(fmt.Sprintf("  File %d (%s):\n%s\n\n", fileIdx, file.Path, sample))
+		referenced, err := utils.LookupFiles(abs, input.Files)
+		if err != nil {
+			return types.RelationshipData{}, restate.TerminalError(err, 400)
+		}
+		for _, rf := range referenced {
+			// Show first 500 chars as sample
+			file, err := loadContent(rf.File)
+			if err != nil {
+				return types.RelationshipData{}, err
+			}
+			sample := utils.SliceLines(file.Content, rf.Ref.StartLine, rf.Ref.EndLine)
+			if len(sample) > 500 {
+				sample = sample[:500] + "..."
 			}
+			codeContextBuilder.WriteString(fmt.Sprintf("  File %s:\n%s\n\n", utils.DescribeFileRef(rf.Ref), sample))
 		}
 	}
 
 	// Create LLM prompt
-	prompt := fmt.Sprintf(`You are analyzing relationships in the "%s" project.
-
-ABSTRACTIONS:
-%s
-
-CODE CONTEXT:
-%s
-
-Your tasks:
-1. Write a high-level project summary (2-3 sentences)
-2. Describe how these abstractions relate to each other
-
-For relationships, specify:
-- from: Source abstraction INDEX (number)
-- to: Target abstraction INDEX (number)  
-- label: Brief description of relationship (e.g., "uses", "extends", "orchestrates")
-
-Output YAML format:
-"""yaml
-summary: "High-level description of what this project does"
-details:
-  - from: 0
-    to: 1
-    label: "uses"
-  - from: 2
-    to: 0
-    label: "orchestrates"
-"""
-
-Return ONLY the YAML, no other text.
-`, input.ProjectName, abstractionListBuilder.String(), codeContextBuilder.String())
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.Relationships, prompts.RelationshipsData{
+		ProjectName:  input.ProjectName,
+		Abstractions: input.Abstractions,
+		CodeContext:  codeContextBuilder.String(),
+	})
+	if err != nil {
+		return types.RelationshipData{}, restate.TerminalError(err, 400)
+	}
 
 	// Call LLM
 	client, err := llm.NewClient()
 	if err != nil {
 		return types.RelationshipData{}, fmt.Errorf("failed to create LLM client: %w", err)
 	}
+	client = client.WithModel(input.Model)
 
-	response, err := client.CallLLM(context.Background(), prompt, "You are a software architecture analyst.")
+	response, err := client.CallLLM(context.Background(), prompt, systemPrompt)
 	if err != nil {
 		return types.RelationshipData{}, fmt.Errorf("LLM call failed: %w", err)
 	}
@@ -109,18 +88,7 @@ Return ONLY the YAML, no other text.
 	var yamlData yamlRelationshipData
 
 	// Extract YAML block
-	yamlContent := response
-	if strings.Contains(response, "```yaml") {
-		parts := strings.Split(response, "```yaml")
-		if len(parts) > 1 {
-			yamlContent = strings.Split(parts[1], "```")[0]
-		}
-	} else if strings.Contains(response, "```") {
-		parts := strings.Split(response, "```")
-		if len(parts) > 1 {
-			yamlContent = parts[1]
-		}
-	}
+	yamlContent := utils.ExtractYAML(response)
 
 	err = yaml.Unmarshal([]byte(yamlContent), &yamlData)
 	if err != nil {
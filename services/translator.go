@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/llm"
+	"github.com/pithomlabs/cb2utorial/prompts"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	restate "github.com/restatedev/sdk-go"
+	"gopkg.in/yaml.v3"
+)
+
+// TranslatorService produces other language versions of an analysis or chapter
+type TranslatorService struct{}
+
+// ServiceName returns the service name for registration
+func (s TranslatorService) ServiceName() string {
+	return "Translator"
+}
+
+// TranslateAnalysis translates abstraction names and descriptions and the
+// relationship summary and labels. Indices and file references are kept.
+func (s TranslatorService) TranslateAnalysis(ctx restate.Context, input types.TranslateAnalysisInput) (types.LocalizedAnalysis, error) {
+	// Validate input
+	if input.Language == "" {
+		return types.LocalizedAnalysis{}, restate.TerminalError(fmt.Errorf("language is required"), 400)
+	}
+	if len(input.Abstractions) == 0 {
+		return types.LocalizedAnalysis{}, fmt.Errorf("no abstractions provided")
+	}
+
+	// Only the text is sent; everything else is copied from the input
+	type yamlAbstraction struct {
+		Index       int    `yaml:"index"`
+		Name        string `yaml:"name"`
+		Description string `yaml:"description"`
+	}
+	type yamlRelationship struct {
+		Index int    `yaml:"index"`
+		Label string `yaml:"label"`
+	}
+	type yamlSection struct {
+		Index   int    `yaml:"index"`
+		Heading string `yaml:"heading"`
+	}
+	type yamlAnalysis struct {
+		Summary       string             `yaml:"summary"`
+		Abstractions  []yamlAbstraction  `yaml:"abstractions"`
+		Relationships []yamlRelationship `yaml:"relationships"`
+		Sections      []yamlSection      `yaml:"sections,omitempty"`
+	}
+
+	source := yamlAnalysis{Summary: input.Relationships.Summary}
+	for _, abs := range input.Abstractions {
+		source.Abstractions = append(source.Abstractions, yamlAbstraction{Index: abs.Index, Name: abs.Name, Description: abs.Description})
+	}
+	for i, rel := range input.Relationships.Details {
+		source.Relationships = append(source.Relationships, yamlRelationship{Index: i, Label: rel.Label})
+	}
+	for i, heading := range input.Sections {
+		source.Sections = append(source.Sections, yamlSection{Index: i, Heading: heading})
+	}
+	sourceYAML, err := yaml.Marshal(source)
+	if err != nil {
+		return types.LocalizedAnalysis{}, fmt.Errorf("failed to serialize analysis: %w", err)
+	}
+
+	// Create LLM prompt
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.TranslateAnalysis, prompts.TranslateAnalysisData{
+		ProjectName: input.ProjectName,
+		Language:    input.Language,
+		Analysis:    string(sourceYAML),
+	})
+	if err != nil {
+		return types.LocalizedAnalysis{}, restate.TerminalError(err, 400)
+	}
+
+	// Call LLM
+	client, err := llm.NewClient()
+	if err != nil {
+		return types.LocalizedAnalysis{}, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	client = client.WithModel(input.Model)
+
+	response, err := client.CallLLM(context.Background(), prompt, systemPrompt)
+	if err != nil {
+		return types.LocalizedAnalysis{}, fmt.Errorf("LLM call failed: %w", err)
+	}
+
+	// Extract YAML block
+	yamlContent := utils.ExtractYAML(response)
+
+	var translated yamlAnalysis
+	if err := yaml.Unmarshal([]byte(yamlContent), &translated); err != nil {
+		return types.LocalizedAnalysis{}, fmt.Errorf("failed to parse YAML response: %w\nResponse: %s", err, response)
+	}
+
+	// Copy the translations onto the original by index; anything missing is
+	// an incomplete answer and is retried
+	localized := types.LocalizedAnalysis{
+		Language:      input.Language,
+		Abstractions:  make([]types.Abstraction, len(input.Abstractions)),
+		Relationships: types.RelationshipData{Summary: translated.Summary},
+	}
+	copy(localized.Abstractions, input.Abstractions)
+	localized.Relationships.Details = append([]types.Relationship{}, input.Relationships.Details...)
+
+	names := make(map[int]yamlAbstraction, len(translated.Abstractions))
+	for _, ya := range translated.Abstractions {
+		names[ya.Index] = ya
+	}
+	for i, abs := range localized.Abstractions {
+		ya, ok := names[abs.Index]
+		if !ok || ya.Name == "" {
+			return types.LocalizedAnalysis{}, fmt.Errorf("translation is missing abstraction %d (%s)", abs.Index, abs.Name)
+		}
+		localized.Abstractions[i].Name = ya.Name
+		if ya.Description != "" {
+			localized.Abstractions[i].Description = ya.Description
+		}
+	}
+
+	labels := make(map[int]string, len(translated.Relationships))
+	for _, yr := range translated.Relationships {
+		labels[yr.Index] = yr.Label
+	}
+	for i := range localized.Relationships.Details {
+		if label := labels[i]; label != "" {
+			localized.Relationships.Details[i].Label = label
+		}
+	}
+	if localized.Relationships.Summary == "" {
+		localized.Relationships.Summary = input.Relationships.Summary
+	}
+
+	// The style check matches chapter headings against these, so every
+	// section must come back translated
+	if len(input.Sections) > 0 {
+		headings := make(map[int]string, len(translated.Sections))
+		for _, ys := range translated.Sections {
+			headings[ys.Index] = strings.TrimSpace(ys.Heading)
+		}
+		localized.Sections = make([]string, len(input.Sections))
+		for i, heading := range input.Sections {
+			if headings[i] == "" {
+				return types.LocalizedAnalysis{}, fmt.Errorf("translation is missing section %d (%s)", i, heading)
+			}
+			localized.Sections[i] = headings[i]
+		}
+	}
+
+	return localized, nil
+}
+
+// TranslateChapter translates a written chapter. Fenced code blocks are
+// replaced by placeholders before translation so code cannot change.
+func (s TranslatorService) TranslateChapter(ctx restate.Context, input types.TranslateChapterInput) (types.WriteChapterOutput, error) {
+	// Validate input
+	if input.Language == "" {
+		return types.WriteChapterOutput{}, restate.TerminalError(fmt.Errorf("language is required"), 400)
+	}
+	if input.Chapter.Content == "" {
+		return types.WriteChapterOutput{}, fmt.Errorf("chapter %d has no content", input.Chapter.ChapterNumber)
+	}
+
+	protected, blocks := protectCodeBlocks(input.Chapter.Content)
+
+	title := input.Title
+	if title == "" {
+		title = input.Chapter.Title
+	}
+
+	// Create LLM prompt
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.TranslateChapter, prompts.TranslateChapterData{
+		Language:    input.Language,
+		Title:       title,
+		Placeholder: codePlaceholder(0),
+		Chapter:     protected,
+	})
+	if err != nil {
+		return types.WriteChapterOutput{}, restate.TerminalError(err, 400)
+	}
+
+	// Call LLM
+	client, err := llm.NewClient()
+	if err != nil {
+		return types.WriteChapterOutput{}, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	client = client.WithModel(input.Model)
+
+	// The chapter is returned untranslated if every answer dropped code
+	response, ok, err := callKeepingCode(client, prompt, systemPrompt, blocks)
+	if err != nil {
+		return types.WriteChapterOutput{}, err
+	}
+	if !ok {
+		return types.WriteChapterOutput{
+			ChapterNumber: input.Chapter.ChapterNumber,
+			Title:         title,
+			Content:       input.Chapter.Content,
+			Untranslated:  true,
+		}, nil
+	}
+	return types.WriteChapterOutput{
+		ChapterNumber: input.Chapter.ChapterNumber,
+		Title:         title,
+		Content:       restoreCodeBlocks(response, blocks),
+	}, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/llm"
+	"github.com/pithomlabs/cb2utorial/prompts"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// VerifierService checks the symbols a chapter mentions against the
+// repository and corrects the ones that do not exist
+type VerifierService struct{}
+
+// ServiceName returns the service name for registration
+func (s VerifierService) ServiceName() string {
+	return "Verifier"
+}
+
+// VerifyChapter extracts the identifiers a chapter references in prose and
+// code blocks and returns those the repository does not declare
+func (s VerifierService) VerifyChapter(ctx restate.Context, input types.VerifyChapterInput) (types.VerifyChapterOutput, error) {
+	// Validate input
+	if input.Chapter.Content == "" {
+		return types.VerifyChapterOutput{}, restate.TerminalError(fmt.Errorf("chapter %d has no content", input.Chapter.ChapterNumber), 400)
+	}
+
+	files, err := loadContents(input.Files)
+	if err != nil {
+		return types.VerifyChapterOutput{}, err
+	}
+
+	table := utils.BuildSymbolTable(files)
+	references, local := utils.ExtractReferences(input.Chapter.Content)
+	return types.VerifyChapterOutput{
+		Checked:    len(references),
+		Unverified: utils.VerifyReferences(table, references, local),
+	}, nil
+}
+
+// CorrectChapter asks the LLM to fix a chapter's unverified references.
+// Cited code blocks are replaced by placeholders so source code cannot
+// change.
+func (s VerifierService) CorrectChapter(ctx restate.Context, input types.CorrectChapterInput) (types.WriteChapterOutput, error) {
+	// Validate input
+	if input.Chapter.Content == "" {
+		return types.WriteChapterOutput{}, restate.TerminalError(fmt.Errorf("chapter %d has no content", input.Chapter.ChapterNumber), 400)
+	}
+	if len(input.Unverified) == 0 {
+		return input.Chapter, nil
+	}
+
+	protected, blocks := protectCitedBlocks(input.Chapter.Content)
+
+	// Create LLM prompt
+	prompt, systemPrompt, err := prompts.Render(input.PromptsDir, prompts.CorrectReferences, prompts.CorrectReferencesData{
+		ProjectName: input.ProjectName,
+		Unverified:  input.Unverified,
+		Placeholder: codePlaceholder(0),
+		Chapter:     protected,
+	})
+	if err != nil {
+		return types.WriteChapterOutput{}, restate.TerminalError(err, 400)
+	}
+
+	// Call LLM
+	client, err := llm.NewClient()
+	if err != nil {
+		return types.WriteChapterOutput{}, fmt.Errorf("failed to create LLM client: %w", err)
+	}
+	client = client.WithModel(input.Model)
+
+	// The chapter is returned uncorrected if every answer dropped cited code.
+	// Code the LLM changed or added is cited like the writer's.
+	response, ok, err := callKeepingCode(client, prompt, systemPrompt, blocks)
+	if err != nil {
+		return types.WriteChapterOutput{}, err
+	}
+	if !ok {
+		chapter := input.Chapter
+		chapter.Uncorrected = true
+		return chapter, nil
+	}
+	chapter, err := citeRewrite(response, blocks, input.Chapter, input.Files, input.SourceURL)
+	if err != nil {
+		return types.WriteChapterOutput{}, err
+	}
+	chapter.Uncorrected = false
+	return chapter, nil
+}
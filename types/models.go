@@ -4,15 +4,26 @@ package types
 type FileContent struct {
 	Index   int    `json:"index"`
 	Path    string `json:"path"`
-	Content string `json:"content"`
+	Content string `json:"content,omitempty"` // Empty between services; load from the content store by Hash
+	Hash    string `json:"hash"`              // SHA-256 of Content
 }
 
 // Abstraction represents a core code concept identified by LLM
 type Abstraction struct {
-	Index       int    `json:"index"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	FileIndices []int  `json:"file_indices"` // References to FileContent by index
+	Index       int       `json:"index"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	FileIndices []int     `json:"file_indices"`    // Positions in this run's file list, derived from Files
+	Files       []FileRef `json:"files,omitempty"` // Stable references by relative path
+}
+
+// FileRef identifies a file by relative path, optionally narrowed to a
+// symbol or line range
+type FileRef struct {
+	Path      string `json:"path"`
+	Symbol    string `json:"symbol,omitempty"`
+	StartLine int    `json:"start_line,omitempty"` // 1-based, inclusive
+	EndLine   int    `json:"end_line,omitempty"`
 }
 
 // Relationship describes how two abstractions interact
@@ -30,38 +41,215 @@ type RelationshipData struct {
 
 // ChapterSummary provides brief context about a chapter
 type ChapterSummary struct {
-	Name    string `json:"name"`
-	Summary string `json:"summary"`
+	ChapterNumber int      `json:"chapter_number,omitempty"`
+	Name          string   `json:"name"`
+	Summary       string   `json:"summary"`
+	Concepts      []string `json:"concepts,omitempty"` // Terms the chapter introduces
+}
+
+// SummarizeChapterInput asks for the summary and concepts of a chapter
+type SummarizeChapterInput struct {
+	Chapter     WriteChapterOutput `json:"chapter"`
+	ProjectName string             `json:"project_name"`
+	Model       string             `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir  string             `json:"prompts_dir,omitempty"` // Prompt template overrides
 }
 
 // WriteChapterOutput contains generated chapter content
 type WriteChapterOutput struct {
+	ChapterNumber int               `json:"chapter_number"`
+	Title         string            `json:"title"`
+	Content       string            `json:"content"`                // Markdown content
+	Citations     []Citation        `json:"citations,omitempty"`    // Code blocks traced to the source
+	Unverified    []SymbolReference `json:"unverified,omitempty"`   // Mentioned symbols the repository does not declare
+	Critiques     []Critique        `json:"critiques,omitempty"`    // Critic scores, one per draft; the last is final
+	Summary       string            `json:"summary,omitempty"`      // What the chapter covers, given to later chapters
+	Concepts      []string          `json:"concepts,omitempty"`     // Terms the chapter introduces
+	Untranslated  bool              `json:"untranslated,omitempty"` // Translations kept dropping code blocks; Content is the original
+	Uncorrected   bool              `json:"uncorrected,omitempty"`  // Corrections of Unverified kept dropping code blocks
+	Unrevised     bool              `json:"unrevised,omitempty"`    // Revisions asked for by the last critique kept dropping code blocks
+}
+
+// Critique is the critic's rubric scores for one draft of a chapter, each
+// from 1 (poor) to 5 (excellent)
+type Critique struct {
+	Accuracy int      `json:"accuracy"` // Agrees with the source code
+	Clarity  int      `json:"clarity"`
+	Coverage int      `json:"coverage"` // Covers the abstraction and its files
+	Novelty  int      `json:"novelty"`  // Does not repeat earlier chapters
+	Score    float64  `json:"score"`    // Average of the four
+	Feedback []string `json:"feedback,omitempty"`
+}
+
+// ChapterScore records how the critic scored a chapter
+type ChapterScore struct {
+	ChapterNumber  int        `json:"chapter_number"`
+	Title          string     `json:"title"`
+	Critiques      []Critique `json:"critiques"` // One per draft; the last is final
+	Revisions      int        `json:"revisions"`
+	Passed         bool       `json:"passed"`                    // Final score reached the threshold
+	RevisionFailed bool       `json:"revision_failed,omitempty"` // The last revision kept dropping code blocks and was discarded
+}
+
+// CritiqueChapterInput asks the critic to score a chapter
+type CritiqueChapterInput struct {
+	Chapter          WriteChapterOutput `json:"chapter"`
+	Abstraction      Abstraction        `json:"abstraction"`
+	Files            []FileContent      `json:"files"`
+	PreviousChapters []ChapterSummary   `json:"previous_chapters"`
+	ProjectName      string             `json:"project_name"`
+	Model            string             `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir       string             `json:"prompts_dir,omitempty"` // Prompt template overrides
+}
+
+// ReviseChapterInput asks for a chapter revised according to a critique
+type ReviseChapterInput struct {
+	Chapter     WriteChapterOutput `json:"chapter"`
+	Critique    Critique           `json:"critique"`
+	Abstraction Abstraction        `json:"abstraction"`
+	Files       []FileContent      `json:"files"`
+	ProjectName string             `json:"project_name"`
+	Model       string             `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir  string             `json:"prompts_dir,omitempty"` // Prompt template overrides
+	SourceURL   string             `json:"source_url,omitempty"`  // Citation link template, {commit} already filled in
+}
+
+// SymbolReference is an identifier a chapter mentions
+type SymbolReference struct {
+	Name        string   `json:"name"`
+	Where       string   `json:"where"`                 // prose | code
+	Suggestions []string `json:"suggestions,omitempty"` // Declared names it may have meant
+}
+
+// UnverifiedIssue is a symbol reference left unverified in a chapter
+type UnverifiedIssue struct {
+	ChapterNumber int    `json:"chapter_number"`
+	Title         string `json:"title"`
+	Uncorrected   bool   `json:"uncorrected,omitempty"` // Correction was attempted but kept dropping code blocks
+	SymbolReference
+}
+
+// VerifyChapterInput asks which symbols of a chapter do not exist
+type VerifyChapterInput struct {
+	Chapter WriteChapterOutput `json:"chapter"`
+	Files   []FileContent      `json:"files"`
+}
+
+// VerifyChapterOutput lists the chapter's unverified symbol references
+type VerifyChapterOutput struct {
+	Checked    int               `json:"checked"` // References extracted from the chapter
+	Unverified []SymbolReference `json:"unverified,omitempty"`
+}
+
+// CorrectChapterInput asks the LLM to fix a chapter's unverified references
+type CorrectChapterInput struct {
+	Chapter     WriteChapterOutput `json:"chapter"`
+	Unverified  []SymbolReference  `json:"unverified"`
+	Files       []FileContent      `json:"files"`
+	ProjectName string             `json:"project_name"`
+	Model       string             `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir  string             `json:"prompts_dir,omitempty"` // Prompt template overrides
+	SourceURL   string             `json:"source_url,omitempty"`  // Citation link template, {commit} already filled in
+}
+
+// Citation traces a chapter's code block to the source lines it quotes
+type Citation struct {
+	Path      string `json:"path,omitempty"`
+	StartLine int    `json:"start_line,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	Label     string `json:"label,omitempty"` // Path the writer gave for the block
+	Status    string `json:"status"`          // verified | corrected | invented
+}
+
+// CitationIssue is a code block that did not match the source as quoted
+type CitationIssue struct {
 	ChapterNumber int    `json:"chapter_number"`
 	Title         string `json:"title"`
-	Content       string `json:"content"` // Markdown content
+	Citation
 }
 
 // ===== Service Input/Output Types =====
 
 // ReadFilesInput configures file reading from local directory
 type ReadFilesInput struct {
-	RepoPath        string   `json:"repo_path"`
-	IncludePatterns []string `json:"include_patterns"`
-	ExcludePatterns []string `json:"exclude_patterns"`
-	MaxFileSize     int64    `json:"max_file_size"`
-	MaxFiles        int      `json:"max_files"`
+	RepoPath        string          `json:"repo_path"`
+	IncludePatterns []string        `json:"include_patterns"`
+	ExcludePatterns []string        `json:"exclude_patterns"`
+	MaxFileSize     int64           `json:"max_file_size"`
+	MaxFiles        int             `json:"max_files"`
+	GitRef          string          `json:"git_ref,omitempty"`         // Read from the .git object store at this branch/tag/commit instead of the working tree
+	RedactionRules  []RedactionRule `json:"redaction_rules,omitempty"` // Applied after the built-in secret detectors
 }
 
 // ReadFilesOutput returns indexed file list
 type ReadFilesOutput struct {
-	Files []FileContent `json:"files"`
+	Files      []FileContent  `json:"files"`
+	Commit     string         `json:"commit,omitempty"`      // Commit SHA the files were read at (GitRef only)
+	Skipped    []SkippedFile  `json:"skipped,omitempty"`     // Capped per reason; see SkipTotals
+	SkipTotals map[string]int `json:"skip_totals,omitempty"` // Reason → number of files skipped
+	Redactions []Redaction    `json:"redactions,omitempty"`
+}
+
+// SkippedFile is a file in the source that was not read, and why
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"` // excluded-by-pattern | too-large | unreadable | limit-reached | binary | generated | minified
+}
+
+// RedactionRule is a custom secret pattern. If the pattern has a capture
+// group only the group is redacted.
+type RedactionRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// Redaction records where a secret was replaced; the value is never stored
+type Redaction struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Rule string `json:"rule"`
+}
+
+// RunReport collects diagnostics about a run for the user
+type RunReport struct {
+	FilesRead    int                 `json:"files_read"`
+	Skipped      []SkippedFile       `json:"skipped,omitempty"`
+	SkipTotals   map[string]int      `json:"skip_totals,omitempty"`
+	Redactions   []Redaction         `json:"redactions,omitempty"`
+	Prompts      []PromptVersion     `json:"prompts,omitempty"`      // Templates the run's prompts were rendered from
+	Style        []StyleIssue        `json:"style,omitempty"`        // Chapters that still break the style guide
+	Citations    []CitationIssue     `json:"citations,omitempty"`    // Quoted code that was corrected or not found
+	Unverified   []UnverifiedIssue   `json:"unverified,omitempty"`   // Mentioned symbols the repository does not declare
+	Snippets     []SnippetIssue      `json:"snippets,omitempty"`     // Go code blocks that do not parse
+	Scores       []ChapterScore      `json:"scores,omitempty"`       // Critic scores per chapter
+	Untranslated []UntranslatedIssue `json:"untranslated,omitempty"` // Variant chapters published in the original language
+}
+
+// UntranslatedIssue is a chapter of a language variant left untranslated
+type UntranslatedIssue struct {
+	Language      string `json:"language"`
+	ChapterNumber int    `json:"chapter_number"`
+	Title         string `json:"title"`
+}
+
+// PromptVersion identifies the prompt template used for one LLM call
+type PromptVersion struct {
+	Name    string `json:"name"`
+	Version string `json:"version"` // From the template's version header
+	Source  string `json:"source"`  // "embedded" or the override file path
+	Digest  string `json:"digest"`  // Short SHA-256 of the template text
 }
 
 // AnalyzeAbstractionsInput provides codebase for abstraction analysis
 type AnalyzeAbstractionsInput struct {
-	Files           []FileContent `json:"files"`
-	ProjectName     string        `json:"project_name"`
-	MaxAbstractions int           `json:"max_abstractions"`
+	Files           []FileContent       `json:"files"`
+	ProjectName     string              `json:"project_name"`
+	MaxAbstractions int                 `json:"max_abstractions"`
+	Pinned          []PinnedAbstraction `json:"pinned,omitempty"`      // Included verbatim, before the LLM's picks
+	Blocked         []string            `json:"blocked,omitempty"`     // Concepts the LLM must not propose
+	Audience        string              `json:"audience,omitempty"`    // Persona name or free-text audience
+	Model           string              `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir      string              `json:"prompts_dir,omitempty"` // Prompt template overrides
 }
 
 // AnalyzeAbstractionsOutput returns identified abstractions
@@ -74,6 +262,8 @@ type AnalyzeRelationshipsInput struct {
 	Abstractions []Abstraction `json:"abstractions"`
 	Files        []FileContent `json:"files"`
 	ProjectName  string        `json:"project_name"`
+	Model        string        `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir   string        `json:"prompts_dir,omitempty"` // Prompt template overrides
 }
 
 // OrderChaptersInput provides data for determining chapter sequence
@@ -81,6 +271,9 @@ type OrderChaptersInput struct {
 	Abstractions  []Abstraction    `json:"abstractions"`
 	Relationships RelationshipData `json:"relationships"`
 	ProjectName   string           `json:"project_name"`
+	Audience      string           `json:"audience,omitempty"`    // Persona name or free-text audience
+	Model         string           `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir    string           `json:"prompts_dir,omitempty"` // Prompt template overrides
 }
 
 // OrderChaptersOutput returns pedagogically-ordered abstraction indices
@@ -95,17 +288,57 @@ type WriteChapterInput struct {
 	PreviousChapters []ChapterSummary `json:"previous_chapters"`
 	ProjectName      string           `json:"project_name"`
 	ChapterNumber    int              `json:"chapter_number"`
+	Audience         string           `json:"audience,omitempty"` // Persona name or free-text audience
+	Language         string           `json:"language,omitempty"`
+	Model            string           `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir       string           `json:"prompts_dir,omitempty"` // Prompt template overrides
+	Style            *StyleGuide      `json:"style,omitempty"`
+	StyleViolations  []StyleViolation `json:"style_violations,omitempty"` // Problems of the previous draft, when regenerating
+	SnippetErrors    []SnippetError   `json:"snippet_errors,omitempty"`   // Go code of the previous draft that did not parse
+	SourceURL        string           `json:"source_url,omitempty"`       // Citation link template, {commit} already filled in
+}
+
+// TranslateAnalysisInput asks for the analysis in another language
+type TranslateAnalysisInput struct {
+	Abstractions  []Abstraction    `json:"abstractions"`
+	Relationships RelationshipData `json:"relationships"`
+	Sections      []string         `json:"sections,omitempty"` // Style guide headings the chapters must use
+	ProjectName   string           `json:"project_name"`
+	Language      string           `json:"language"`
+	Model         string           `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir    string           `json:"prompts_dir,omitempty"` // Prompt template overrides
+}
+
+// TranslateChapterInput asks for a written chapter in another language
+type TranslateChapterInput struct {
+	Chapter    WriteChapterOutput `json:"chapter"`
+	Title      string             `json:"title"` // Chapter title in the target language
+	Language   string             `json:"language"`
+	Model      string             `json:"model,omitempty"`       // Overrides LLM_MODEL
+	PromptsDir string             `json:"prompts_dir,omitempty"` // Prompt template overrides
 }
 
 // WriteMarkdownFilesInput specifies where to write chapters
 type WriteMarkdownFilesInput struct {
 	OutputDir string               `json:"output_dir"`
 	Chapters  []WriteChapterOutput `json:"chapters"`
+	RunID     string               `json:"run_id"`             // Names the staging/backup dirs for this run
+	Manifest  *TutorialManifest    `json:"manifest,omitempty"` // Written alongside chapters for incremental reruns
+	Stale     []string             `json:"stale,omitempty"`    // Files of the previous run not rewritten; moved to the backup
+
+	// markdown-index also writes index.md linking all chapters
+	Format      string `json:"format,omitempty"`
+	ProjectName string `json:"project_name,omitempty"`
+	Summary     string `json:"summary,omitempty"`
+
+	// Translations written to <language>/ alongside the chapters
+	Variants []LanguageVariant `json:"variants,omitempty"`
 }
 
 // WriteMarkdownFilesOutput returns paths of created files
 type WriteMarkdownFilesOutput struct {
-	FilesWritten []string `json:"files_written"`
+	FilesWritten    []string            `json:"files_written"`
+	VariantsWritten map[string][]string `json:"variants_written,omitempty"` // Language → files
 }
 
 // TutorialWorkflowInput configures the entire tutorial generation workflow
@@ -113,15 +346,322 @@ type TutorialWorkflowInput struct {
 	LocalRepoPath string `json:"local_repo_path"`
 	OutputDir     string `json:"output_dir"`
 	MaxFiles      int    `json:"max_files"`
-	ProjectName   string `json:"project_name,omitempty"` // Optional, derived from path if empty
+
+	// File selection; unset fields come from the preset, then built-in defaults
+	Preset          string   `json:"preset,omitempty"` // default | go | python | javascript | typescript | java | ruby | rust
+	IncludePatterns []string `json:"include_patterns,omitempty"`
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
+	MaxFileSize     int64    `json:"max_file_size,omitempty"`    // Bytes; default 1MB
+	MaxAbstractions int      `json:"max_abstractions,omitempty"` // Default 10
+
+	ProjectName string `json:"project_name,omitempty"` // Optional, derived from path if empty
+	GitRef      string `json:"git_ref,omitempty"`      // Optional branch/tag/commit to read instead of the working tree
+
+	// Custom secret patterns, redacted in addition to the built-in detectors
+	RedactionRules []RedactionRule `json:"redaction_rules,omitempty"`
+
+	// Generation settings, usually from cb2utorial.yaml
+	Models              ModelRouting        `json:"models,omitempty"`
+	Audience            string              `json:"audience,omitempty"`             // Persona (newcomer, contributor, operator, api-consumer, stakeholder) or free text
+	Language            string              `json:"language,omitempty"`             // Natural language of the tutorial (default English)
+	Languages           []string            `json:"languages,omitempty"`            // Additional translations, each written to OutputDir/<language>
+	PromptsDir          string              `json:"prompts_dir,omitempty"`          // Directory of <name>.tmpl files replacing the embedded prompts
+	Style               *StyleGuide         `json:"style,omitempty"`                // House style for chapters
+	SourceURL           string              `json:"source_url,omitempty"`           // Citation link template with {commit}, {path}, {start}, {end}
+	Verification        string              `json:"verification,omitempty"`         // Symbol check after each chapter: report (default) | correct | off
+	GoSnippets          string              `json:"go_snippets,omitempty"`          // Go code block check after each chapter: report (default) | regenerate | off
+	Critic              *CriticSettings     `json:"critic,omitempty"`               // LLM review of each chapter; nil disables it
+	PinnedAbstractions  []PinnedAbstraction `json:"pinned_abstractions,omitempty"`  // Concepts the tutorial must cover
+	BlockedAbstractions []string            `json:"blocked_abstractions,omitempty"` // Concepts the tutorial must not cover
+	OutputFormat        string              `json:"output_format,omitempty"`        // markdown (default) | markdown-index
+
+	// Human-in-the-loop review of abstractions before chapters are written
+	ReviewAbstractions   bool `json:"review_abstractions,omitempty"`
+	ReviewTimeoutMinutes int  `json:"review_timeout_minutes,omitempty"` // Defaults to 24h
+
+	// Incremental regeneration against the manifest of the previous run
+	ForceRegenerate     bool    `json:"force_regenerate,omitempty"`     // Ignore the manifest and regenerate everything
+	RegenerateThreshold float64 `json:"regenerate_threshold,omitempty"` // Fraction of changed files that triggers re-analysis (default 0.3)
+
+	// Resume a previous run from its artifact, skipping completed stages
+	ResumeRunID string `json:"resume_run_id,omitempty"`
+	ResumeFrom  string `json:"resume_from,omitempty"` // Stage to restart from; empty = first incomplete
+}
+
+// ModelRouting selects the LLM per pipeline stage; empty stages use Default,
+// and an empty Default uses the server's LLM_MODEL
+type ModelRouting struct {
+	Default       string `json:"default,omitempty"`
+	Abstractions  string `json:"abstractions,omitempty"`
+	Relationships string `json:"relationships,omitempty"`
+	Order         string `json:"order,omitempty"`
+	Chapters      string `json:"chapters,omitempty"`
+	Translation   string `json:"translation,omitempty"`
+	Review        string `json:"review,omitempty"`
 }
 
-// TutorialState tracks workflow progress (stored in workflow context)
+// ModelTranslation is the ForStage key of the translation service, which
+// runs within the chapters stage rather than as a stage of its own
+const ModelTranslation = "translation"
+
+// ModelReview is the ForStage key of the chapter critic and reviser, which
+// run within the chapters stage
+const ModelReview = "review"
+
+// ForStage returns the model for a pipeline stage, or "" for the server default
+func (m ModelRouting) ForStage(stage string) string {
+	var model string
+	switch stage {
+	case StageAbstractions:
+		model = m.Abstractions
+	case StageRelationships:
+		model = m.Relationships
+	case StageOrder:
+		model = m.Order
+	case StageChapters:
+		model = m.Chapters
+	case ModelTranslation:
+		model = m.Translation
+	case ModelReview:
+		model = m.Review
+	}
+	if model == "" {
+		return m.Default
+	}
+	return model
+}
+
+// CriticSettings enable the critic stage: a second LLM pass scores each
+// chapter against a rubric and asks for a revision below the threshold
+type CriticSettings struct {
+	Threshold    float64 `json:"threshold,omitempty"`     // Minimum average score, 1-5 (default 3.5)
+	MaxRevisions int     `json:"max_revisions,omitempty"` // Revisions per chapter (default 1)
+}
+
+// StyleGuide is a house style every chapter must follow. It is injected into
+// the chapter prompt and checked after each chapter is written.
+type StyleGuide struct {
+	Guide            string   `json:"guide,omitempty"` // Free-form guidance, e.g. a docs team's STYLE.md
+	Tone             string   `json:"tone,omitempty"`
+	Sections         []string `json:"sections,omitempty"`       // Required "##" headings in order; replaces the default outline
+	MaxWords         int      `json:"max_words,omitempty"`      // Prose words per chapter, code blocks excluded
+	BannedPhrases    []string `json:"banned_phrases,omitempty"` // Matched case-insensitively outside code
+	OnViolation      string   `json:"on_violation,omitempty"`   // flag (default) | regenerate
+	MaxRegenerations int      `json:"max_regenerations,omitempty"`
+}
+
+// StyleViolation is one way a chapter breaks the style guide
+type StyleViolation struct {
+	Rule   string `json:"rule"` // missing-title | missing-section | section-order | too-long | banned-phrase
+	Detail string `json:"detail"`
+}
+
+// StyleIssue is a style violation left in a written chapter
+type StyleIssue struct {
+	ChapterNumber int    `json:"chapter_number"`
+	Title         string `json:"title"`
+	StyleViolation
+}
+
+// SnippetError is a Go code block of a chapter that does not parse
+type SnippetError struct {
+	Block int    `json:"block"` // 1-based index among the chapter's Go code blocks
+	Line  int    `json:"line"`  // Line of the error within the block
+	Error string `json:"error"`
+}
+
+// SnippetIssue is a Go code block left unparseable in a written chapter
+type SnippetIssue struct {
+	ChapterNumber int    `json:"chapter_number"`
+	Title         string `json:"title"`
+	SnippetError
+}
+
+// PinnedAbstraction is a concept the user requires in the tutorial
+type PinnedAbstraction struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Files       []string `json:"files,omitempty"` // Glob patterns of implementing files
+}
+
+// TutorialWorkflowOutput is the result of a complete workflow run
+type TutorialWorkflowOutput struct {
+	FilesWritten []string            `json:"files_written"`
+	Commit       string              `json:"commit,omitempty"` // Commit SHA the tutorial was generated from (GitRef only)
+	Report       RunReport           `json:"report"`
+	Variants     map[string][]string `json:"variants,omitempty"` // Language → files written
+}
+
+// TutorialState tracks workflow progress (stored in workflow state and run artifacts)
 type TutorialState struct {
+	Commit          string               `json:"commit,omitempty"`
+	Report          RunReport            `json:"report"`
 	Files           []FileContent        `json:"files"`
 	Abstractions    []Abstraction        `json:"abstractions"`
 	Relationships   RelationshipData     `json:"relationships"`
 	ChapterOrder    []int                `json:"chapter_order"`
 	Chapters        []WriteChapterOutput `json:"chapters"`
 	ChaptersWritten []string             `json:"chapters_written"` // For cleanup
+
+	// Set when writing in a language other than English
+	Localized *LocalizedAnalysis `json:"localized,omitempty"`
+	// Additional language versions (TutorialWorkflowInput.Languages)
+	Variants []LanguageVariant `json:"variants,omitempty"`
+}
+
+// LocalizedAnalysis is the analysis with abstraction names, descriptions and
+// relationship text in another language. Indices and file references are
+// those of the original.
+type LocalizedAnalysis struct {
+	Language      string           `json:"language"`
+	Abstractions  []Abstraction    `json:"abstractions"`
+	Relationships RelationshipData `json:"relationships"`
+	Sections      []string         `json:"sections,omitempty"` // Style guide headings, in the same order
+}
+
+// LanguageVariant is the tutorial translated into an additional language
+type LanguageVariant struct {
+	LocalizedAnalysis
+	Chapters     []WriteChapterOutput `json:"chapters"`
+	FilesWritten []string             `json:"files_written,omitempty"`
+}
+
+// ChapterInfo identifies a generated chapter without its content
+type ChapterInfo struct {
+	ChapterNumber int    `json:"chapter_number"`
+	Title         string `json:"title"`
+}
+
+// TutorialArtifacts is the view of a run returned by the GetArtifacts handler
+type TutorialArtifacts struct {
+	CompletedStages []string          `json:"completed_stages"`
+	Revision        int               `json:"revision"` // RunArtifact revision of the last checkpoint
+	Commit          string            `json:"commit,omitempty"`
+	FilePaths       []string          `json:"file_paths"`
+	FileHashes      map[string]string `json:"file_hashes,omitempty"` // Path → content store hash
+	Abstractions    []Abstraction     `json:"abstractions"`
+	Relationships   RelationshipData  `json:"relationships"`
+	ChapterOrder    []int             `json:"chapter_order"`
+	Chapters        []ChapterInfo     `json:"chapters"`
+	ChaptersWritten []string          `json:"chapters_written"`
+}
+
+// TutorialManifest records what a run generated from which inputs, so the
+// next run over the same output directory can skip unchanged work
+type TutorialManifest struct {
+	ProjectName   string                `json:"project_name"`
+	Commit        string                `json:"commit,omitempty"`
+	Audience      string                `json:"audience,omitempty"` // Persona the tutorial was written for
+	Language      string                `json:"language,omitempty"` // Empty for English
+	FileHashes    map[string]string     `json:"file_hashes"`        // Relative path → content hash
+	Abstractions  []ManifestAbstraction `json:"abstractions"`
+	Relationships RelationshipData      `json:"relationships"`
+	ChapterOrder  []int                 `json:"chapter_order"`
+	Chapters      []ManifestChapter     `json:"chapters"`
+}
+
+// ManifestAbstraction stores an abstraction with its files by path, since
+// file indices are only meaningful within a single read. FilePaths predates
+// Abstraction.Files and is kept for older manifests.
+type ManifestAbstraction struct {
+	Abstraction
+	FilePaths []string `json:"file_paths"`
+}
+
+// ManifestChapter links a chapter file to the fingerprint of its inputs
+type ManifestChapter struct {
+	ChapterNumber int      `json:"chapter_number"`
+	Title         string   `json:"title"`
+	Filename      string   `json:"filename"`
+	Fingerprint   string   `json:"fingerprint"`
+	Summary       string   `json:"summary,omitempty"` // Context for later chapters when this one is reused
+	Concepts      []string `json:"concepts,omitempty"`
+
+	// Check results, restored with a reused chapter for the run report
+	Citations   []Citation        `json:"citations,omitempty"`
+	Unverified  []SymbolReference `json:"unverified,omitempty"`
+	Critiques   []Critique        `json:"critiques,omitempty"`
+	Uncorrected bool              `json:"uncorrected,omitempty"`
+	Unrevised   bool              `json:"unrevised,omitempty"`
+}
+
+// Pipeline stages, in execution order
+const (
+	StageFiles         = "files"
+	StageAbstractions  = "abstractions"
+	StageRelationships = "relationships"
+	StageOrder         = "order"
+	StageChapters      = "chapters"
+	StageWrite         = "write"
+)
+
+// Stages lists pipeline stages in execution order
+var Stages = []string{StageFiles, StageAbstractions, StageRelationships, StageOrder, StageChapters, StageWrite}
+
+// RunArtifact persists a run's stage outputs so it can be resumed later
+type RunArtifact struct {
+	RunID           string                `json:"run_id"`
+	Input           TutorialWorkflowInput `json:"input"`
+	ProjectName     string                `json:"project_name"`
+	CompletedStages []string              `json:"completed_stages"`
+	Revision        int                   `json:"revision"` // Incremented on every checkpoint
+	State           TutorialState         `json:"state"`
+}
+
+// IsCompleted reports whether a stage finished in this run
+func (a RunArtifact) IsCompleted(stage string) bool {
+	for _, s := range a.CompletedStages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// ===== Human Review Types =====
+
+// Review statuses exposed through the workflow's GetReview handler
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+// Review actions a reviewer can submit
+const (
+	ReviewActionApprove = "approve"
+	ReviewActionEdit    = "edit"
+	ReviewActionReject  = "reject"
+)
+
+// Review edit operations
+const (
+	ReviewEditRename  = "rename"
+	ReviewEditMerge   = "merge"
+	ReviewEditDrop    = "drop"
+	ReviewEditReorder = "reorder"
+)
+
+// ReviewProposal is the abstraction list and chapter order awaiting human review
+type ReviewProposal struct {
+	Status        string           `json:"status"` // Empty if the workflow has not reached review
+	Abstractions  []Abstraction    `json:"abstractions"`
+	Relationships RelationshipData `json:"relationships"`
+	ChapterOrder  []int            `json:"chapter_order"`
+}
+
+// ReviewEdit is a single change to the proposal; indices refer to the proposal
+type ReviewEdit struct {
+	Op          string `json:"op"`                    // rename | merge | drop | reorder
+	Index       int    `json:"index,omitempty"`       // rename, drop
+	Indices     []int  `json:"indices,omitempty"`     // merge (first is kept), reorder (full order)
+	Name        string `json:"name,omitempty"`        // rename, merge
+	Description string `json:"description,omitempty"` // rename, merge
+}
+
+// ReviewDecision is a reviewer's response to a ReviewProposal
+type ReviewDecision struct {
+	Action string       `json:"action"` // approve | edit | reject
+	Edits  []ReviewEdit `json:"edits,omitempty"`
+	Reason string       `json:"reason,omitempty"`
 }
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gobwas/glob"
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// MatchFileIndices returns the indices of files whose path matches any of
+// the glob patterns, in file order
+func MatchFileIndices(patterns []string, files []types.FileContent) ([]int, error) {
+	globs := make([]glob.Glob, 0, len(patterns))
+	for _, pattern := range patterns {
+		g, err := glob.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		globs = append(globs, g)
+	}
+
+	var indices []int
+	for _, file := range files {
+		for _, g := range globs {
+			if g.Match(file.Path) {
+				indices = append(indices, file.Index)
+				break
+			}
+		}
+	}
+	return indices, nil
+}
+
+// ConceptKey normalizes an abstraction name for comparison: lowercase
+// letters and digits only, so "Event Bus" and "event-bus" are equal
+func ConceptKey(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// IsBlockedConcept reports whether name equals a blocked concept or
+// contains it as whole words, so blocking "Logging" also drops "Logging
+// Utilities" but not "Catalog"
+func IsBlockedConcept(name string, blocked []string) bool {
+	key := ConceptKey(name)
+	words := strings.Join(conceptWords(name), " ")
+	for _, b := range blocked {
+		blockedKey := ConceptKey(b)
+		if blockedKey == "" {
+			continue
+		}
+		blockedWords := strings.Join(conceptWords(b), " ")
+		if key == blockedKey || strings.Contains(" "+words+" ", " "+blockedWords+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// conceptWords splits a name into lowercase words of letters and digits
+func conceptWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,293 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// archiveExtensions lists the archive formats WalkArchive can read
+var archiveExtensions = []string{".zip", ".tar.gz", ".tgz"}
+
+// IsArchive reports whether path names a supported archive
+func IsArchive(path string) bool {
+	return archiveExtension(path) != ""
+}
+
+// archiveExtension returns the matching archive extension, or ""
+func archiveExtension(path string) string {
+	lower := strings.ToLower(path)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// SourceName returns a display name for a repository path, archive or module
+// spec: the base name without archive extension or module version
+func SourceName(source string) string {
+	name := filepath.Base(source)
+	if ext := archiveExtension(name); ext != "" {
+		name = name[:len(name)-len(ext)]
+	}
+	if at := strings.LastIndex(name, "@"); at > 0 {
+		name = name[:at]
+	}
+	return name
+}
+
+// ResolveSource maps a source to a path on disk. Existing paths are returned
+// unchanged; a Go module spec ("module/path@version") is resolved against the
+// module cache, preferring the extracted directory over the download zip.
+func ResolveSource(source string) (string, error) {
+	if _, err := os.Stat(source); err == nil {
+		return source, nil
+	}
+
+	modulePath, version, ok := strings.Cut(source, "@")
+	if !ok || modulePath == "" || version == "" {
+		return "", fmt.Errorf("source %s does not exist", source)
+	}
+
+	escapedPath, err := escapeModulePath(modulePath)
+	if err != nil {
+		return "", err
+	}
+	escapedVersion, err := escapeModulePath(version)
+	if err != nil {
+		return "", err
+	}
+
+	cache := moduleCacheDir()
+	candidates := []string{
+		filepath.Join(cache, filepath.FromSlash(escapedPath)+"@"+escapedVersion),
+		filepath.Join(cache, "cache", "download", filepath.FromSlash(escapedPath), "@v", escapedVersion+".zip"),
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("module %s@%s not found in module cache %s (run `go mod download %s@%s`)",
+		modulePath, version, cache, modulePath, version)
+}
+
+// moduleCacheDir returns GOMODCACHE, falling back to GOPATH/pkg/mod
+func moduleCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		home, _ := os.UserHomeDir()
+		gopath = filepath.Join(home, "go")
+	}
+	// GOPATH may be a list; the module cache lives in the first entry
+	gopath = filepath.SplitList(gopath)[0]
+	return filepath.Join(gopath, "pkg", "mod")
+}
+
+// escapeModulePath applies the module cache's case encoding ("!" + lower)
+func escapeModulePath(p string) (string, error) {
+	var b strings.Builder
+	for _, r := range p {
+		if r == '!' || r >= unicode.MaxASCII {
+			return "", fmt.Errorf("invalid module path or version %q", p)
+		}
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
+
+// WalkArchive returns matching files from a .zip, .tar.gz or .tgz archive
+// at opts.RootPath, applying the same filters and limits as WalkDirectory.
+// A single top-level directory shared by all entries (as in release tarballs
+// and module zips) is stripped. Entries that would escape the archive root
+// fail the walk.
+func WalkArchive(opts WalkDirectoryOptions) ([]FileInfo, error) {
+	collector, err := newFileCollector(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch archiveExtension(opts.RootPath) {
+	case ".zip":
+		err = walkZip(opts.RootPath, collector)
+	case ".tar.gz", ".tgz":
+		err = walkTarGz(opts.RootPath, collector)
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", opts.RootPath)
+	}
+	if err != nil && !errors.Is(err, errLimitReached) {
+		return nil, fmt.Errorf("error reading archive: %w", err)
+	}
+	return collector.files, nil
+}
+
+// walkZip feeds regular files in a zip archive to the collector
+func walkZip(archivePath string, collector *fileCollector) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer reader.Close()
+
+	var names []string
+	for _, f := range reader.File {
+		if f.Mode().IsRegular() {
+			names = append(names, f.Name)
+		}
+	}
+	prefix, err := archivePrefix(names)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range reader.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		name, _ := safeArchivePath(f.Name)
+		err := collector.add(strings.TrimPrefix(name, prefix), int64(f.UncompressedSize64), func() ([]byte, error) {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return readLimited(rc, collector.opts.MaxFileSize)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTarGz feeds regular files in a gzipped tarball to the collector.
+// Tar is sequential, so the common prefix is found in a first pass.
+func walkTarGz(archivePath string, collector *fileCollector) error {
+	var names []string
+	err := scanTarGz(archivePath, func(hdr *tar.Header, tr *tar.Reader) error {
+		names = append(names, hdr.Name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	prefix, err := archivePrefix(names)
+	if err != nil {
+		return err
+	}
+
+	return scanTarGz(archivePath, func(hdr *tar.Header, tr *tar.Reader) error {
+		name, _ := safeArchivePath(hdr.Name)
+		return collector.add(strings.TrimPrefix(name, prefix), hdr.Size, func() ([]byte, error) {
+			return readLimited(tr, collector.opts.MaxFileSize)
+		})
+	})
+}
+
+// scanTarGz calls fn for every regular file in a gzipped tarball
+func scanTarGz(archivePath string, fn func(hdr *tar.Header, tr *tar.Reader) error) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+		// Symlinks, hard links and devices are skipped
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// archivePrefix validates entry names and returns the top-level directory
+// (with trailing slash) shared by all of them, or "" if there is none
+func archivePrefix(names []string) (string, error) {
+	cleaned := make([]string, len(names))
+	for i, raw := range names {
+		name, err := safeArchivePath(raw)
+		if err != nil {
+			return "", err
+		}
+		cleaned[i] = name
+	}
+
+	prefix := ""
+	for i, name := range cleaned {
+		dir, _, found := strings.Cut(name, "/")
+		if !found {
+			return "", nil // A file at the root: nothing to strip
+		}
+		if i == 0 {
+			prefix = dir + "/"
+		} else if prefix != dir+"/" {
+			return "", nil
+		}
+	}
+	return prefix, nil
+}
+
+// safeArchivePath cleans an entry name and rejects absolute paths and
+// entries that climb out of the archive root
+func safeArchivePath(name string) (string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(slashed, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("unsafe archive entry %q: absolute path", name)
+	}
+	cleaned := path.Clean(slashed)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("unsafe archive entry %q: escapes archive root", name)
+	}
+	return cleaned, nil
+}
+
+// readLimited reads r fully, returning errTooLarge past maxSize (if
+// positive) so a misreported entry size cannot exhaust memory
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSafeArchivePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", entry: "src/main.go", want: "src/main.go"},
+		{name: "dot segments inside root", entry: "./src/../src/main.go", want: "src/main.go"},
+		{name: "backslashes", entry: `src\util\io.go`, want: "src/util/io.go"},
+		{name: "dots in a name", entry: "..config/app.yaml", want: "..config/app.yaml"},
+		{name: "parent", entry: "..", wantErr: true},
+		{name: "parent prefix", entry: "../etc/passwd", wantErr: true},
+		{name: "climbs out after descending", entry: "src/../../etc/passwd", wantErr: true},
+		{name: "backslash parent", entry: `..\etc\passwd`, wantErr: true},
+		{name: "absolute", entry: "/etc/passwd", wantErr: true},
+		{name: "backslash absolute", entry: `\etc\passwd`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := safeArchivePath(tt.entry)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("safeArchivePath(%q) = %q, want an error", tt.entry, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("safeArchivePath(%q): %v", tt.entry, err)
+			}
+			if got != tt.want {
+				t.Errorf("safeArchivePath(%q) = %q, want %q", tt.entry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		maxSize int64
+		wantErr error
+	}{
+		{name: "no limit", content: "0123456789", maxSize: 0},
+		{name: "under the limit", content: "0123456789", maxSize: 11},
+		{name: "at the limit", content: "0123456789", maxSize: 10},
+		{name: "over the limit", content: "0123456789", maxSize: 9, wantErr: errTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readLimited(strings.NewReader(tt.content), tt.maxSize)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("readLimited error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(got) != tt.content {
+				t.Errorf("readLimited = %q, want %q", got, tt.content)
+			}
+		})
+	}
+}
+
+func TestCollectorReportsMisreportedSizes(t *testing.T) {
+	tests := []struct {
+		name   string
+		read   func() ([]byte, error)
+		reason string
+	}{
+		{
+			name:   "too large once read",
+			read:   func() ([]byte, error) { return readLimited(strings.NewReader("0123456789"), 4) },
+			reason: SkipTooLarge,
+		},
+		{
+			name:   "read failure",
+			read:   func() ([]byte, error) { return nil, errors.New("corrupt entry") },
+			reason: SkipUnreadable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reasons []string
+			collector, err := newFileCollector(WalkDirectoryOptions{
+				MaxFileSize: 4,
+				OnSkip:      func(path, reason string) { reasons = append(reasons, reason) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The entry claims to be small, so only the read can catch it
+			if err := collector.add("big.txt", 2, tt.read); err != nil {
+				t.Fatalf("add: %v", err)
+			}
+			if len(collector.files) != 0 || len(reasons) != 1 || reasons[0] != tt.reason {
+				t.Errorf("collected %d files, skip reasons %q, want none and [%s]", len(collector.files), reasons, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// ArtifactDir returns the absolute directory run artifacts are stored in.
+// Configurable via ARTIFACT_DIR; defaults to .cb2utorial/runs. Relative
+// paths are resolved against the server's working directory.
+func ArtifactDir() string {
+	dir := os.Getenv("ARTIFACT_DIR")
+	if dir == "" {
+		dir = filepath.Join(".cb2utorial", "runs")
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// artifactPath returns the file a run's artifact is stored in
+func artifactPath(runID string) string {
+	return filepath.Join(ArtifactDir(), SanitizeFilename(runID)+".json")
+}
+
+// SaveRunArtifact atomically writes a run artifact to the artifact directory
+func SaveRunArtifact(artifact types.RunArtifact) error {
+	if artifact.RunID == "" {
+		return fmt.Errorf("run artifact has no run ID")
+	}
+
+	data, err := json.MarshalIndent(artifact, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize run artifact: %w", err)
+	}
+
+	if err := os.MkdirAll(ArtifactDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	// Write to a temp file and rename so readers never see a partial artifact
+	path := artifactPath(artifact.RunID)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run artifact: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to save run artifact: %w", err)
+	}
+	return nil
+}
+
+// LoadRunArtifact reads a previously saved run artifact
+func LoadRunArtifact(runID string) (types.RunArtifact, error) {
+	artifact, err := readRunArtifact(artifactPath(runID))
+	if os.IsNotExist(err) {
+		return types.RunArtifact{}, fmt.Errorf("no artifact found for run %s in %s", runID, ArtifactDir())
+	}
+	return artifact, err
+}
+
+// readRunArtifact reads and parses the artifact file at path. A missing file
+// returns an error satisfying os.IsNotExist.
+func readRunArtifact(path string) (types.RunArtifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return types.RunArtifact{}, err
+		}
+		return types.RunArtifact{}, fmt.Errorf("failed to read run artifact: %w", err)
+	}
+
+	var artifact types.RunArtifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return types.RunArtifact{}, fmt.Errorf("failed to parse run artifact %s: %w", filepath.Base(path), err)
+	}
+	return artifact, nil
+}
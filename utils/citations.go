@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// Citation statuses
+const (
+	CitationVerified  = "verified"  // Found in the source as quoted
+	CitationCorrected = "corrected" // Differed from the source and was replaced by it
+	CitationInvented  = "invented"  // Labelled as source code but found nowhere
+)
+
+// Matching limits: a snippet must share at least half of its lines with one
+// region of a file to be corrected, and gaps between matched lines (elided
+// code) may not exceed matchWindow lines
+const (
+	minCorrectableRatio = 0.5
+	maxCorrectedLines   = 80
+	matchWindow         = 40
+)
+
+// sourceURLPlaceholder matches {commit}, {path}, {start} and {end}
+var sourceURLPlaceholder = regexp.MustCompile(`\{[a-z]+\}`)
+
+// SourceURLPlaceholders are the fields of a source URL template
+var SourceURLPlaceholders = []string{"{commit}", "{path}", "{start}", "{end}"}
+
+// ValidateSourceURL checks a source URL template's placeholders
+func ValidateSourceURL(template string) error {
+	if !strings.Contains(template, "{path}") {
+		return fmt.Errorf("source URL template %q must contain {path}", template)
+	}
+	for _, placeholder := range sourceURLPlaceholder.FindAllString(template, -1) {
+		known := false
+		for _, p := range SourceURLPlaceholders {
+			known = known || placeholder == p
+		}
+		if !known {
+			return fmt.Errorf("source URL template %q: unknown placeholder %s (expected %s)", template, placeholder, strings.Join(SourceURLPlaceholders, ", "))
+		}
+	}
+	return nil
+}
+
+// SourceURL fills a source URL template for a line range
+func SourceURL(template string, commit string, path string, start int, end int) string {
+	return strings.NewReplacer(
+		"{commit}", commit,
+		"{path}", path,
+		"{start}", strconv.Itoa(start),
+		"{end}", strconv.Itoa(end),
+	).Replace(template)
+}
+
+// CiteCodeBlocks verifies each fenced code block of a chapter against the
+// source files and writes a "Source: path:start-end" line below each one it
+// finds. The writer labels quoted code with its path after the language
+// (```go internal/store.go); the label is removed from the fence. Labelled
+// blocks that differ from the source are replaced by the source lines they
+// were taken from, or marked as not found. Unlabelled blocks that match no
+// file (usage examples, shell commands) are left alone. link renders the
+// citation target and may return "" for plain text.
+func CiteCodeBlocks(content string, files []types.FileContent, link func(path string, start int, end int) string) (string, []types.Citation) {
+	lines := strings.Split(content, "\n")
+	sources := make([][]string, len(files))
+	for i, file := range files {
+		sources[i] = normalizedLines(file.Content)
+	}
+
+	var out []string
+	var citations []types.Citation
+	for i := 0; i < len(lines); i++ {
+		fence, language, label, ok := parseFenceOpening(lines[i])
+		if !ok {
+			out = append(out, lines[i])
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !isFenceClosing(lines[end], fence) {
+			end++
+		}
+		if end == len(lines) {
+			out = append(out, lines[i:]...) // Unterminated block
+			break
+		}
+		body := lines[i+1 : end]
+		opening := strings.TrimRight(lines[i][:strings.Index(lines[i], fence)]+fence+language, " ")
+
+		citation, replacement := citeSnippet(body, label, files, sources)
+		if citation == nil {
+			out = append(out, opening)
+			out = append(out, body...)
+			out = append(out, lines[end])
+			i = end
+			continue
+		}
+
+		if replacement != nil {
+			body = replacement
+		}
+		out = append(out, opening)
+		out = append(out, body...)
+		out = append(out, lines[end], "")
+		out = append(out, citationLine(*citation, link))
+		citations = append(citations, *citation)
+		i = end
+	}
+	return strings.Join(out, "\n"), citations
+}
+
+// citeSnippet locates a code block in the files. Returns nil for an
+// unlabelled block found nowhere, and the source lines when the block must
+// be corrected.
+func citeSnippet(body []string, label string, files []types.FileContent, sources [][]string) (*types.Citation, []string) {
+	snippet := significantLines(body)
+	if len(snippet) == 0 {
+		return nil, nil
+	}
+
+	// Prefer the labelled file; otherwise the file sharing the most lines
+	labelled := -1
+	if label != "" {
+		if file, ok := MatchFilePath(ParseFileRef(label).Path, files); ok {
+			for i := range files {
+				if files[i].Path == file.Path {
+					labelled = i
+				}
+			}
+		}
+	}
+	bestFile, bestStart, bestEnd, bestMatched := -1, 0, 0, 0
+	for i, source := range sources {
+		start, end, matched := matchSnippet(snippet, source)
+		if matched > bestMatched || (matched == bestMatched && matched > 0 && i == labelled) {
+			bestFile, bestStart, bestEnd, bestMatched = i, start, end, matched
+		}
+	}
+
+	switch {
+	case bestMatched == len(snippet):
+		return &types.Citation{Path: files[bestFile].Path, StartLine: bestStart + 1, EndLine: bestEnd + 1, Label: label, Status: CitationVerified}, nil
+	case label == "":
+		return nil, nil // Not presented as source code
+	case float64(bestMatched) >= minCorrectableRatio*float64(len(snippet)) && bestEnd-bestStart < maxCorrectedLines:
+		source := strings.Split(files[bestFile].Content, "\n")[bestStart : bestEnd+1]
+		return &types.Citation{Path: files[bestFile].Path, StartLine: bestStart + 1, EndLine: bestEnd + 1, Label: label, Status: CitationCorrected}, source
+	default:
+		return &types.Citation{Label: label, Status: CitationInvented}, nil
+	}
+}
+
+// matchSnippet finds the region of a file sharing the most lines with a
+// snippet, in order. Returns 0-based first and last matched lines.
+func matchSnippet(snippet []string, source []string) (start int, end int, matched int) {
+	for anchor := 0; anchor < len(snippet) && anchor < 3; anchor++ {
+		if !isDistinctive(snippet[anchor]) {
+			continue
+		}
+		for i, line := range source {
+			if line != snippet[anchor] {
+				continue
+			}
+			first, last, count, pos := i, i, 1, i+1
+			for _, want := range snippet[anchor+1:] {
+				for j := pos; j < len(source) && j < pos+matchWindow; j++ {
+					if source[j] == want {
+						last, count, pos = j, count+1, j+1
+						break
+					}
+				}
+			}
+			if count > matched || (count == matched && last-first < end-start) {
+				start, end, matched = first, last, count
+			}
+		}
+	}
+	return start, end, matched
+}
+
+// citationLine renders the line written below a cited code block
+func citationLine(citation types.Citation, link func(path string, start int, end int) string) string {
+	if citation.Status == CitationInvented {
+		return fmt.Sprintf("*⚠️ Not found in the source (`%s`); illustrative only.*", citation.Label)
+	}
+	ref := fmt.Sprintf("%s:%d-%d", citation.Path, citation.StartLine, citation.EndLine)
+	if link != nil {
+		if url := link(citation.Path, citation.StartLine, citation.EndLine); url != "" {
+			return fmt.Sprintf("*Source: [`%s`](%s)*", ref, url)
+		}
+	}
+	return fmt.Sprintf("*Source: `%s`*", ref)
+}
+
+// codeBlock is a fenced code block of a chapter
+type codeBlock struct {
+	Language string
+	Body     []string
+	Cited    bool // Followed by a source citation: copied from the repository
+}
+
+// splitCodeBlocks returns a chapter's fenced code blocks and the lines
+// outside them. An unterminated block runs to the end of the chapter.
+func splitCodeBlocks(content string) ([]codeBlock, []string) {
+	lines := strings.Split(content, "\n")
+	var blocks []codeBlock
+	var prose []string
+	for i := 0; i < len(lines); i++ {
+		fence, language, _, ok := parseFenceOpening(lines[i])
+		if !ok {
+			prose = append(prose, lines[i])
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !isFenceClosing(lines[end], fence) {
+			end++
+		}
+		next := end + 1
+		for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+			next++
+		}
+		blocks = append(blocks, codeBlock{
+			Language: language,
+			Body:     lines[i+1 : min(end, len(lines))],
+			Cited:    next < len(lines) && IsCitationLine(lines[next]),
+		})
+		i = end
+	}
+	return blocks, prose
+}
+
+// parseFenceOpening splits an opening fence into the fence, the language and
+// an optional path label (```go path, ```go title="path", ```go file=path)
+func parseFenceOpening(line string) (fence string, language string, label string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	for _, marker := range []string{"```", "~~~"} {
+		if !strings.HasPrefix(trimmed, marker) {
+			continue
+		}
+		fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, marker[:1]))]
+		fields := strings.Fields(trimmed[len(fence):])
+		if len(fields) > 0 {
+			language = fields[0]
+		}
+		if len(fields) > 1 {
+			label = fields[1]
+			if key, value, found := strings.Cut(label, "="); found && (key == "title" || key == "file") {
+				label = value
+			}
+			label = strings.Trim(label, "\"'`")
+		}
+		return fence, language, label, true
+	}
+	return "", "", "", false
+}
+
+// isFenceClosing reports whether line closes a block opened with fence
+func isFenceClosing(line string, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// significantLines normalizes a snippet, dropping blank and elision lines
+// ("...", "// ...", "# ... more code")
+func significantLines(body []string) []string {
+	var lines []string
+	for _, line := range normalizedLines(strings.Join(body, "\n")) {
+		if line == "" || isElision(line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// normalizedLines collapses whitespace so indentation changes still match
+func normalizedLines(content string) []string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return lines
+}
+
+// isElision reports whether a line stands for omitted code
+func isElision(line string) bool {
+	stripped := strings.TrimSpace(strings.Trim(line, "/#*-<>!; "))
+	return strings.HasPrefix(stripped, "...") || strings.HasPrefix(stripped, "…")
+}
+
+// isDistinctive reports whether a line is specific enough to anchor a match
+// (braces and one-word lines occur everywhere)
+func isDistinctive(line string) bool {
+	alphanumeric := 0
+	for _, r := range line {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			alphanumeric++
+		}
+	}
+	return alphanumeric >= 4 && strings.Contains(line, " ") || alphanumeric >= 8
+}
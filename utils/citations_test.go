@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// storeSource is the fixture file the chapter snippets are cited against
+const storeSource = `package store
+
+import "sync"
+
+// Store keeps values in memory
+type Store struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+// Get returns the value for key
+func (s *Store) Get(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key]
+}
+
+// Set stores a value
+func (s *Store) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+}`
+
+// chapter wraps a snippet in a fence with the given info string
+func chapter(info string, body string) string {
+	return "Intro\n\n```" + info + "\n" + body + "\n```\n\nOutro"
+}
+
+func TestCiteCodeBlocks(t *testing.T) {
+	files := []types.FileContent{
+		{Path: "README.md", Content: "# Store\n\nAn in-memory store.\n"},
+		{Path: "store/store.go", Content: storeSource},
+	}
+	getBody := "func (s *Store) Get(key string) string {\n\ts.mu.Lock()\n\tdefer s.mu.Unlock()\n\treturn s.data[key]\n}"
+	setSource := "func (s *Store) Set(key, value string) {\n\ts.mu.Lock()\n\tdefer s.mu.Unlock()\n\ts.data[key] = value\n}"
+
+	tests := []struct {
+		name      string
+		content   string
+		want      string
+		citations []types.Citation
+	}{
+		{
+			name:      "labelled exact quote",
+			content:   chapter("go store/store.go", getBody),
+			want:      chapter("go", getBody),
+			citations: []types.Citation{{Path: "store/store.go", StartLine: 12, EndLine: 16, Label: "store/store.go", Status: CitationVerified}},
+		},
+		{
+			name:      "title attribute label",
+			content:   chapter(`go title="store.go"`, getBody),
+			want:      chapter("go", getBody),
+			citations: []types.Citation{{Path: "store/store.go", StartLine: 12, EndLine: 16, Label: "store.go", Status: CitationVerified}},
+		},
+		{
+			name:      "unlabelled and re-indented",
+			content:   chapter("go", strings.ReplaceAll(getBody, "\t", "    ")),
+			want:      chapter("go", strings.ReplaceAll(getBody, "\t", "    ")),
+			citations: []types.Citation{{Path: "store/store.go", StartLine: 12, EndLine: 16, Status: CitationVerified}},
+		},
+		{
+			name:      "elided lines span the whole range",
+			content:   chapter("go", "func (s *Store) Get(key string) string {\n\t// ...\n\treturn s.data[key]\n}"),
+			want:      chapter("go", "func (s *Store) Get(key string) string {\n\t// ...\n\treturn s.data[key]\n}"),
+			citations: []types.Citation{{Path: "store/store.go", StartLine: 12, EndLine: 16, Status: CitationVerified}},
+		},
+		{
+			name:      "repeated lines match the tightest region",
+			content:   chapter("go", "\tdefer s.mu.Unlock()\n\ts.data[key] = value"),
+			want:      chapter("go", "\tdefer s.mu.Unlock()\n\ts.data[key] = value"),
+			citations: []types.Citation{{Path: "store/store.go", StartLine: 21, EndLine: 22, Status: CitationVerified}},
+		},
+		{
+			name:      "labelled quote that differs is replaced by the source",
+			content:   chapter("go store/store.go", strings.Replace(setSource, "= value", "= strings.TrimSpace(value)", 1)),
+			want:      chapter("go", setSource),
+			citations: []types.Citation{{Path: "store/store.go", StartLine: 19, EndLine: 23, Label: "store/store.go", Status: CitationCorrected}},
+		},
+		{
+			name:      "labelled code found nowhere",
+			content:   chapter("go store/store.go", "func (s *Store) Delete(key string) {\n\tdelete(s.data, key)\n}"),
+			want:      chapter("go", "func (s *Store) Delete(key string) {\n\tdelete(s.data, key)\n}"),
+			citations: []types.Citation{{Label: "store/store.go", Status: CitationInvented}},
+		},
+		{
+			name:    "unlabelled example is left alone",
+			content: chapter("bash", "go run ./cmd/server --port 8080"),
+			want:    chapter("bash", "go run ./cmd/server --port 8080"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, citations := CiteCodeBlocks(tt.content, files, nil)
+			want := tt.want
+			if len(tt.citations) > 0 {
+				// The citation line goes below the closing fence
+				want = strings.Replace(want, "```\n\nOutro", "```\n\n"+citationLine(tt.citations[0], nil)+"\n\nOutro", 1)
+			}
+			if got != want {
+				t.Errorf("CiteCodeBlocks =\n%s\nwant\n%s", got, want)
+			}
+			if !reflect.DeepEqual(citations, tt.citations) {
+				t.Errorf("citations = %+v, want %+v", citations, tt.citations)
+			}
+		})
+	}
+}
+
+func TestCitationLine(t *testing.T) {
+	link := func(path string, start int, end int) string {
+		return fmt.Sprintf("https://example.com/blob/main/%s#L%d-L%d", path, start, end)
+	}
+
+	tests := []struct {
+		name     string
+		citation types.Citation
+		link     func(path string, start int, end int) string
+		want     string
+	}{
+		{
+			name:     "plain",
+			citation: types.Citation{Path: "store.go", StartLine: 3, EndLine: 9, Status: CitationVerified},
+			want:     "*Source: `store.go:3-9`*",
+		},
+		{
+			name:     "linked",
+			citation: types.Citation{Path: "store.go", StartLine: 3, EndLine: 9, Status: CitationCorrected},
+			link:     link,
+			want:     "*Source: [`store.go:3-9`](https://example.com/blob/main/store.go#L3-L9)*",
+		},
+		{
+			name:     "invented",
+			citation: types.Citation{Label: "store.go", Status: CitationInvented},
+			link:     link,
+			want:     "*⚠️ Not found in the source (`store.go`); illustrative only.*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := citationLine(tt.citation, tt.link); got != tt.want {
+				t.Errorf("citationLine = %q, want %q", got, tt.want)
+			}
+			line := citationLine(tt.citation, tt.link)
+			if invented := tt.citation.Status == CitationInvented; IsCitationLine(line) == invented || IsNotFoundLine(line) != invented {
+				t.Errorf("IsCitationLine = %v, IsNotFoundLine = %v for a %s citation", IsCitationLine(line), IsNotFoundLine(line), tt.citation.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// emptyContentHash is the hash of an empty file, which needs no lookup
+var emptyContentHash = ContentHash("")
+
+// ContentStoreDir returns the absolute directory file contents are stored
+// in, keyed by hash. Configurable via CONTENT_STORE_DIR; defaults to
+// .cb2utorial/content, resolved against the server's working directory.
+// Every service instance must see the same directory.
+func ContentStoreDir() string {
+	dir := os.Getenv("CONTENT_STORE_DIR")
+	if dir == "" {
+		dir = filepath.Join(".cb2utorial", "content")
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// CheckContentStore creates the content store if needed and verifies it is
+// writable, so a misconfigured directory fails at startup rather than in
+// the first run
+func CheckContentStore() error {
+	dir := ContentStoreDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create content store %s: %w", dir, err)
+	}
+	probe, err := os.CreateTemp(dir, "probe.*.tmp")
+	if err != nil {
+		return fmt.Errorf("content store %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// contentPath returns the blob path for a hash, fanned out by its first byte
+func contentPath(hash string) (string, error) {
+	if _, err := hex.DecodeString(hash); err != nil || len(hash) != 64 {
+		return "", fmt.Errorf("invalid content hash %q", hash)
+	}
+	return filepath.Join(ContentStoreDir(), hash[:2], hash), nil
+}
+
+// PutContent stores content under its hash and returns the hash. Storing
+// content that is already present is a no-op.
+func PutContent(content string) (string, error) {
+	hash := ContentHash(content)
+	path, err := contentPath(hash)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err == nil {
+		// Refresh the blob's age so PruneContent keeps it for the new run
+		now := time.Now()
+		if err := os.Chtimes(path, now, now); err != nil {
+			return "", fmt.Errorf("failed to store content: %w", err)
+		}
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create content store: %w", err)
+	}
+	// Write to a temp file and rename so readers never see a partial blob
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to store content: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to store content: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to store content: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to store content: %w", err)
+	}
+	return hash, nil
+}
+
+// GetContent reads content by hash, verifying it against the hash. A blob
+// that is not in the store returns an error wrapping os.ErrNotExist.
+func GetContent(hash string) (string, error) {
+	if hash == emptyContentHash {
+		return "", nil
+	}
+	path, err := contentPath(hash)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("content %s is not in %s: %w", hash, ContentStoreDir(), err)
+	}
+	if ContentHash(string(data)) != hash {
+		return "", fmt.Errorf("content %s in %s is corrupt", hash, ContentStoreDir())
+	}
+	return string(data), nil
+}
+
+// LoadContent fills in the content of a file that carries only its hash.
+// Files with inline content (older artifacts) are returned unchanged.
+func LoadContent(file types.FileContent) (types.FileContent, error) {
+	if file.Content != "" || file.Hash == "" {
+		return file, nil
+	}
+	content, err := GetContent(file.Hash)
+	if err != nil {
+		return file, fmt.Errorf("failed to load %s: %w", file.Path, err)
+	}
+	file.Content = content
+	return file, nil
+}
+
+// LoadContents is LoadContent for a file list; the input is not modified
+func LoadContents(files []types.FileContent) ([]types.FileContent, error) {
+	loaded := make([]types.FileContent, len(files))
+	for i, file := range files {
+		var err error
+		if loaded[i], err = LoadContent(file); err != nil {
+			return nil, err
+		}
+	}
+	return loaded, nil
+}
+
+// PruneContent removes blobs that no run artifact references and that were
+// last stored more than minAge ago, and returns how many were removed. The
+// age keeps the contents of runs that have not checkpointed yet. Manifests
+// record hashes only, so they need no blobs.
+func PruneContent(minAge time.Duration) (int, error) {
+	referenced, err := referencedContent()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-minAge)
+	removed := 0
+	err = filepath.WalkDir(ContentStoreDir(), func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || referenced[entry.Name()] {
+			return nil
+		}
+		// Leave anything that is not a blob or leftover temp file alone
+		if _, err := contentPath(entry.Name()); err != nil && !strings.HasSuffix(entry.Name(), ".tmp") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to prune content store: %w", err)
+	}
+	return removed, nil
+}
+
+// referencedContent returns the content hashes of the files of every run
+// artifact, which a resumed run loads from the store
+func referencedContent() (map[string]bool, error) {
+	entries, err := os.ReadDir(ArtifactDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list run artifacts: %w", err)
+	}
+
+	referenced := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		artifact, err := readRunArtifact(filepath.Join(ArtifactDir(), entry.Name()))
+		if os.IsNotExist(err) {
+			continue // Removed since the listing
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range artifact.State.Files {
+			referenced[file.Hash] = true
+		}
+	}
+	return referenced, nil
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+func TestPruneContent(t *testing.T) {
+	t.Setenv("CONTENT_STORE_DIR", t.TempDir())
+	t.Setenv("ARTIFACT_DIR", t.TempDir())
+
+	store := func(content string, age time.Duration) string {
+		t.Helper()
+		hash, err := PutContent(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, _ := contentPath(hash)
+		stamp := time.Now().Add(-age)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	referencedOld := store("package referenced", 48*time.Hour)
+	unreferencedOld := store("package unreferenced", 48*time.Hour)
+	unreferencedNew := store("package inflight", time.Minute)
+
+	err := SaveRunArtifact(types.RunArtifact{
+		RunID: "run-1",
+		State: types.TutorialState{Files: []types.FileContent{{Path: "a.go", Hash: referencedOld}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := PruneContent(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed %d blobs, want 1", removed)
+	}
+
+	for hash, want := range map[string]bool{referencedOld: true, unreferencedOld: false, unreferencedNew: true} {
+		_, err := GetContent(hash)
+		if kept := err == nil; kept != want {
+			t.Errorf("blob %s kept = %v, want %v (err %v)", hash[:8], kept, want, err)
+		}
+	}
+
+	// Storing a pruned-eligible blob again refreshes it for the new run
+	store("package unreferenced", 48*time.Hour)
+	if _, err := PutContent("package unreferenced"); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := PruneContent(24 * time.Hour); err != nil || removed != 0 {
+		t.Errorf("PruneContent after restore removed %d blobs (err %v), want 0", removed, err)
+	}
+}
+
+func TestContentStoreDirIsAbsolute(t *testing.T) {
+	t.Setenv("CONTENT_STORE_DIR", filepath.Join("relative", "content"))
+	if dir := ContentStoreDir(); !filepath.IsAbs(dir) {
+		t.Errorf("ContentStoreDir() = %q, want an absolute path", dir)
+	}
+	t.Setenv("ARTIFACT_DIR", filepath.Join("relative", "runs"))
+	if dir := ArtifactDir(); !filepath.IsAbs(dir) {
+		t.Errorf("ArtifactDir() = %q, want an absolute path", dir)
+	}
+}
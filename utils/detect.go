@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Reasons a file was not returned by a walk
+const (
+	SkipExcluded     = "excluded-by-pattern"
+	SkipTooLarge     = "too-large"
+	SkipUnreadable   = "unreadable"
+	SkipLimitReached = "limit-reached"
+	SkipBinary       = "binary"
+	SkipGenerated    = "generated"
+	SkipMinified     = "minified"
+)
+
+// sniffLimit bounds how much of a file is inspected for binary content
+const sniffLimit = 8000
+
+// generatedMarker matches Go's generated-code convention
+// (https://go.dev/s/generatedcode), which only counts before the first
+// non-comment, non-blank text of the file
+var generatedMarker = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// lockfiles are generated by package managers and never worth explaining
+var lockfiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"Gemfile.lock":      true,
+	"poetry.lock":       true,
+	"composer.lock":     true,
+	"Pipfile.lock":      true,
+}
+
+// ClassifyContent returns why a file should be skipped (SkipBinary,
+// SkipGenerated or SkipMinified), or "" if it looks like hand-written source
+func ClassifyContent(relPath string, content []byte) string {
+	if isBinary(content) {
+		return SkipBinary
+	}
+	if isGenerated(relPath, content) {
+		return SkipGenerated
+	}
+	if isMinified(relPath, content) {
+		return SkipMinified
+	}
+	return ""
+}
+
+// isBinary sniffs the head of a file for NUL bytes or mostly invalid UTF-8
+func isBinary(content []byte) bool {
+	head := content
+	if len(head) > sniffLimit {
+		head = head[:sniffLimit]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+
+	invalid := 0
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		// A rune cut off by the sniff limit is not evidence of binary data
+		if r == utf8.RuneError && size == 1 && len(head)-i >= utf8.UTFMax {
+			invalid++
+		}
+		i += size
+	}
+	return len(head) > 0 && float64(invalid)/float64(len(head)) > 0.1
+}
+
+// isGenerated detects lockfiles and files carrying a generated-code marker
+func isGenerated(relPath string, content []byte) bool {
+	if lockfiles[path.Base(relPath)] {
+		return true
+	}
+	return hasGeneratedHeader(content)
+}
+
+// hasGeneratedHeader reports whether the comments and blank lines at the
+// start of content include a generated-code marker
+func hasGeneratedHeader(content []byte) bool {
+	inBlock := false
+	for rest, more := string(content), true; more; {
+		var line string
+		line, rest, more = strings.Cut(rest, "\n")
+		line = strings.TrimRight(line, "\r")
+		switch trimmed := strings.TrimSpace(line); {
+		case inBlock:
+			inBlock = !strings.Contains(trimmed, "*/")
+		case generatedMarker.MatchString(line):
+			return true
+		case trimmed == "" || strings.HasPrefix(trimmed, "//"):
+		case strings.HasPrefix(trimmed, "/*"):
+			inBlock = !strings.Contains(trimmed[2:], "*/")
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// isMinified detects bundled/minified code: *.min.* names, or files whose
+// lines are far longer than anything written by hand
+func isMinified(relPath string, content []byte) bool {
+	if strings.Contains(path.Base(relPath), ".min.") {
+		return true
+	}
+	if len(content) < 1024 {
+		return false
+	}
+
+	lines := bytes.Count(content, []byte{'\n'}) + 1
+	if len(content)/lines > 300 {
+		return true
+	}
+
+	// Minifiers strip nearly all whitespace
+	whitespace := 0
+	for _, b := range content {
+		if b == ' ' || b == '\t' || b == '\n' || b == '\r' {
+			whitespace++
+		}
+	}
+	return float64(whitespace)/float64(len(content)) < 0.03
+}
+
+// skipReasons lists skip reasons in the order they are reported
+var skipReasons = []string{SkipExcluded, SkipTooLarge, SkipUnreadable, SkipLimitReached, SkipBinary, SkipGenerated, SkipMinified}
+
+// FormatSkipTotals renders per-reason skip counts, e.g. "too-large=2, binary=5"
+func FormatSkipTotals(totals map[string]int) string {
+	var parts []string
+	for _, reason := range skipReasons {
+		if n := totals[reason]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", reason, n))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
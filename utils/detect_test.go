@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestClassifyContentGenerated(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    string
+	}{
+		{name: "marker", path: "api.pb.go", content: "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n", want: SkipGenerated},
+		{name: "marker with CRLF", path: "api.pb.go", content: "// Code generated by protoc-gen-go. DO NOT EDIT.\r\n\r\npackage api\r\n", want: SkipGenerated},
+		{name: "marker after license", path: "mock.go", content: "// Copyright 2024\n\n// Code generated by mockgen. DO NOT EDIT.\npackage mock\n", want: SkipGenerated},
+		{name: "marker after block comment", path: "mock.go", content: "/*\n * Copyright 2024\n */\n// Code generated by mockgen. DO NOT EDIT.\npackage mock\n", want: SkipGenerated},
+		{name: "marker after package clause", path: "gen.go", content: "package gen\n\n// Code generated by hand. DO NOT EDIT.\n", want: ""},
+		{name: "marker in generator string literal", path: "gen.go", content: "package main\n\nconst header = `\n// Code generated by gen. DO NOT EDIT.\n`\n", want: ""},
+		{name: "lockfile", path: "web/package-lock.json", content: "{}\n", want: SkipGenerated},
+		{name: "marker not at line start", path: "doc.go", content: "package doc\n\n// See \"// Code generated by X. DO NOT EDIT.\"\n", want: ""},
+		{name: "hand written", path: "main.go", content: "package main\n\nfunc main() {}\n", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyContent(tt.path, []byte(tt.content)); got != tt.want {
+				t.Errorf("ClassifyContent(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// ReferencedFile is a file an abstraction references, with the reference
+type ReferencedFile struct {
+	Ref  types.FileRef
+	File types.FileContent
+}
+
+// lineRangeSuffix matches a trailing ":start-end" or ":line" on a path
+var lineRangeSuffix = regexp.MustCompile(`:(\d+)(?:-(\d+))?$`)
+
+// ParseFileRef parses a file reference as written by the LLM or a user:
+// "path", "path:10-40", "path:12" or "path#Symbol"
+func ParseFileRef(value string) types.FileRef {
+	value = strings.Trim(strings.TrimSpace(value), "\"'`")
+	var ref types.FileRef
+	if p, symbol, ok := strings.Cut(value, "#"); ok {
+		value = strings.TrimSpace(p)
+		ref.Symbol = strings.TrimSpace(symbol)
+	}
+	if m := lineRangeSuffix.FindStringSubmatch(value); m != nil {
+		ref.StartLine, _ = strconv.Atoi(m[1])
+		ref.EndLine = ref.StartLine
+		if m[2] != "" {
+			ref.EndLine, _ = strconv.Atoi(m[2])
+		}
+		value = value[:len(value)-len(m[0])]
+	}
+	ref.Path = NormalizeRefPath(value)
+	return ref
+}
+
+// NormalizeRefPath cleans a path to the slash-separated relative form used
+// by FileContent.Path
+func NormalizeRefPath(p string) string {
+	p = strings.ReplaceAll(strings.TrimSpace(p), "\\", "/")
+	p = strings.TrimLeft(path.Clean("/"+p), "/")
+	if p == "" || p == "." {
+		return ""
+	}
+	return p
+}
+
+// MatchFilePath finds the file a possibly inexact path refers to. In order:
+// exact match, case-insensitive match, the longest suffix match on whole path
+// components (either direction, for extra or missing leading directories),
+// then a unique base name match. Returns false if nothing or several files
+// match equally well.
+func MatchFilePath(p string, files []types.FileContent) (types.FileContent, bool) {
+	p = NormalizeRefPath(p)
+	if p == "" {
+		return types.FileContent{}, false
+	}
+
+	for _, file := range files {
+		if file.Path == p {
+			return file, true
+		}
+	}
+
+	// Each rule scores candidates (0 = no match); the best unique score wins
+	lower := strings.ToLower(p)
+	rules := []func(candidate string) int{
+		func(candidate string) int {
+			if candidate == lower {
+				return 1
+			}
+			return 0
+		},
+		func(candidate string) int {
+			return suffixComponents(candidate, lower)
+		},
+		func(candidate string) int {
+			if path.Base(candidate) == path.Base(lower) {
+				return 1
+			}
+			return 0
+		},
+	}
+	for _, score := range rules {
+		var found []types.FileContent
+		best := 0
+		for _, file := range files {
+			s := score(strings.ToLower(file.Path))
+			if s == 0 || s < best {
+				continue
+			}
+			if s > best {
+				best, found = s, nil
+			}
+			found = append(found, file)
+		}
+		if len(found) == 1 {
+			return found[0], true
+		}
+		if len(found) > 1 {
+			return types.FileContent{}, false // Ambiguous; a looser rule would not help
+		}
+	}
+	return types.FileContent{}, false
+}
+
+// suffixComponents returns how many path components two paths share when
+// one is a whole-component suffix of the other, or 0
+func suffixComponents(a, b string) int {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if !strings.HasSuffix(b, "/"+a) {
+		return 0
+	}
+	return strings.Count(a, "/") + 1
+}
+
+// FileRefsFromIndices converts file indices to path references, skipping
+// indices outside files
+func FileRefsFromIndices(indices []int, files []types.FileContent) []types.FileRef {
+	var refs []types.FileRef
+	for _, idx := range indices {
+		if idx >= 0 && idx < len(files) {
+			refs = append(refs, types.FileRef{Path: files[idx].Path})
+		}
+	}
+	return refs
+}
+
+// ResolveAbstractionFiles re-derives an abstraction's FileIndices from its
+// path references against files (for example after a re-read). References
+// that no longer match a file are dropped and returned. Abstractions from
+// before path references existed get them from their indices.
+func ResolveAbstractionFiles(abs types.Abstraction, files []types.FileContent) (types.Abstraction, []types.FileRef) {
+	if len(abs.Files) == 0 {
+		abs.Files = FileRefsFromIndices(abs.FileIndices, files)
+	}
+
+	var kept, missing []types.FileRef
+	abs.FileIndices = nil
+	seen := make(map[int]bool)
+	for _, ref := range abs.Files {
+		file, ok := MatchFilePath(ref.Path, files)
+		if !ok {
+			missing = append(missing, ref)
+			continue
+		}
+		ref.Path = file.Path
+		kept = append(kept, ref)
+		if !seen[file.Index] {
+			seen[file.Index] = true
+			abs.FileIndices = append(abs.FileIndices, file.Index)
+		}
+	}
+	abs.Files = kept
+	return abs, missing
+}
+
+// LookupFiles returns the files an abstraction references, by path, or by
+// index for abstractions without path references. Unlike the old silent
+// skipping, a reference that matches no file is an error.
+func LookupFiles(abs types.Abstraction, files []types.FileContent) ([]ReferencedFile, error) {
+	refs := abs.Files
+	if len(refs) == 0 {
+		for _, idx := range abs.FileIndices {
+			if idx < 0 || idx >= len(files) {
+				return nil, fmt.Errorf("abstraction %q references file index %d, but only %d files were read", abs.Name, idx, len(files))
+			}
+		}
+		refs = FileRefsFromIndices(abs.FileIndices, files)
+	}
+
+	referenced := make([]ReferencedFile, 0, len(refs))
+	for _, ref := range refs {
+		file, ok := MatchFilePath(ref.Path, files)
+		if !ok {
+			return nil, fmt.Errorf("abstraction %q references %s, which matches none of the files read", abs.Name, ref.Path)
+		}
+		ref.Path = file.Path
+		referenced = append(referenced, ReferencedFile{Ref: ref, File: file})
+	}
+	return referenced, nil
+}
+
+// MergeFileRefs returns the union of two reference lists sorted by path,
+// keeping the first of identical references
+func MergeFileRefs(a, b []types.FileRef) []types.FileRef {
+	seen := make(map[types.FileRef]bool)
+	var merged []types.FileRef
+	for _, ref := range append(append([]types.FileRef{}, a...), b...) {
+		if !seen[ref] {
+			seen[ref] = true
+			merged = append(merged, ref)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Path < merged[j].Path })
+	return merged
+}
+
+// SliceLines returns the lines start through end (1-based, inclusive) of
+// content, clamped to its length. A zero start returns content unchanged.
+func SliceLines(content string, start, end int) string {
+	if start <= 0 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	if start > len(lines) {
+		return ""
+	}
+	if end < start || end > len(lines) {
+		end = len(lines)
+	}
+	return strings.Join(lines[start-1:end], "\n")
+}
+
+// DescribeFileRef renders a reference as "path", "path:10-40" and/or
+// "path (Symbol)"
+func DescribeFileRef(ref types.FileRef) string {
+	s := ref.Path
+	if ref.StartLine > 0 {
+		if ref.EndLine > ref.StartLine {
+			s += fmt.Sprintf(":%d-%d", ref.StartLine, ref.EndLine)
+		} else {
+			s += fmt.Sprintf(":%d", ref.StartLine)
+		}
+	}
+	if ref.Symbol != "" {
+		s += " (" + ref.Symbol + ")"
+	}
+	return s
+}
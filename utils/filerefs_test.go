@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+func TestMatchFilePath(t *testing.T) {
+	var files []types.FileContent
+	for i, p := range []string{
+		"README.md",
+		"internal/server/Router.go",
+		"pkg/router.go",
+		"server/router.go",
+		"cmd/app/main.go",
+		"cmd/tool/main.go",
+		"api/v1/handler.go",
+		"api/v2/handler.go",
+		"store/store.go",
+	} {
+		files = append(files, types.FileContent{Index: i, Path: p})
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string // "" = no match
+	}{
+		{name: "exact", path: "pkg/router.go", want: "pkg/router.go"},
+		{name: "normalized exact", path: "./pkg\\router.go", want: "pkg/router.go"},
+		{name: "case only", path: "readme.MD", want: "README.md"},
+		{name: "missing leading directories", path: "v1/handler.go", want: "api/v1/handler.go"},
+		{name: "extra leading directories", path: "src/github.com/org/repo/store/store.go", want: "store/store.go"},
+		{name: "longest component suffix wins", path: "repo/internal/server/router.go", want: "internal/server/Router.go"},
+		{name: "partial component is not a suffix", path: "ter/router.go", want: ""},
+		{name: "unique base name", path: "lib/store.go", want: "store/store.go"},
+		{name: "ambiguous suffix", path: "main.go", want: ""},
+		{name: "ambiguous equal-length suffix", path: "handler.go", want: ""},
+		{name: "no match", path: "missing.go", want: ""},
+		{name: "empty", path: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, ok := MatchFilePath(tt.path, files)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("MatchFilePath(%q) = %s, want no match", tt.path, file.Path)
+				}
+				return
+			}
+			if !ok || file.Path != tt.want {
+				t.Errorf("MatchFilePath(%q) = %s, %v, want %s", tt.path, file.Path, ok, tt.want)
+			}
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/gobwas/glob"
 )
@@ -22,29 +24,172 @@ type WalkDirectoryOptions struct {
 	ExcludePatterns []string
 	MaxFileSize     int64
 	MaxFiles        int
+	OnSkip          func(path, reason string) // Optional; called with a Skip* reason for files and pruned directories not returned
 }
 
-// WalkDirectory traverses a directory and returns matching files
-func WalkDirectory(opts WalkDirectoryOptions) ([]FileInfo, error) {
-	var files []FileInfo
+// fileFilter applies include/exclude globs to slash-separated relative paths
+type fileFilter struct {
+	include    []glob.Glob
+	exclude    []glob.Glob
+	excludeDir []glob.Glob // Excludes ending in "*", which cover a whole directory
+}
 
-	// Compile glob patterns
-	includeGlobs := make([]glob.Glob, 0, len(opts.IncludePatterns))
-	for _, pattern := range opts.IncludePatterns {
+// newFileFilter compiles the include and exclude patterns
+func newFileFilter(includePatterns, excludePatterns []string) (*fileFilter, error) {
+	filter := &fileFilter{
+		include: make([]glob.Glob, 0, len(includePatterns)),
+		exclude: make([]glob.Glob, 0, len(excludePatterns)),
+	}
+
+	for _, pattern := range includePatterns {
 		g, err := glob.Compile(pattern)
 		if err != nil {
 			return nil, fmt.Errorf("invalid include pattern %s: %w", pattern, err)
 		}
-		includeGlobs = append(includeGlobs, g)
+		filter.include = append(filter.include, g)
 	}
 
-	excludeGlobs := make([]glob.Glob, 0, len(opts.ExcludePatterns))
-	for _, pattern := range opts.ExcludePatterns {
+	for _, pattern := range excludePatterns {
 		g, err := glob.Compile(pattern)
 		if err != nil {
 			return nil, fmt.Errorf("invalid exclude pattern %s: %w", pattern, err)
 		}
-		excludeGlobs = append(excludeGlobs, g)
+		filter.exclude = append(filter.exclude, g)
+		if strings.HasSuffix(pattern, "*") && !strings.HasSuffix(pattern, `\*`) {
+			filter.excludeDir = append(filter.excludeDir, g)
+		}
+	}
+
+	return filter, nil
+}
+
+// matches reports whether a path passes the filter
+func (f *fileFilter) matches(normalizedPath string) bool {
+	// Check exclude patterns first
+	for _, g := range f.exclude {
+		if g.Match(normalizedPath) {
+			return false
+		}
+	}
+
+	// Check include patterns (if any specified)
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, g := range f.include {
+		if g.Match(normalizedPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludesDir reports whether every path under a directory is excluded, so
+// the walk need not enter it. Only patterns ending in "*" qualify: if one
+// matches "dir/", the trailing wildcard also matches anything below it.
+func (f *fileFilter) excludesDir(normalizedDir string) bool {
+	for _, g := range f.excludeDir {
+		if g.Match(normalizedDir + "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// errLimitReached stops a walk once MaxFiles files have been collected
+var errLimitReached = errors.New("max files reached")
+
+// errTooLarge is returned by a read that finds the file exceeds MaxFileSize
+var errTooLarge = errors.New("file exceeds the maximum size")
+
+// fileCollector applies the filters and limits shared by all walkers
+type fileCollector struct {
+	opts   WalkDirectoryOptions
+	filter *fileFilter
+	files  []FileInfo
+}
+
+// newFileCollector compiles the filter for a walk
+func newFileCollector(opts WalkDirectoryOptions) (*fileCollector, error) {
+	filter, err := newFileFilter(opts.IncludePatterns, opts.ExcludePatterns)
+	if err != nil {
+		return nil, err
+	}
+	return &fileCollector{opts: opts, filter: filter}, nil
+}
+
+// add collects a file if it passes the filters. size may be -1 when it is
+// only known after reading; read is called only for files that pass.
+// Returns errLimitReached once MaxFiles is hit, after reporting a single
+// summary skip for the files left unscanned.
+func (c *fileCollector) add(normalizedPath string, size int64, read func() ([]byte, error)) error {
+	if !c.filter.matches(normalizedPath) {
+		c.skip(normalizedPath, SkipExcluded)
+		return nil
+	}
+
+	// Check file size limit
+	if c.opts.MaxFileSize > 0 && size > c.opts.MaxFileSize {
+		c.skip(normalizedPath, SkipTooLarge)
+		return nil
+	}
+
+	// Check max files limit
+	if c.opts.MaxFiles > 0 && len(c.files) >= c.opts.MaxFiles {
+		c.skip(fmt.Sprintf("%s and later files (not scanned)", normalizedPath), SkipLimitReached)
+		return errLimitReached // Stop walking
+	}
+
+	content, err := read()
+	if errors.Is(err, errTooLarge) {
+		c.skip(normalizedPath, SkipTooLarge) // Size was misreported
+		return nil
+	}
+	if err != nil {
+		// Skip files we can't read (permissions, etc.)
+		c.skip(normalizedPath, SkipUnreadable)
+		return nil
+	}
+	if c.opts.MaxFileSize > 0 && int64(len(content)) > c.opts.MaxFileSize {
+		c.skip(normalizedPath, SkipTooLarge) // Size was unknown until read
+		return nil
+	}
+
+	// Binary, generated and minified files only add noise to the prompts
+	if reason := ClassifyContent(normalizedPath, content); reason != "" {
+		c.skip(normalizedPath, reason)
+		return nil
+	}
+
+	c.files = append(c.files, FileInfo{
+		RelativePath: normalizedPath,
+		Content:      string(content),
+	})
+	return nil
+}
+
+// skipDir reports whether the walk should not enter a directory, reporting
+// it once if so
+func (c *fileCollector) skipDir(normalizedDir string) bool {
+	if !c.filter.excludesDir(normalizedDir) {
+		return false
+	}
+	c.skip(normalizedDir+"/", SkipExcluded)
+	return true
+}
+
+// skip reports a file that was not collected
+func (c *fileCollector) skip(normalizedPath string, reason string) {
+	if c.opts.OnSkip != nil {
+		c.opts.OnSkip(normalizedPath, reason)
+	}
+}
+
+// WalkDirectory traverses a directory and returns matching files
+func WalkDirectory(opts WalkDirectoryOptions) ([]FileInfo, error) {
+	collector, err := newFileCollector(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get absolute path for proper relative path calculation
@@ -58,11 +203,6 @@ func WalkDirectory(opts WalkDirectoryOptions) ([]FileInfo, error) {
 			return err
 		}
 
-		// Skip directories
-		if info.IsDir() {
-			return nil
-		}
-
 		// Calculate relative path
 		relPath, err := filepath.Rel(absRoot, path)
 		if err != nil {
@@ -72,57 +212,71 @@ func WalkDirectory(opts WalkDirectoryOptions) ([]FileInfo, error) {
 		// Normalize path separators for matching (use forward slash)
 		normalizedPath := filepath.ToSlash(relPath)
 
-		// Check exclude patterns first
-		for _, g := range excludeGlobs {
-			if g.Match(normalizedPath) {
-				return nil // Skip this file
+		// Prune excluded directories instead of visiting every file in them
+		if info.IsDir() {
+			if path != absRoot && collector.skipDir(normalizedPath) {
+				return filepath.SkipDir
 			}
+			return nil
 		}
 
-		// Check include patterns (if any specified)
-		if len(includeGlobs) > 0 {
-			matched := false
-			for _, g := range includeGlobs {
-				if g.Match(normalizedPath) {
-					matched = true
-					break
-				}
-			}
-			if !matched {
-				return nil // Skip this file
-			}
+		err = collector.add(normalizedPath, info.Size(), func() ([]byte, error) {
+			return os.ReadFile(path)
+		})
+		if errors.Is(err, errLimitReached) {
+			return filepath.SkipAll // Stop walking
 		}
+		return err
+	})
 
-		// Check file size limit
-		if opts.MaxFileSize > 0 && info.Size() > opts.MaxFileSize {
-			return nil // Skip large files
-		}
+	if err != nil {
+		return nil, fmt.Errorf("error walking directory: %w", err)
+	}
 
-		// Check max files limit
-		if opts.MaxFiles > 0 && len(files) >= opts.MaxFiles {
-			return filepath.SkipAll // Stop walking
-		}
+	return collector.files, nil
+}
 
-		// Read file content
-		content, err := os.ReadFile(path)
-		if err != nil {
-			// Skip files we can't read (permissions, etc.)
+// WalkGitRevision returns matching files as they exist at a git revision
+// (branch, tag or commit), read from the object store of the repository at
+// opts.RootPath without touching its working tree. Also returns the commit SHA.
+func WalkGitRevision(opts WalkDirectoryOptions, rev string) ([]FileInfo, string, error) {
+	collector, err := newFileCollector(opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	repo, err := OpenGitRepo(opts.RootPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	commit, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+
+	err = repo.WalkCommit(commit, func(entry GitTreeEntry) error {
+		if entry.Mode == gitModeTree {
+			if collector.skipDir(entry.Path) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
-
-		files = append(files, FileInfo{
-			RelativePath: normalizedPath,
-			Content:      string(content),
+		// Read the size from the object header so oversized blobs are
+		// skipped before they are inflated; a bad header fails the read too
+		size, err := repo.ObjectSize(entry.SHA)
+		if err != nil {
+			size = -1
+		}
+		return collector.add(entry.Path, size, func() ([]byte, error) {
+			return repo.ReadBlob(entry.SHA)
 		})
-
-		return nil
 	})
-
-	if err != nil {
-		return nil, fmt.Errorf("error walking directory: %w", err)
+	if err != nil && !errors.Is(err, errLimitReached) {
+		return nil, "", fmt.Errorf("error walking %s: %w", rev, err)
 	}
 
-	return files, nil
+	return collector.files, commit, nil
 }
 
 // SanitizeFilename converts a string into a valid filename
@@ -130,9 +284,10 @@ func SanitizeFilename(name string) string {
 	// Convert to lowercase
 	name = strings.ToLower(name)
 
-	// Replace spaces and special chars with underscore
+	// Replace spaces and special chars with underscore. Letters of any
+	// script are kept so translated titles still yield readable names.
 	name = strings.Map(func(r rune) rune {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || (r > unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))) {
 			return r
 		}
 		return '_'
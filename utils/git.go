@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FindGitDir locates the .git directory for a repository root.
+// Handles worktrees and submodules where .git is a "gitdir: <path>" file.
+// Returns an empty string if the path is not a git repository.
+func FindGitDir(repoPath string) (string, error) {
+	gitPath := filepath.Join(repoPath, ".git")
+	info, err := os.Stat(gitPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to stat .git: %w", err)
+	}
+
+	if info.IsDir() {
+		return gitPath, nil
+	}
+
+	// .git file pointing elsewhere (worktree/submodule)
+	data, err := os.ReadFile(gitPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read .git file: %w", err)
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "gitdir:") {
+		return "", fmt.Errorf("unrecognized .git file format")
+	}
+	dir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoPath, dir)
+	}
+	return dir, nil
+}
+
+// ResolveHead returns the commit SHA that HEAD points to.
+// Returns an empty string if the path is not a git repository or HEAD is unborn.
+func ResolveHead(repoPath string) (string, error) {
+	gitDir, err := FindGitDir(repoPath)
+	if err != nil || gitDir == "" {
+		return "", err
+	}
+	return resolveRef(gitDir, "HEAD")
+}
+
+// resolveRef follows symbolic refs until it reaches a commit SHA
+func resolveRef(gitDir string, ref string) (string, error) {
+	for depth := 0; depth < 10; depth++ {
+		value, err := readRef(gitDir, ref)
+		if err != nil || value == "" {
+			return "", err
+		}
+
+		if !strings.HasPrefix(value, "ref:") {
+			return value, nil
+		}
+		ref = strings.TrimSpace(strings.TrimPrefix(value, "ref:"))
+	}
+	return "", fmt.Errorf("too many levels of symbolic refs resolving %s", ref)
+}
+
+// readRef reads a loose ref file, falling back to packed-refs
+func readRef(gitDir string, ref string) (string, error) {
+	for _, dir := range refDirs(gitDir) {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref)))
+		if err == nil {
+			return strings.TrimSpace(string(data)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to read ref %s: %w", ref, err)
+		}
+	}
+
+	for _, dir := range refDirs(gitDir) {
+		sha, err := readPackedRef(filepath.Join(dir, "packed-refs"), ref)
+		if err != nil || sha != "" {
+			return sha, err
+		}
+	}
+	return "", nil
+}
+
+// refDirs returns the per-worktree git dir followed by the common dir (if different)
+func refDirs(gitDir string) []string {
+	dirs := []string{gitDir}
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return dirs
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return append(dirs, filepath.Clean(common))
+}
+
+// readPackedRef looks up a ref in a packed-refs file
+func readPackedRef(path string, ref string) (string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open packed-refs: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) == 2 && parts[1] == ref {
+			return parts[0], nil
+		}
+	}
+	return "", scanner.Err()
+}
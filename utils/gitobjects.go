@@ -0,0 +1,656 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Git object types as stored in pack entry headers
+const (
+	gitObjCommit   = 1
+	gitObjTree     = 2
+	gitObjBlob     = 3
+	gitObjTag      = 4
+	gitObjOfsDelta = 6
+	gitObjRefDelta = 7
+)
+
+// gitModeTree is the tree entry mode of a subdirectory
+const gitModeTree = "40000"
+
+// gitTypeNames maps loose object header names to pack type codes
+var gitTypeNames = map[string]int{
+	"commit": gitObjCommit,
+	"tree":   gitObjTree,
+	"blob":   gitObjBlob,
+	"tag":    gitObjTag,
+}
+
+// GitRepo reads objects directly from a repository's .git object store
+type GitRepo struct {
+	gitDir     string
+	objectsDir string
+	packs      []*gitPack
+}
+
+// gitPack is a packfile with its version 2 index loaded into memory
+type gitPack struct {
+	packPath string
+	names    [][20]byte // Sorted object names
+	offsets  []int64    // Pack offset per name
+}
+
+// GitTreeEntry is a file found while walking a tree
+type GitTreeEntry struct {
+	Path string // Slash-separated path from the tree root
+	Mode string
+	SHA  string
+}
+
+// OpenGitRepo opens the object store of the repository at repoPath
+func OpenGitRepo(repoPath string) (*GitRepo, error) {
+	gitDir, err := FindGitDir(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if gitDir == "" {
+		return nil, fmt.Errorf("%s is not a git repository", repoPath)
+	}
+
+	// Worktrees share objects with the main repository
+	dirs := refDirs(gitDir)
+	objectsDir := filepath.Join(dirs[len(dirs)-1], "objects")
+
+	repo := &GitRepo{gitDir: gitDir, objectsDir: objectsDir}
+	idxFiles, err := filepath.Glob(filepath.Join(objectsDir, "pack", "*.idx"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packfiles: %w", err)
+	}
+	for _, idxPath := range idxFiles {
+		pack, err := loadGitPackIndex(idxPath)
+		if err != nil {
+			return nil, err
+		}
+		repo.packs = append(repo.packs, pack)
+	}
+	return repo, nil
+}
+
+// ResolveRevision resolves a branch, tag, remote ref, HEAD or (abbreviated)
+// commit SHA to the full SHA of a commit. Annotated tags are peeled.
+func (r *GitRepo) ResolveRevision(rev string) (string, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	sha := ""
+	candidates := []string{rev, "refs/" + rev, "refs/tags/" + rev, "refs/heads/" + rev, "refs/remotes/" + rev, "refs/remotes/" + rev + "/HEAD"}
+	for _, ref := range candidates {
+		// Unreadable candidates (directories, non-ref files) fall through
+		resolved, err := resolveRef(r.gitDir, ref)
+		if err != nil || resolved == "" {
+			continue
+		}
+		if _, err := parseSHA(resolved); err == nil {
+			sha = resolved
+			break
+		}
+	}
+
+	if sha == "" {
+		expanded, err := r.expandSHA(rev)
+		if err != nil {
+			return "", err
+		}
+		sha = expanded
+	}
+
+	// Peel annotated tags down to the commit they point at
+	for depth := 0; depth < 10; depth++ {
+		objType, data, err := r.ReadObject(sha)
+		if err != nil {
+			return "", err
+		}
+		switch objType {
+		case gitObjCommit:
+			return sha, nil
+		case gitObjTag:
+			target := headerField(data, "object")
+			if target == "" {
+				return "", fmt.Errorf("tag object %s has no target", sha)
+			}
+			sha = target
+		default:
+			return "", fmt.Errorf("revision %s does not point to a commit", rev)
+		}
+	}
+	return "", fmt.Errorf("too many nested tags resolving %s", rev)
+}
+
+// ReadBlob returns the content of a blob object
+func (r *GitRepo) ReadBlob(sha string) ([]byte, error) {
+	objType, data, err := r.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	if objType != gitObjBlob {
+		return nil, fmt.Errorf("object %s is not a blob", sha)
+	}
+	return data, nil
+}
+
+// WalkCommit calls fn for every directory and regular file in a commit's
+// tree; returning filepath.SkipDir for a directory skips its contents.
+// Symlinks and submodules are skipped.
+func (r *GitRepo) WalkCommit(commitSHA string, fn func(entry GitTreeEntry) error) error {
+	objType, data, err := r.ReadObject(commitSHA)
+	if err != nil {
+		return err
+	}
+	if objType != gitObjCommit {
+		return fmt.Errorf("object %s is not a commit", commitSHA)
+	}
+	treeSHA := headerField(data, "tree")
+	if treeSHA == "" {
+		return fmt.Errorf("commit %s has no tree", commitSHA)
+	}
+	return r.walkTree(treeSHA, "", fn)
+}
+
+// walkTree recursively visits a tree object
+func (r *GitRepo) walkTree(treeSHA string, prefix string, fn func(entry GitTreeEntry) error) error {
+	objType, data, err := r.ReadObject(treeSHA)
+	if err != nil {
+		return err
+	}
+	if objType != gitObjTree {
+		return fmt.Errorf("object %s is not a tree", treeSHA)
+	}
+
+	for len(data) > 0 {
+		// Entry format: "<mode> <name>\0<20-byte sha>"
+		space := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if space < 0 || nul < space || nul+21 > len(data) {
+			return fmt.Errorf("corrupt tree object %s", treeSHA)
+		}
+		mode := string(data[:space])
+		name := string(data[space+1 : nul])
+		sha := hex.EncodeToString(data[nul+1 : nul+21])
+		data = data[nul+21:]
+
+		path := name
+		if prefix != "" {
+			path = prefix + "/" + name
+		}
+
+		switch {
+		case mode == gitModeTree:
+			err := fn(GitTreeEntry{Path: path, Mode: mode, SHA: sha})
+			if errors.Is(err, filepath.SkipDir) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := r.walkTree(sha, path, fn); err != nil {
+				return err
+			}
+		case strings.HasPrefix(mode, "100"):
+			if err := fn(GitTreeEntry{Path: path, Mode: mode, SHA: sha}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadObject returns the type and fully resolved content of an object
+func (r *GitRepo) ReadObject(sha string) (int, []byte, error) {
+	name, err := parseSHA(sha)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Loose objects take precedence
+	loosePath := filepath.Join(r.objectsDir, sha[:2], sha[2:])
+	if f, err := os.Open(loosePath); err == nil {
+		defer f.Close()
+		return readLooseObject(f, sha)
+	}
+
+	for _, pack := range r.packs {
+		if offset, ok := pack.find(name); ok {
+			return r.readPackObject(pack, offset)
+		}
+	}
+	return 0, nil, fmt.Errorf("object %s not found", sha)
+}
+
+// ObjectSize returns the size of an object's content without inflating it:
+// from the loose object header, the pack entry header, or for a delta the
+// target size at the start of the delta stream
+func (r *GitRepo) ObjectSize(sha string) (int64, error) {
+	name, err := parseSHA(sha)
+	if err != nil {
+		return 0, err
+	}
+
+	loosePath := filepath.Join(r.objectsDir, sha[:2], sha[2:])
+	if f, err := os.Open(loosePath); err == nil {
+		defer f.Close()
+		return looseObjectSize(f, sha)
+	}
+
+	for _, pack := range r.packs {
+		if offset, ok := pack.find(name); ok {
+			return packObjectSize(pack, offset)
+		}
+	}
+	return 0, fmt.Errorf("object %s not found", sha)
+}
+
+// expandSHA resolves an abbreviated object name (at least 4 hex chars)
+func (r *GitRepo) expandSHA(prefix string) (string, error) {
+	prefix = strings.ToLower(prefix)
+	if len(prefix) < 4 || len(prefix) > 40 {
+		return "", fmt.Errorf("unknown revision %q", prefix)
+	}
+	if _, err := hex.DecodeString(prefix[:len(prefix)&^1]); err != nil {
+		return "", fmt.Errorf("unknown revision %q", prefix)
+	}
+
+	matches := make(map[string]bool)
+	entries, _ := os.ReadDir(filepath.Join(r.objectsDir, prefix[:2]))
+	for _, entry := range entries {
+		full := prefix[:2] + entry.Name()
+		if strings.HasPrefix(full, prefix) {
+			matches[full] = true
+		}
+	}
+	for _, pack := range r.packs {
+		for _, name := range pack.names {
+			full := hex.EncodeToString(name[:])
+			if strings.HasPrefix(full, prefix) {
+				matches[full] = true
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown revision %q", prefix)
+	case 1:
+		for full := range matches {
+			return full, nil
+		}
+	}
+	return "", fmt.Errorf("ambiguous revision %q", prefix)
+}
+
+// readLooseObject inflates a loose object and splits off its header
+func readLooseObject(f io.Reader, sha string) (int, []byte, error) {
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to inflate object %s: %w", sha, err)
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to inflate object %s: %w", sha, err)
+	}
+
+	nul := bytes.IndexByte(raw, 0)
+	if nul < 0 {
+		return 0, nil, fmt.Errorf("corrupt object %s", sha)
+	}
+	header := strings.SplitN(string(raw[:nul]), " ", 2)
+	objType, ok := gitTypeNames[header[0]]
+	if !ok {
+		return 0, nil, fmt.Errorf("unknown object type %q for %s", header[0], sha)
+	}
+	return objType, raw[nul+1:], nil
+}
+
+// looseObjectSize inflates only the header of a loose object
+func looseObjectSize(f io.Reader, sha string) (int64, error) {
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inflate object %s: %w", sha, err)
+	}
+	defer zr.Close()
+
+	header, err := bufio.NewReader(zr).ReadString(0)
+	if err != nil {
+		return 0, fmt.Errorf("corrupt object %s", sha)
+	}
+	fields := strings.SplitN(strings.TrimSuffix(header, "\x00"), " ", 2)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("corrupt object %s", sha)
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("corrupt object %s: %w", sha, err)
+	}
+	return size, nil
+}
+
+// packObjectSize reads the size of the pack entry at offset. A delta entry's
+// header gives the delta's size, so the target size is read from the first
+// bytes of the inflated delta.
+func packObjectSize(pack *gitPack, offset int64) (int64, error) {
+	f, err := os.Open(pack.packPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open packfile: %w", err)
+	}
+	defer f.Close()
+	reader := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+
+	// Header: type in bits 4-6 of the first byte, size as a varint
+	b, err := reader.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read pack entry: %w", err)
+	}
+	objType := int(b>>4) & 7
+	size, shift := int64(b&0x0f), 4
+	for b&0x80 != 0 {
+		if b, err = reader.ReadByte(); err != nil {
+			return 0, fmt.Errorf("failed to read pack entry: %w", err)
+		}
+		size |= int64(b&0x7f) << shift
+		shift += 7
+	}
+
+	switch objType {
+	case gitObjCommit, gitObjTree, gitObjBlob, gitObjTag:
+		return size, nil
+	case gitObjOfsDelta:
+		for b = 0x80; b&0x80 != 0; {
+			if b, err = reader.ReadByte(); err != nil {
+				return 0, err
+			}
+		}
+	case gitObjRefDelta:
+		if _, err := reader.Discard(20); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unknown pack object type %d in %s", objType, pack.packPath)
+	}
+
+	// Delta header: base size, then target size, as little-endian varints
+	zr, err := zlib.NewReader(reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inflate pack entry: %w", err)
+	}
+	defer zr.Close()
+	delta := bufio.NewReader(zr)
+	if _, err := binary.ReadUvarint(delta); err != nil {
+		return 0, fmt.Errorf("corrupt delta in %s: %w", pack.packPath, err)
+	}
+	target, err := binary.ReadUvarint(delta)
+	if err != nil {
+		return 0, fmt.Errorf("corrupt delta in %s: %w", pack.packPath, err)
+	}
+	return int64(target), nil
+}
+
+// readPackObject reads an object at offset, applying delta chains
+func (r *GitRepo) readPackObject(pack *gitPack, offset int64) (int, []byte, error) {
+	f, err := os.Open(pack.packPath)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to open packfile: %w", err)
+	}
+	defer f.Close()
+	return r.readPackEntry(pack, f, offset, 0)
+}
+
+// readPackEntry reads one pack entry; depth guards against corrupt delta loops
+func (r *GitRepo) readPackEntry(pack *gitPack, f *os.File, offset int64, depth int) (int, []byte, error) {
+	if depth > 50 {
+		return 0, nil, fmt.Errorf("delta chain too deep in %s", pack.packPath)
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(f, offset, 1<<62))
+
+	// Header: type in bits 4-6 of the first byte, size as a varint
+	b, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read pack entry: %w", err)
+	}
+	objType := int(b>>4) & 7
+	for b&0x80 != 0 {
+		if b, err = reader.ReadByte(); err != nil {
+			return 0, nil, fmt.Errorf("failed to read pack entry: %w", err)
+		}
+	}
+
+	switch objType {
+	case gitObjCommit, gitObjTree, gitObjBlob, gitObjTag:
+		data, err := inflate(reader)
+		return objType, data, err
+
+	case gitObjOfsDelta:
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		rel := int64(b & 0x7f)
+		for b&0x80 != 0 {
+			if b, err = reader.ReadByte(); err != nil {
+				return 0, nil, err
+			}
+			rel = ((rel + 1) << 7) | int64(b&0x7f)
+		}
+		delta, err := inflate(reader)
+		if err != nil {
+			return 0, nil, err
+		}
+		baseType, base, err := r.readPackEntry(pack, f, offset-rel, depth+1)
+		if err != nil {
+			return 0, nil, err
+		}
+		data, err := applyGitDelta(base, delta)
+		return baseType, data, err
+
+	case gitObjRefDelta:
+		var baseName [20]byte
+		if _, err := io.ReadFull(reader, baseName[:]); err != nil {
+			return 0, nil, err
+		}
+		delta, err := inflate(reader)
+		if err != nil {
+			return 0, nil, err
+		}
+		baseType, base, err := r.ReadObject(hex.EncodeToString(baseName[:]))
+		if err != nil {
+			return 0, nil, err
+		}
+		data, err := applyGitDelta(base, delta)
+		return baseType, data, err
+	}
+
+	return 0, nil, fmt.Errorf("unknown pack object type %d in %s", objType, pack.packPath)
+}
+
+// inflate decompresses a zlib stream
+func inflate(r io.Reader) ([]byte, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate pack entry: %w", err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// applyGitDelta reconstructs an object from its base and a delta
+func applyGitDelta(base []byte, delta []byte) ([]byte, error) {
+	readSize := func() int {
+		size, shift := 0, 0
+		for len(delta) > 0 {
+			b := delta[0]
+			delta = delta[1:]
+			size |= int(b&0x7f) << shift
+			shift += 7
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		return size
+	}
+
+	if srcSize := readSize(); srcSize != len(base) {
+		return nil, fmt.Errorf("delta base size mismatch: %d != %d", srcSize, len(base))
+	}
+	// The target size comes from the (untrusted) delta, so it only bounds the
+	// output; preallocation is capped by what base and delta can add up to
+	dstSize := readSize()
+	if dstSize < 0 {
+		return nil, fmt.Errorf("invalid delta target size %d", dstSize)
+	}
+	out := make([]byte, 0, min(dstSize, len(base)+len(delta)))
+
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+
+		if op&0x80 != 0 {
+			// Copy from base: offset and size bytes are present per bit flags
+			var offset, size int
+			for i := 0; i < 4; i++ {
+				if op&(1<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, fmt.Errorf("truncated delta")
+					}
+					offset |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			for i := 0; i < 3; i++ {
+				if op&(1<<(4+i)) != 0 {
+					if len(delta) == 0 {
+						return nil, fmt.Errorf("truncated delta")
+					}
+					size |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > len(base) {
+				return nil, fmt.Errorf("delta copy out of range")
+			}
+			if len(out)+size > dstSize {
+				return nil, fmt.Errorf("delta exceeds its target size %d", dstSize)
+			}
+			out = append(out, base[offset:offset+size]...)
+		} else if op != 0 {
+			// Insert literal bytes
+			n := int(op)
+			if n > len(delta) {
+				return nil, fmt.Errorf("truncated delta")
+			}
+			if len(out)+n > dstSize {
+				return nil, fmt.Errorf("delta exceeds its target size %d", dstSize)
+			}
+			out = append(out, delta[:n]...)
+			delta = delta[n:]
+		} else {
+			return nil, fmt.Errorf("invalid delta opcode 0")
+		}
+	}
+	if len(out) != dstSize {
+		return nil, fmt.Errorf("delta target size mismatch: %d != %d", len(out), dstSize)
+	}
+	return out, nil
+}
+
+// loadGitPackIndex reads a version 2 pack index
+func loadGitPackIndex(idxPath string) (*gitPack, error) {
+	data, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack index: %w", err)
+	}
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index format: %s", idxPath)
+	}
+
+	count := int(binary.BigEndian.Uint32(data[8+255*4 : 8+256*4]))
+	namesStart := 8 + 256*4
+	crcStart := namesStart + count*20
+	offsetsStart := crcStart + count*4
+	largeStart := offsetsStart + count*4
+	if len(data) < largeStart {
+		return nil, fmt.Errorf("truncated pack index: %s", idxPath)
+	}
+
+	pack := &gitPack{
+		packPath: strings.TrimSuffix(idxPath, ".idx") + ".pack",
+		names:    make([][20]byte, count),
+		offsets:  make([]int64, count),
+	}
+	for i := 0; i < count; i++ {
+		copy(pack.names[i][:], data[namesStart+i*20:])
+		offset := binary.BigEndian.Uint32(data[offsetsStart+i*4:])
+		if offset&0x80000000 != 0 {
+			// Offsets beyond 2GB live in the 64-bit table
+			pos := largeStart + int(offset&0x7fffffff)*8
+			if pos+8 > len(data) {
+				return nil, fmt.Errorf("truncated pack index: %s", idxPath)
+			}
+			pack.offsets[i] = int64(binary.BigEndian.Uint64(data[pos:]))
+		} else {
+			pack.offsets[i] = int64(offset)
+		}
+	}
+	return pack, nil
+}
+
+// find returns the pack offset of an object
+func (p *gitPack) find(name [20]byte) (int64, bool) {
+	i := sort.Search(len(p.names), func(i int) bool {
+		return bytes.Compare(p.names[i][:], name[:]) >= 0
+	})
+	if i < len(p.names) && p.names[i] == name {
+		return p.offsets[i], true
+	}
+	return 0, false
+}
+
+// parseSHA decodes a full 40-character hex object name
+func parseSHA(sha string) ([20]byte, error) {
+	var name [20]byte
+	if len(sha) != 40 {
+		return name, fmt.Errorf("invalid object name %q", sha)
+	}
+	if _, err := hex.Decode(name[:], []byte(sha)); err != nil {
+		return name, fmt.Errorf("invalid object name %q", sha)
+	}
+	return name, nil
+}
+
+// headerField returns the value of a header line in a commit or tag object
+func headerField(data []byte, field string) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break // End of headers
+		}
+		if value, ok := strings.CutPrefix(line, field+" "); ok {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,330 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fixtureRepoRoot returns a directory whose .git file points at
+// testdata/gitrepo.git (see testdata/make_gitrepo.sh), as a worktree would
+func fixtureRepoRoot(t *testing.T) string {
+	t.Helper()
+	gitDir, err := filepath.Abs(filepath.Join("testdata", "gitrepo.git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ".git"), []byte("gitdir: "+gitDir+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// openFixtureRepo opens the fixture repository
+func openFixtureRepo(t *testing.T) *GitRepo {
+	t.Helper()
+	repo, err := OpenGitRepo(fixtureRepoRoot(t))
+	if err != nil {
+		t.Fatalf("OpenGitRepo: %v", err)
+	}
+	return repo
+}
+
+// fixtureLines returns lines.txt as written by make_gitrepo.sh
+func fixtureLines(changed ...int) string {
+	var b strings.Builder
+	for i := 1; i <= 100; i++ {
+		line := fmt.Sprintf("line %d", i)
+		for _, c := range changed {
+			if c == i {
+				line += " changed"
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func TestGitRepoReadsRevisions(t *testing.T) {
+	repo := openFixtureRepo(t)
+
+	tests := []struct {
+		name   string
+		rev    string
+		commit string
+		files  map[string]string
+	}{
+		{
+			name:   "packed ref, ref-delta pack",
+			rev:    "v1",
+			commit: "487f8db3730ae10a1858dc641bf61dda431b9a07",
+			files: map[string]string{
+				"README.md":     "fixture\n",
+				"dir/nested.go": "package dir\n",
+				"lines.txt":     fixtureLines(10),
+			},
+		},
+		{
+			name:   "abbreviated SHA",
+			rev:    "487f8db",
+			commit: "487f8db3730ae10a1858dc641bf61dda431b9a07",
+			files: map[string]string{
+				"README.md":     "fixture\n",
+				"dir/nested.go": "package dir\n",
+				"lines.txt":     fixtureLines(10),
+			},
+		},
+		{
+			name:   "annotated tag, offset-delta pack",
+			rev:    "v2",
+			commit: "846f45633d6b483a8996ff12cae0f2cfee301c7c",
+			files: map[string]string{
+				"README.md":        "fixture\n",
+				"dir/nested.go":    "package dir\n",
+				"dir/sub/deep.txt": "deep\n",
+				"lines.txt":        fixtureLines(10, 50, 90),
+			},
+		},
+		{
+			name:   "loose ref, loose objects",
+			rev:    "main",
+			commit: "64659d63e5b6f942ef09346d80c864b29926730d",
+			files: map[string]string{
+				"README.md":        "fixture\n",
+				"dir/nested.go":    "package dir\n",
+				"dir/sub/deep.txt": "deep\n",
+				"lines.txt":        fixtureLines(1, 10, 50, 90),
+				"loose.txt":        "loose\n",
+			},
+		},
+		{
+			name:   "HEAD",
+			rev:    "HEAD",
+			commit: "64659d63e5b6f942ef09346d80c864b29926730d",
+			files: map[string]string{
+				"README.md":        "fixture\n",
+				"dir/nested.go":    "package dir\n",
+				"dir/sub/deep.txt": "deep\n",
+				"lines.txt":        fixtureLines(1, 10, 50, 90),
+				"loose.txt":        "loose\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit, err := repo.ResolveRevision(tt.rev)
+			if err != nil {
+				t.Fatalf("ResolveRevision(%q): %v", tt.rev, err)
+			}
+			if commit != tt.commit {
+				t.Fatalf("ResolveRevision(%q) = %s, want %s", tt.rev, commit, tt.commit)
+			}
+
+			// The symlink "link" is not a regular file and must be skipped
+			files := make(map[string]string)
+			err = repo.WalkCommit(commit, func(entry GitTreeEntry) error {
+				if entry.Mode == gitModeTree {
+					return nil
+				}
+				content, err := repo.ReadBlob(entry.SHA)
+				if err != nil {
+					return fmt.Errorf("%s: %w", entry.Path, err)
+				}
+				files[entry.Path] = string(content)
+
+				// The size is read from the header without inflating
+				size, err := repo.ObjectSize(entry.SHA)
+				if err != nil {
+					return fmt.Errorf("%s: ObjectSize: %w", entry.Path, err)
+				}
+				if size != int64(len(content)) {
+					return fmt.Errorf("%s: ObjectSize = %d, want %d", entry.Path, size, len(content))
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WalkCommit: %v", err)
+			}
+			if !reflect.DeepEqual(files, tt.files) {
+				t.Errorf("files at %s:\n got %q\nwant %q", tt.rev, files, tt.files)
+			}
+		})
+	}
+}
+
+func TestGitRepoRejectsBadRevisions(t *testing.T) {
+	repo := openFixtureRepo(t)
+
+	tests := []struct {
+		name string
+		rev  string
+	}{
+		{name: "unknown branch", rev: "no-such-branch"},
+		{name: "unknown SHA", rev: "0123456789abcdef0123456789abcdef01234567"},
+		{name: "tree object", rev: "6738db2295e2593949ea417b0b14f1dc4ff114ea"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if commit, err := repo.ResolveRevision(tt.rev); err == nil {
+				t.Errorf("ResolveRevision(%q) = %s, want an error", tt.rev, commit)
+			}
+		})
+	}
+}
+
+func TestApplyGitDelta(t *testing.T) {
+	base := []byte("hello world")
+
+	tests := []struct {
+		name    string
+		delta   []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "copy and insert",
+			// src 11, dst 11, copy base[0:6], insert "there"
+			delta: append([]byte{11, 11, 0x90, 6, 5}, "there"...),
+			want:  "hello there",
+		},
+		{
+			name: "copy with offset",
+			// src 11, dst 5, copy base[6:11]
+			delta: []byte{11, 5, 0x91, 6, 5},
+			want:  "world",
+		},
+		{
+			name:    "base size mismatch",
+			delta:   []byte{12, 5, 0x91, 6, 5},
+			wantErr: true,
+		},
+		{
+			name:    "copy out of range",
+			delta:   []byte{11, 5, 0x91, 8, 5},
+			wantErr: true,
+		},
+		{
+			name:    "truncated insert",
+			delta:   append([]byte{11, 5, 5}, "the"...),
+			wantErr: true,
+		},
+		{
+			name: "huge target size",
+			// dst 2^62, copy base[6:11]
+			delta:   []byte{11, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x40, 0x91, 6, 5},
+			wantErr: true,
+		},
+		{
+			name:    "output longer than target size",
+			delta:   []byte{11, 3, 0x91, 6, 5},
+			wantErr: true,
+		},
+		{
+			name:    "output shorter than target size",
+			delta:   []byte{11, 9, 0x91, 6, 5},
+			wantErr: true,
+		},
+		{
+			name:    "opcode 0",
+			delta:   []byte{11, 0, 0},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyGitDelta(base, tt.delta)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("applyGitDelta = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyGitDelta: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("applyGitDelta = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkGitRevision(t *testing.T) {
+	root := fixtureRepoRoot(t)
+
+	tests := []struct {
+		name     string
+		exclude  []string
+		maxFiles int
+		maxSize  int64
+		files    []string
+		skipped  []string
+	}{
+		{
+			name:  "all files",
+			files: []string{"README.md", "dir/nested.go", "dir/sub/deep.txt", "lines.txt", "loose.txt"},
+		},
+		{
+			name:    "excluded directory is pruned",
+			exclude: []string{"dir/*"},
+			files:   []string{"README.md", "lines.txt", "loose.txt"},
+			skipped: []string{"dir/ excluded-by-pattern"},
+		},
+		{
+			name:    "excluded file",
+			exclude: []string{"*.txt"},
+			files:   []string{"README.md", "dir/nested.go"},
+			skipped: []string{"dir/sub/deep.txt excluded-by-pattern", "lines.txt excluded-by-pattern", "loose.txt excluded-by-pattern"},
+		},
+		{
+			name:    "oversized blob is skipped",
+			maxSize: 100,
+			files:   []string{"README.md", "dir/nested.go", "dir/sub/deep.txt", "loose.txt"},
+			skipped: []string{"lines.txt too-large"},
+		},
+		{
+			name:     "limit stops the walk",
+			maxFiles: 2,
+			files:    []string{"README.md", "dir/nested.go"},
+			skipped:  []string{"dir/sub/deep.txt and later files (not scanned) limit-reached"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var skipped []string
+			files, commit, err := WalkGitRevision(WalkDirectoryOptions{
+				RootPath:        root,
+				ExcludePatterns: tt.exclude,
+				MaxFiles:        tt.maxFiles,
+				MaxFileSize:     tt.maxSize,
+				OnSkip: func(path, reason string) {
+					skipped = append(skipped, path+" "+reason)
+				},
+			}, "main")
+			if err != nil {
+				t.Fatalf("WalkGitRevision: %v", err)
+			}
+			if commit != "64659d63e5b6f942ef09346d80c864b29926730d" {
+				t.Errorf("commit = %s", commit)
+			}
+			var paths []string
+			for _, file := range files {
+				paths = append(paths, file.RelativePath)
+			}
+			if !reflect.DeepEqual(paths, tt.files) {
+				t.Errorf("files = %q, want %q", paths, tt.files)
+			}
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("skipped = %q, want %q", skipped, tt.skipped)
+			}
+		})
+	}
+}
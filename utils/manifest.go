@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// ManifestFilename is the file in the output directory describing the last run
+const ManifestFilename = ".cb2utorial-manifest.json"
+
+// ContentHash returns the hex SHA-256 of file content
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadManifest reads the manifest from an output directory.
+// Returns nil if no previous run wrote one.
+func LoadManifest(outputDir string) (*types.TutorialManifest, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, ManifestFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest types.TutorialManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// ReadOutputFile reads a previously written file from the output directory
+func ReadOutputFile(outputDir string, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, name))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// OutputFile is a file to be published into an output directory
+type OutputFile struct {
+	Name    string // Filename relative to the output directory
+	Content []byte
+}
+
+// StagingDir returns the per-run directory files are written to before publishing
+func StagingDir(outputDir string, runID string) string {
+	return filepath.Join(outputDir, ".cb2utorial-staging-"+SanitizeFilename(runID))
+}
+
+// BackupDir returns the per-run directory that holds files replaced by a run
+func BackupDir(outputDir string, runID string) string {
+	return filepath.Join(outputDir, ".cb2utorial-backup-"+SanitizeFilename(runID))
+}
+
+// backupMarker returns the file that marks a run's backup as complete
+func backupMarker(outputDir string, runID string) string {
+	return filepath.Join(BackupDir(outputDir, runID), ".complete")
+}
+
+// PublishFiles writes files to a staging directory, then renames each one
+// into outputDir. Existing files are moved to the run's backup directory
+// first so a failed run can restore them, as are the stale files an earlier
+// run wrote that this one replaces under other names.
+//
+// outputDir may hold files of other tools, so it is deliberately not
+// swapped as a whole. Files are renamed one at a time, so a crash midway leaves new files next
+// to old ones (or gaps where an old file was backed up but its replacement
+// not yet published). The caller's retry recovers: the complete backup is
+// kept, the files are staged again and all of them renamed into place. A
+// run that fails for good calls RestoreOutput, which puts every backed-up
+// file back. Output is only mixed until one of the two has run.
+func PublishFiles(outputDir string, runID string, files []OutputFile, stale []string) ([]string, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Stage everything first so a write failure leaves outputDir untouched
+	stagingDir := StagingDir(outputDir, runID)
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return nil, fmt.Errorf("failed to clear staging directory: %w", err)
+	}
+	if err := os.MkdirAll(stagingDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	for _, file := range files {
+		stagedPath := filepath.Join(stagingDir, file.Name)
+		if err := os.MkdirAll(filepath.Dir(stagedPath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", file.Name, err)
+		}
+		if err := os.WriteFile(stagedPath, file.Content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write file %s: %w", file.Name, err)
+		}
+	}
+
+	// Back up existing files in a separate pass, marked complete once done.
+	// On retry the marker prevents files published by an earlier attempt
+	// from being mistaken for pre-existing ones.
+	backupDir := BackupDir(outputDir, runID)
+	markerPath := backupMarker(outputDir, runID)
+	if _, err := os.Stat(markerPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(backupDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		names := slices.Clone(stale)
+		for _, file := range files {
+			names = append(names, file.Name)
+		}
+		for _, name := range names {
+			targetPath := filepath.Join(outputDir, name)
+			backupPath := filepath.Join(backupDir, name)
+			if _, err := os.Stat(targetPath); err != nil {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(backupPath), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create backup directory: %w", err)
+			}
+			if err := os.Rename(targetPath, backupPath); err != nil {
+				return nil, fmt.Errorf("failed to back up %s: %w", name, err)
+			}
+		}
+		if err := os.WriteFile(markerPath, nil, 0644); err != nil {
+			return nil, fmt.Errorf("failed to mark backup complete: %w", err)
+		}
+	}
+
+	// Publish: rename each staged file into place
+	var published []string
+	for _, file := range files {
+		targetPath := filepath.Join(outputDir, file.Name)
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return published, fmt.Errorf("failed to create directory for %s: %w", file.Name, err)
+		}
+		if err := os.Rename(filepath.Join(stagingDir, file.Name), targetPath); err != nil {
+			return published, fmt.Errorf("failed to publish %s: %w", file.Name, err)
+		}
+		published = append(published, targetPath)
+	}
+
+	return published, nil
+}
+
+// RestoreOutput undoes PublishFiles for a run: files with a backup are
+// restored, the rest are removed. Idempotent, so it can be retried.
+func RestoreOutput(outputDir string, runID string, names []string) error {
+	backupDir := BackupDir(outputDir, runID)
+
+	// Files are only published once the backup is marked complete. New files
+	// are removed while the marker exists and backups restored after it is
+	// gone, so a retry never removes a file that was already restored.
+	markerPath := backupMarker(outputDir, runID)
+	if _, err := os.Stat(markerPath); err == nil {
+		for _, name := range names {
+			targetPath := filepath.Join(outputDir, name)
+			if _, err := os.Stat(filepath.Join(backupDir, name)); err == nil {
+				continue
+			}
+			// Nothing to remove if it was never published (its parent may even be a file)
+			if _, err := os.Lstat(targetPath); err != nil {
+				continue
+			}
+			if err := os.Remove(targetPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", name, err)
+			}
+		}
+		if err := os.Remove(markerPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove backup marker: %w", err)
+		}
+	}
+
+	for _, name := range names {
+		backupPath := filepath.Join(backupDir, name)
+		if _, err := os.Stat(backupPath); err != nil {
+			continue
+		}
+		if err := os.Rename(backupPath, filepath.Join(outputDir, name)); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+	}
+
+	if err := os.RemoveAll(StagingDir(outputDir, runID)); err != nil {
+		return fmt.Errorf("failed to remove staging directory: %w", err)
+	}
+	if err := os.RemoveAll(backupDir); err != nil {
+		return fmt.Errorf("failed to remove backup directory: %w", err)
+	}
+	return nil
+}
+
+// DiscardBackup removes a run's backup directory once the run has succeeded
+func DiscardBackup(outputDir string, runID string) error {
+	if err := os.RemoveAll(BackupDir(outputDir, runID)); err != nil {
+		return fmt.Errorf("failed to remove backup directory: %w", err)
+	}
+	return nil
+}
+
+// ChapterFilename returns the markdown filename for a chapter
+func ChapterFilename(chapterNumber int, title string) string {
+	return fmt.Sprintf("%02d_%s.md", chapterNumber, SanitizeFilename(title))
+}
+
+// VariantDir is the subdirectory of the output directory a language
+// variant is written to
+func VariantDir(language string) string {
+	return SanitizeFilename(language)
+}
+
+// IndexFilename is the table of contents written by the markdown-index format
+const IndexFilename = "index.md"
+
+// BuildIndex renders the table of contents linking every chapter
+func BuildIndex(projectName string, summary string, chapters []types.WriteChapterOutput) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Tutorial: %s\n\n", projectName))
+	if summary != "" {
+		sb.WriteString(summary)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("## Chapters\n\n")
+	for _, chapter := range chapters {
+		sb.WriteString(fmt.Sprintf("%d. [%s](%s)\n", chapter.ChapterNumber, chapter.Title, ChapterFilename(chapter.ChapterNumber, chapter.Title)))
+	}
+	return sb.String()
+}
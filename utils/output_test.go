@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readTree returns the regular files under dir by slash-separated path
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		files[filepath.ToSlash(rel)] = string(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// writeTree creates files under dir
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPublishFilesAndRestoreOutput(t *testing.T) {
+	const runID = "run-1"
+
+	tests := []struct {
+		name      string
+		existing  map[string]string
+		files     []OutputFile
+		stale     []string
+		attempts  int // PublishFiles calls, to check retries keep the first backup
+		published map[string]string
+		backedUp  map[string]string
+	}{
+		{
+			name:      "empty output directory",
+			files:     []OutputFile{{Name: "index.md", Content: []byte("new index")}, {Name: "01_intro.md", Content: []byte("new intro")}},
+			attempts:  1,
+			published: map[string]string{"index.md": "new index", "01_intro.md": "new intro"},
+			backedUp:  map[string]string{".complete": ""},
+		},
+		{
+			name:      "replaces and backs up existing files",
+			existing:  map[string]string{"index.md": "old index", "notes.txt": "unrelated"},
+			files:     []OutputFile{{Name: "index.md", Content: []byte("new index")}, {Name: "de/index.md", Content: []byte("neuer Index")}},
+			attempts:  1,
+			published: map[string]string{"index.md": "new index", "de/index.md": "neuer Index", "notes.txt": "unrelated"},
+			backedUp:  map[string]string{".complete": "", "index.md": "old index"},
+		},
+		{
+			name:      "moves stale files to the backup",
+			existing:  map[string]string{"01_intro.md": "old intro", "02_old_title.md": "old chapter"},
+			files:     []OutputFile{{Name: "01_intro.md", Content: []byte("new intro")}, {Name: "02_new_title.md", Content: []byte("new chapter")}},
+			stale:     []string{"02_old_title.md", "03_removed.md"},
+			attempts:  1,
+			published: map[string]string{"01_intro.md": "new intro", "02_new_title.md": "new chapter"},
+			backedUp:  map[string]string{".complete": "", "01_intro.md": "old intro", "02_old_title.md": "old chapter"},
+		},
+		{
+			name:      "retry keeps the original backup",
+			existing:  map[string]string{"index.md": "old index"},
+			files:     []OutputFile{{Name: "index.md", Content: []byte("new index")}},
+			attempts:  2,
+			published: map[string]string{"index.md": "new index"},
+			backedUp:  map[string]string{".complete": "", "index.md": "old index"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			writeTree(t, outputDir, tt.existing)
+
+			names := tt.stale
+			for _, file := range tt.files {
+				names = append(names, file.Name)
+			}
+			for i := 0; i < tt.attempts; i++ {
+				published, err := PublishFiles(outputDir, runID, tt.files, tt.stale)
+				if err != nil {
+					t.Fatalf("PublishFiles: %v", err)
+				}
+				if len(published) != len(tt.files) {
+					t.Errorf("published %d files, want %d", len(published), len(tt.files))
+				}
+			}
+
+			if _, err := os.Stat(StagingDir(outputDir, runID)); !os.IsNotExist(err) {
+				t.Errorf("staging directory left behind: %v", err)
+			}
+			backupDir := BackupDir(outputDir, runID)
+			if got := readTree(t, backupDir); !reflect.DeepEqual(got, tt.backedUp) {
+				t.Errorf("backup = %q, want %q", got, tt.backedUp)
+			}
+			if err := os.RemoveAll(backupDir); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTree(t, outputDir); !reflect.DeepEqual(got, tt.published) {
+				t.Errorf("output = %q, want %q", got, tt.published)
+			}
+
+			// Put the backup back and undo the run
+			writeTree(t, backupDir, tt.backedUp)
+			if err := RestoreOutput(outputDir, runID, names); err != nil {
+				t.Fatalf("RestoreOutput: %v", err)
+			}
+			want := tt.existing
+			if want == nil {
+				want = map[string]string{}
+			}
+			if got := readTree(t, outputDir); !reflect.DeepEqual(got, want) {
+				t.Errorf("restored output = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRestoreOutputAfterFailedPublish(t *testing.T) {
+	const runID = "run-1"
+	outputDir := t.TempDir()
+
+	// "de" is a file, so publishing "de/index.md" fails after index.md is in place
+	existing := map[string]string{"index.md": "old index", "de": "not a directory"}
+	writeTree(t, outputDir, existing)
+	files := []OutputFile{{Name: "index.md", Content: []byte("new index")}, {Name: "de/index.md", Content: []byte("neuer Index")}}
+
+	published, err := PublishFiles(outputDir, runID, files, nil)
+	if err == nil {
+		t.Fatal("PublishFiles succeeded, want an error")
+	}
+	if want := []string{filepath.Join(outputDir, "index.md")}; !reflect.DeepEqual(published, want) {
+		t.Errorf("published = %q, want %q", published, want)
+	}
+
+	if err := RestoreOutput(outputDir, runID, []string{"index.md", "de/index.md"}); err != nil {
+		t.Fatalf("RestoreOutput: %v", err)
+	}
+	if got := readTree(t, outputDir); !reflect.DeepEqual(got, existing) {
+		t.Errorf("restored output = %q, want %q", got, existing)
+	}
+
+	// Restoring again is a no-op
+	if err := RestoreOutput(outputDir, runID, []string{"index.md", "de/index.md"}); err != nil {
+		t.Fatalf("second RestoreOutput: %v", err)
+	}
+	if got := readTree(t, outputDir); !reflect.DeepEqual(got, existing) {
+		t.Errorf("output after second restore = %q, want %q", got, existing)
+	}
+}
+
+func TestRecoverFromCrashDuringPublish(t *testing.T) {
+	const runID = "run-1"
+	existing := map[string]string{"index.md": "old index", "01_intro.md": "old intro", "notes.txt": "unrelated"}
+	files := []OutputFile{{Name: "index.md", Content: []byte("new index")}, {Name: "01_intro.md", Content: []byte("new intro")}}
+	names := []string{"index.md", "01_intro.md"}
+
+	// crashedOutput is outputDir after a crash between the two renames: the
+	// backup is complete, index.md is new and 01_intro.md is missing
+	crashedOutput := func(t *testing.T) string {
+		outputDir := t.TempDir()
+		writeTree(t, outputDir, map[string]string{"index.md": "new index", "notes.txt": "unrelated"})
+		writeTree(t, BackupDir(outputDir, runID), map[string]string{".complete": "", "index.md": "old index", "01_intro.md": "old intro"})
+		writeTree(t, StagingDir(outputDir, runID), map[string]string{"01_intro.md": "new intro"})
+		return outputDir
+	}
+
+	t.Run("retry completes the publish", func(t *testing.T) {
+		outputDir := crashedOutput(t)
+		if _, err := PublishFiles(outputDir, runID, files, nil); err != nil {
+			t.Fatalf("PublishFiles: %v", err)
+		}
+		backupDir := BackupDir(outputDir, runID)
+		if got, want := readTree(t, backupDir), map[string]string{".complete": "", "index.md": "old index", "01_intro.md": "old intro"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("backup = %q, want %q", got, want)
+		}
+		if err := DiscardBackup(outputDir, runID); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := readTree(t, outputDir), map[string]string{"index.md": "new index", "01_intro.md": "new intro", "notes.txt": "unrelated"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("output = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("restore puts the old files back", func(t *testing.T) {
+		outputDir := crashedOutput(t)
+		if err := RestoreOutput(outputDir, runID, names); err != nil {
+			t.Fatalf("RestoreOutput: %v", err)
+		}
+		if got := readTree(t, outputDir); !reflect.DeepEqual(got, existing) {
+			t.Errorf("restored output = %q, want %q", got, existing)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// redactionRule replaces matches of pattern (or of its first capture group,
+// if it has one) with a placeholder naming the rule
+type redactionRule struct {
+	name    string
+	pattern *regexp.Regexp
+	accept  func(secret string) bool // Optional extra check on the match
+}
+
+// builtinRedactionRules detect common credential formats
+var builtinRedactionRules = []redactionRule{
+	{
+		name:    "private-key",
+		pattern: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----`),
+	},
+	{
+		name:    "aws-access-key-id",
+		pattern: regexp.MustCompile(`\b((?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA)[0-9A-Z]{16})\b`),
+	},
+	{
+		name:    "aws-secret-access-key",
+		pattern: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+	},
+	{
+		name:    "jwt",
+		pattern: regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,})`),
+	},
+	{
+		// KEY=value assignments in .env files, shell scripts and YAML; the
+		// value must be a literal, so code like os.Getenv(...) is left alone
+		name:    "api-key-assignment",
+		pattern: regexp.MustCompile(`(?m)^\s*(?:export\s+)?[A-Za-z0-9_]*(?:API_KEY|SECRET_KEY|ACCESS_TOKEN|AUTH_TOKEN|PASSWORD)["']?\s*[:=]\s*["']?([^\s"'#()]{8,})["']?\s*(?:#.*)?$`),
+	},
+	{
+		// Random-looking values assigned to a key, token, secret or password
+		// (or sent as a bearer token); the same string elsewhere is usually
+		// a hash, an ID or test data
+		name:    "high-entropy-string",
+		pattern: regexp.MustCompile(`(?i)(?:(?:key|token|secret|passw(?:or)?d|pwd|credential)s?[\w.-]*["']?\s*(?::=|=>|[:=])\s*|\bbearer\s+)["']?([A-Za-z0-9+/_=-]{32,})`),
+		accept:  isHighEntropy,
+	},
+}
+
+// Redactor replaces secrets in file content with placeholders
+type Redactor struct {
+	rules []redactionRule
+}
+
+// NewRedactor returns a redactor with the built-in detectors followed by
+// custom rules. Custom patterns redact their first capture group if present.
+func NewRedactor(custom []types.RedactionRule) (*Redactor, error) {
+	rules := append([]redactionRule{}, builtinRedactionRules...)
+	for _, rule := range custom {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("redaction rule %q has no name", rule.Pattern)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction rule %s: %w", rule.Name, err)
+		}
+		rules = append(rules, redactionRule{name: rule.Name, pattern: pattern})
+	}
+	return &Redactor{rules: rules}, nil
+}
+
+// Redact returns content with secrets replaced by "[REDACTED:<rule>]" and
+// the location of each redaction. Secret values are never recorded. When
+// matches overlap, the earlier rule wins.
+func (r *Redactor) Redact(path string, content string) (string, []types.Redaction) {
+	type span struct {
+		start, end int
+		rule       string
+	}
+
+	var spans []span
+	overlaps := func(start, end int) bool {
+		for _, s := range spans {
+			if start < s.end && s.start < end {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, rule := range r.rules {
+		for _, m := range rule.pattern.FindAllStringSubmatchIndex(content, -1) {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if end <= start || overlaps(start, end) {
+				continue
+			}
+			if rule.accept != nil && !rule.accept(content[start:end]) {
+				continue
+			}
+			spans = append(spans, span{start, end, rule.name})
+		}
+	}
+	if len(spans) == 0 {
+		return content, nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	var b strings.Builder
+	redactions := make([]types.Redaction, 0, len(spans))
+	last := 0
+	for _, s := range spans {
+		redactions = append(redactions, types.Redaction{
+			Path: path,
+			Line: strings.Count(content[:s.start], "\n") + 1,
+			Rule: s.rule,
+		})
+		b.WriteString(content[last:s.start])
+		b.WriteString("[REDACTED:" + s.rule + "]")
+		last = s.end
+	}
+	b.WriteString(content[last:])
+	return b.String(), redactions
+}
+
+// isHighEntropy flags random-looking tokens such as API keys while leaving
+// identifiers, paths and hex digests (entropy <= 4 bits/char) alone
+func isHighEntropy(token string) bool {
+	hasDigit, hasUpper, hasLower := false, false, false
+	for _, c := range token {
+		switch {
+		case c >= '0' && c <= '9':
+			hasDigit = true
+		case c >= 'A' && c <= 'Z':
+			hasUpper = true
+		case c >= 'a' && c <= 'z':
+			hasLower = true
+		}
+	}
+	if !hasDigit || !hasUpper || !hasLower {
+		return false
+	}
+	return shannonEntropy(token) > 4.5
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, c := range s {
+		counts[c]++
+		total++
+	}
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
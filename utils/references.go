@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// Reference patterns: calls and qualified exported names in code, dotted
+// identifiers in inline code, and Name() in prose
+var (
+	callPattern       = regexp.MustCompile(`([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)\s*\(`)
+	qualifiedPattern  = regexp.MustCompile(`\b([a-z]\w*\.[A-Z]\w*)\b`)
+	spanPattern       = regexp.MustCompile(`^([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)(?:\(\))?$`)
+	proseCallPattern  = regexp.MustCompile(`\b([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)\(\)`)
+	stringLiteral     = regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\"|'(?:[^'\\\\]|\\\\.)*'|`[^`]*`")
+	localDeclarations = regexp.MustCompile(`(?:func\s+(?:\([^)]*\)\s*)?|def\s+|function\s+|class\s+|type\s+|fn\s+|struct\s+|var\s+|let\s+(?:mut\s+)?|const\s+)([A-Za-z_]\w*)|([A-Za-z_]\w*(?:\s*,\s*[A-Za-z_]\w*)*)\s*:?=[^=]|\(\s*([A-Za-z_]\w*)\s+[*\[\]A-Za-z_.]+\s*[,)]`)
+)
+
+// uncheckedLanguages are code blocks that are not program source
+var uncheckedLanguages = toSet("", "bash", "sh", "shell", "zsh", "console", "text", "txt", "plaintext", "output",
+	"yaml", "yml", "json", "toml", "ini", "xml", "html", "css", "markdown", "md", "diff", "dockerfile", "makefile", "sql", "mermaid")
+
+// fileExtensions are suffixes that make "name.ext" a file name, not a member
+var fileExtensions = toSet("go", "py", "js", "jsx", "ts", "tsx", "java", "rb", "rs", "md", "mod", "sum", "yaml", "yml",
+	"json", "toml", "txt", "sh", "html", "css", "lock", "cfg", "ini", "env", "proto", "sql")
+
+// IsCitationLine reports whether line is a citation written by CiteCodeBlocks
+func IsCitationLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "*Source: ")
+}
+
+// IsNotFoundLine reports whether line is the note CiteCodeBlocks writes
+// below labelled code it did not find in the source
+func IsNotFoundLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "*⚠️ Not found in the source ")
+}
+
+// ExtractReferences returns the identifiers a chapter mentions: calls and
+// qualified names in code blocks, and code-like names in inline code or
+// followed by "()" in prose. Code blocks with a citation are copied from the
+// source and skipped. Also returns the names the chapter's own code defines.
+func ExtractReferences(content string) ([]types.SymbolReference, map[string]bool) {
+	local := make(map[string]bool)
+	seen := make(map[string]bool)
+	var references []types.SymbolReference
+	add := func(name string, where string) {
+		if !seen[name] {
+			seen[name] = true
+			references = append(references, types.SymbolReference{Name: name, Where: where})
+		}
+	}
+
+	blocks, prose := splitCodeBlocks(content)
+	for _, line := range prose {
+		for _, name := range proseReferences(line) {
+			add(name, ReferenceProse)
+		}
+	}
+	for _, block := range blocks {
+		if !block.Cited && !uncheckedLanguages[strings.ToLower(block.Language)] {
+			for _, line := range block.Body {
+				line = stripCode(line, block.Language)
+				for _, match := range localDeclarations.FindAllStringSubmatch(line, -1) {
+					for _, names := range match[1:] {
+						for _, name := range strings.Split(names, ",") {
+							if name = strings.TrimSpace(name); name != "" {
+								local[name] = true
+							}
+						}
+					}
+				}
+				for _, match := range callPattern.FindAllStringSubmatchIndex(line, -1) {
+					if !isDeclaration(line[:match[0]]) {
+						add(line[match[2]:match[3]], ReferenceCode)
+					}
+				}
+				for _, match := range qualifiedPattern.FindAllStringSubmatch(line, -1) {
+					add(match[1], ReferenceCode)
+				}
+			}
+		}
+	}
+	return references, local
+}
+
+// VerifyReferences returns the references the symbol table does not know,
+// with suggestions
+func VerifyReferences(table *SymbolTable, references []types.SymbolReference, local map[string]bool) []types.SymbolReference {
+	var unverified []types.SymbolReference
+	for _, ref := range references {
+		if table.Verify(ref.Name, local) {
+			continue
+		}
+		ref.Suggestions = table.Suggest(ref.Name)
+		unverified = append(unverified, ref)
+	}
+	return unverified
+}
+
+// proseReferences returns code-like names in inline code spans and calls
+// written as Name() outside them
+func proseReferences(line string) []string {
+	var names []string
+	for _, span := range inlineCode.FindAllString(line, -1) {
+		span = strings.Trim(span, "`")
+		match := spanPattern.FindStringSubmatch(span)
+		if match != nil && isCodeLike(span) && !isFileName(match[1]) {
+			names = append(names, match[1])
+		}
+	}
+	for _, match := range proseCallPattern.FindAllStringSubmatch(inlineCode.ReplaceAllString(line, " "), -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// isCodeLike reports whether a name is clearly an identifier rather than a
+// word: a call, a qualified name, snake_case or camelCase
+func isCodeLike(name string) bool {
+	if strings.ContainsAny(name, "._(") {
+		return true
+	}
+	runes := []rune(name)
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFileName reports whether a dotted name is a file such as "main.go"
+func isFileName(name string) bool {
+	dot := strings.LastIndex(name, ".")
+	return dot >= 0 && fileExtensions[strings.ToLower(name[dot+1:])]
+}
+
+// isDeclaration reports whether a call match is the name in a declaration
+func isDeclaration(before string) bool {
+	before = strings.TrimRight(before, " \t")
+	for _, keyword := range []string{"func", "def", "function", "fn", "class", "new"} {
+		if strings.HasSuffix(before, keyword) || strings.HasSuffix(before, ")") && strings.HasPrefix(strings.TrimSpace(before), "func") {
+			return true
+		}
+	}
+	return false
+}
+
+// stripCode blanks string literals and comments out of a line of code
+func stripCode(line string, language string) string {
+	line = stringLiteral.ReplaceAllString(line, `""`)
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	switch strings.ToLower(language) {
+	case "python", "py", "ruby", "rb":
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
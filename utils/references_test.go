@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+func TestExtractReferences(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		references []types.SymbolReference
+		local      map[string]bool
+	}{
+		{
+			name:    "prose",
+			content: "Call `store.Get` to read, then Set() writes. See `main.go`, `Config` and `newStore`.",
+			references: []types.SymbolReference{
+				{Name: "store.Get", Where: ReferenceProse},
+				{Name: "newStore", Where: ReferenceProse},
+				{Name: "Set", Where: ReferenceProse},
+			},
+			local: map[string]bool{},
+		},
+		{
+			name:    "code block",
+			content: "```go\ncache := store.New(10)\ncache.Put(\"Key()\", v) // see Foo()\nfunc helper(x int) {}\nfor i, item := range items { use(item) }\n```",
+			references: []types.SymbolReference{
+				{Name: "store.New", Where: ReferenceCode},
+				{Name: "cache.Put", Where: ReferenceCode},
+				{Name: "use", Where: ReferenceCode},
+			},
+			local: map[string]bool{"cache": true, "helper": true, "x": true, "i": true, "item": true},
+		},
+		{
+			name:    "python comments",
+			content: "```python\nloader = Loader()\nloader.load(path)  # call Save()\n```",
+			references: []types.SymbolReference{
+				{Name: "Loader", Where: ReferenceCode},
+				{Name: "loader.load", Where: ReferenceCode},
+			},
+			local: map[string]bool{"loader": true},
+		},
+		{
+			name:    "cited and shell blocks are skipped",
+			content: "```go\nstore.Open()\n```\n\n*Source: `store.go:1-2`*\n\n```bash\ngo run ./cmd --flag=Value()\n```",
+			local:   map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			references, local := ExtractReferences(tt.content)
+			if !reflect.DeepEqual(references, tt.references) {
+				t.Errorf("references = %+v, want %+v", references, tt.references)
+			}
+			if !reflect.DeepEqual(local, tt.local) {
+				t.Errorf("local = %v, want %v", local, tt.local)
+			}
+		})
+	}
+}
+
+func TestVerifyReferences(t *testing.T) {
+	table := BuildSymbolTable(symbolFixture)
+	content := "Use `store.New` to create a `Store`, then call `Store.Gett` and `helper()`.\n\n```go\nhelper := func() {}\n```"
+
+	references, local := ExtractReferences(content)
+	unverified := VerifyReferences(table, references, local)
+	want := []types.SymbolReference{{Name: "Store.Gett", Where: ReferenceProse, Suggestions: []string{"Store.Get"}}}
+	if !reflect.DeepEqual(unverified, want) {
+		t.Errorf("unverified = %+v, want %+v", unverified, want)
+	}
+}
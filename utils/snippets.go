@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"errors"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// snippetForm wraps a Go snippet so it parses as a whole file
+type snippetForm struct {
+	prefix string
+	suffix string
+}
+
+// snippetForms are the ways a snippet may be valid: a file, a list of
+// declarations or a list of statements
+var snippetForms = []snippetForm{
+	{prefix: "", suffix: ""},
+	{prefix: "package snippet\n", suffix: ""},
+	{prefix: "package snippet\nfunc _() {\n", suffix: "\n}"},
+}
+
+// elidedBody matches a body written as { ... }
+var elidedBody = regexp.MustCompile(`\{\s*(?:\.\.\.|…)\s*\}`)
+
+// IsGoLanguage reports whether a code block's language tag is Go
+func IsGoLanguage(language string) bool {
+	language = strings.ToLower(language)
+	return language == "go" || language == "golang"
+}
+
+// CheckGoSnippets parses the chapter's Go code blocks and returns those that
+// do not parse as a file, declarations or statements. Cited blocks are
+// copied from the source and may be partial, so they are skipped; elided
+// code ("...", "{ ... }") is allowed.
+func CheckGoSnippets(content string) []types.SnippetError {
+	blocks, _ := splitCodeBlocks(content)
+	var problems []types.SnippetError
+	index := 0
+	for _, block := range blocks {
+		if !IsGoLanguage(block.Language) {
+			continue
+		}
+		index++
+		if block.Cited {
+			continue
+		}
+		if line, msg, ok := parseGoSnippet(block.Body); !ok {
+			problems = append(problems, types.SnippetError{Block: index, Line: line, Error: msg})
+		}
+	}
+	return problems
+}
+
+// parseGoSnippet tries every snippet form. On failure it returns the error of
+// the form that parsed furthest, with its line within the snippet.
+func parseGoSnippet(body []string) (int, string, bool) {
+	lines := make([]string, len(body))
+	for i, line := range body {
+		if isElision(strings.TrimSpace(line)) && !strings.HasPrefix(strings.TrimSpace(line), "//") {
+			line = ""
+		}
+		lines[i] = elidedBody.ReplaceAllString(line, "{}")
+	}
+	source := strings.Join(lines, "\n")
+	if strings.TrimSpace(source) == "" {
+		return 0, "", true
+	}
+
+	bestLine, bestMsg := -1, ""
+	for _, form := range snippetForms {
+		if form.prefix == "" && !strings.HasPrefix(strings.TrimSpace(source), "package ") {
+			continue // Only complete files start with a package clause
+		}
+		_, err := parser.ParseFile(token.NewFileSet(), "", form.prefix+source+form.suffix, parser.SkipObjectResolution)
+		if err == nil {
+			return 0, "", true
+		}
+
+		line, msg := 1, err.Error()
+		var list scanner.ErrorList
+		if errors.As(err, &list) && len(list) > 0 {
+			line = list[0].Pos.Line - strings.Count(form.prefix, "\n")
+			msg = list[0].Msg
+		}
+		line = max(1, min(line, len(body)))
+		if line > bestLine {
+			bestLine, bestMsg = line, msg
+		}
+	}
+	return bestLine, bestMsg, false
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+func TestCheckGoSnippets(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []types.SnippetError
+	}{
+		{
+			name:    "complete file",
+			content: "```go\npackage main\n\nfunc main() {}\n```",
+		},
+		{
+			name:    "declarations",
+			content: "```go\nfunc add(a, b int) int { return a + b }\n\ntype pair struct{ a, b int }\n```",
+		},
+		{
+			name:    "statements",
+			content: "```go\nx := add(1, 2)\nfmt.Println(x)\n```",
+		},
+		{
+			name:    "elided bodies and lines",
+			content: "```go\nfunc (s *Store) Get(key string) string { ... }\n\nfunc Set() {\n\t// ...\n}\n...\n```",
+		},
+		{
+			name:    "syntax error",
+			content: "```go\nfunc broken( {\n}\n```",
+			want:    []types.SnippetError{{Block: 1, Line: 1, Error: "expected ')', found '{'"}},
+		},
+		{
+			name:    "error line within the snippet",
+			content: "```go\npackage main\nfunc main() {\n\tfmt.Println(\"hi\"\n}\n```",
+			want:    []types.SnippetError{{Block: 1, Line: 3, Error: "missing ',' before newline in argument list"}},
+		},
+		{
+			name:    "cited blocks are skipped",
+			content: "```go\nfunc broken( {\n```\n\n*Source: `store.go:1-2`*",
+		},
+		{
+			name:    "blocks are numbered among Go blocks only",
+			content: "```python\ndef f(:\n```\n\n```go\nx := 1\n```\n\n```golang\nfunc broken( {\n}\n```",
+			want:    []types.SnippetError{{Block: 2, Line: 1, Error: "expected ')', found '{'"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckGoSnippets(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckGoSnippets = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
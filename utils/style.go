@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// Style violation rules
+const (
+	StyleMissingTitle   = "missing-title"
+	StyleMissingSection = "missing-section"
+	StyleSectionOrder   = "section-order"
+	StyleTooLong        = "too-long"
+	StyleBannedPhrase   = "banned-phrase"
+)
+
+// inlineCode matches a `code span`
+var inlineCode = regexp.MustCompile("`[^`\n]*`")
+
+// CheckStyle reports how a chapter's markdown breaks a style guide. Code
+// blocks and inline code are ignored when counting words and matching
+// banned phrases.
+func CheckStyle(content string, guide types.StyleGuide) []types.StyleViolation {
+	prose, title, headings := splitMarkdown(content)
+	var violations []types.StyleViolation
+
+	if title == "" {
+		violations = append(violations, types.StyleViolation{
+			Rule:   StyleMissingTitle,
+			Detail: `the chapter must start with a "# " title`,
+		})
+	}
+
+	// Required sections, in order
+	last, lastName := -1, ""
+	for _, section := range guide.Sections {
+		position := -1
+		for i, heading := range headings {
+			if normalizeHeading(heading) == normalizeHeading(section) {
+				position = i
+				break
+			}
+		}
+		switch {
+		case position < 0:
+			violations = append(violations, types.StyleViolation{
+				Rule:   StyleMissingSection,
+				Detail: fmt.Sprintf("missing section %q", "## "+section),
+			})
+		case position < last:
+			violations = append(violations, types.StyleViolation{
+				Rule:   StyleSectionOrder,
+				Detail: fmt.Sprintf("section %q must come after %q", section, lastName),
+			})
+		default:
+			last, lastName = position, section
+		}
+	}
+
+	if guide.MaxWords > 0 {
+		if words := len(strings.Fields(prose)); words > guide.MaxWords {
+			violations = append(violations, types.StyleViolation{
+				Rule:   StyleTooLong,
+				Detail: fmt.Sprintf("%d words of prose exceed the limit of %d", words, guide.MaxWords),
+			})
+		}
+	}
+
+	for _, phrase := range guide.BannedPhrases {
+		count := len(phrasePattern(phrase).FindAllStringIndex(prose, -1))
+		if count == 0 {
+			continue
+		}
+		times := fmt.Sprintf("%d times", count)
+		if count == 1 {
+			times = "once"
+		}
+		violations = append(violations, types.StyleViolation{
+			Rule:   StyleBannedPhrase,
+			Detail: fmt.Sprintf("uses banned phrase %q %s", phrase, times),
+		})
+	}
+	return violations
+}
+
+// splitMarkdown returns the prose outside code, the "# " title if the
+// chapter starts with one, and the "## " headings in order
+func splitMarkdown(content string) (prose string, title string, headings []string) {
+	var builder strings.Builder
+	inFence, seenText := false, false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			seenText = true
+			continue
+		}
+		if inFence || trimmed == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "# ") && !seenText:
+			title = strings.TrimSpace(trimmed[2:])
+		case strings.HasPrefix(trimmed, "## "):
+			headings = append(headings, strings.TrimSpace(trimmed[3:]))
+		}
+		seenText = true
+
+		builder.WriteString(inlineCode.ReplaceAllString(trimmed, " "))
+		builder.WriteString("\n")
+	}
+	return builder.String(), title, headings
+}
+
+// normalizeHeading compares headings by their words, ignoring case,
+// numbering, emphasis and punctuation ("2. **Key Code:**" = "Key Code")
+func normalizeHeading(heading string) string {
+	words := strings.FieldsFunc(strings.ToLower(heading), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for len(words) > 0 && strings.IndexFunc(words[0], func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		words = words[1:] // Leading section number
+	}
+	return strings.Join(words, " ")
+}
+
+// phrasePattern matches a phrase case-insensitively, as whole words where
+// the phrase starts or ends with a word character
+func phrasePattern(phrase string) *regexp.Regexp {
+	phrase = strings.TrimSpace(phrase)
+	pattern := regexp.QuoteMeta(phrase)
+	if r := []rune(phrase); len(r) > 0 {
+		if isWordRune(r[0]) {
+			pattern = `\b` + pattern
+		}
+		if isWordRune(r[len(r)-1]) {
+			pattern += `\b`
+		}
+	}
+	return regexp.MustCompile(`(?i)` + pattern)
+}
+
+// isWordRune reports whether r is a regexp \b word character
+func isWordRune(r rune) bool {
+	return r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
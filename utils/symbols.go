@@ -0,0 +1,325 @@
+package utils
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// Where a symbol reference appears in a chapter
+const (
+	ReferenceProse = "prose"
+	ReferenceCode  = "code"
+)
+
+// SymbolTable indexes the names a repository declares and uses. Go files
+// are parsed; other languages are indexed with declaration patterns.
+type SymbolTable struct {
+	Declared map[string]bool            // Functions, types, methods, fields, constants, variables
+	Members  map[string]map[string]bool // Type, class or package → its members
+	Open     map[string]bool            // Types with embedded fields: members are incomplete
+	External map[string]bool            // Imported package names
+	Tokens   map[string]bool            // Every identifier in the source
+}
+
+// identifierPattern matches identifier tokens
+var identifierPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// declarationPatterns index declarations of languages without a parser here
+var declarationPatterns = map[string]*regexp.Regexp{
+	".py":   regexp.MustCompile(`(?m)^\s*(?:async\s+)?(?:def|class)\s+([A-Za-z_]\w*)|^([A-Za-z_]\w*)\s*(?::[^=\n]*)?=`),
+	".js":   regexp.MustCompile(`(?m)(?:function\*?|class|const|let|var)\s+([A-Za-z_$][\w$]*)|^\s+(?:async\s+)?([A-Za-z_$][\w$]*)\s*\([^)]*\)\s*\{`),
+	".ts":   regexp.MustCompile(`(?m)(?:function\*?|class|const|let|var|interface|type|enum)\s+([A-Za-z_$][\w$]*)|^\s+(?:public\s+|private\s+|protected\s+|static\s+|async\s+)*([A-Za-z_$][\w$]*)\s*\([^)]*\)\s*[:{]`),
+	".java": regexp.MustCompile(`(?m)(?:class|interface|enum|record)\s+([A-Za-z_]\w*)|^\s+(?:[\w<>\[\],]+\s+)+([A-Za-z_]\w*)\s*\([^)]*\)\s*(?:throws [\w., ]+)?\{`),
+	".rb":   regexp.MustCompile(`(?m)^\s*(?:def\s+(?:self\.)?([A-Za-z_]\w*[?!]?)|(?:class|module)\s+([A-Z]\w*))`),
+	".rs":   regexp.MustCompile(`(?m)(?:fn|struct|enum|trait|type|mod|const|static|union)\s+([A-Za-z_]\w*)`),
+}
+
+// declarationAliases maps extensions to the pattern of their language
+var declarationAliases = map[string]string{".jsx": ".js", ".mjs": ".js", ".cjs": ".js", ".tsx": ".ts"}
+
+// pythonClassMember matches a class or an indented method
+var pythonClassMember = regexp.MustCompile(`^(\s*)(?:class\s+([A-Za-z_]\w*)|(?:async\s+)?def\s+([A-Za-z_]\w*))`)
+
+// BuildSymbolTable indexes the files, whose contents must be loaded
+func BuildSymbolTable(files []types.FileContent) *SymbolTable {
+	table := &SymbolTable{
+		Declared: make(map[string]bool),
+		Members:  make(map[string]map[string]bool),
+		Open:     make(map[string]bool),
+		External: make(map[string]bool),
+		Tokens:   make(map[string]bool),
+	}
+	for _, file := range files {
+		for _, name := range identifierPattern.FindAllString(file.Content, -1) {
+			table.Tokens[name] = true
+		}
+
+		ext := strings.ToLower(path.Ext(file.Path))
+		if alias, ok := declarationAliases[ext]; ok {
+			ext = alias
+		}
+		switch {
+		case ext == ".go":
+			table.indexGo(file)
+		case declarationPatterns[ext] != nil:
+			for _, match := range declarationPatterns[ext].FindAllStringSubmatch(file.Content, -1) {
+				for _, name := range match[1:] {
+					if name != "" {
+						table.Declared[name] = true
+					}
+				}
+			}
+			if ext == ".py" {
+				table.indexPythonClasses(file.Content)
+			}
+		}
+	}
+	return table
+}
+
+// indexGo records a Go file's declarations, methods by receiver type,
+// struct fields and imports. Files that fail to parse contribute whatever
+// was parsed before the error.
+func (t *SymbolTable) indexGo(file types.FileContent) {
+	parsed, _ := parser.ParseFile(token.NewFileSet(), file.Path, file.Content, parser.SkipObjectResolution)
+	if parsed == nil || parsed.Name == nil {
+		return
+	}
+	pkg := parsed.Name.Name
+
+	for _, spec := range parsed.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		t.External[name] = true
+	}
+
+	for _, decl := range parsed.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			t.Declared[decl.Name.Name] = true
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				t.addMember(receiverType(decl.Recv.List[0].Type), decl.Name.Name)
+			} else {
+				t.addMember(pkg, decl.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					t.Declared[spec.Name.Name] = true
+					t.addMember(pkg, spec.Name.Name)
+					t.indexGoType(spec.Name.Name, spec.Type)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						t.Declared[name.Name] = true
+						t.addMember(pkg, name.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// indexGoType records the fields of a struct or the methods of an interface
+func (t *SymbolTable) indexGoType(name string, expr ast.Expr) {
+	var fields *ast.FieldList
+	switch typ := expr.(type) {
+	case *ast.StructType:
+		fields = typ.Fields
+	case *ast.InterfaceType:
+		fields = typ.Methods
+	default:
+		return
+	}
+	if _, ok := t.Members[name]; !ok {
+		t.Members[name] = make(map[string]bool)
+	}
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			t.Open[name] = true // Embedded: promoted members are unknown
+			continue
+		}
+		for _, fieldName := range field.Names {
+			t.Declared[fieldName.Name] = true
+			t.addMember(name, fieldName.Name)
+		}
+	}
+}
+
+// indexPythonClasses records methods by class using indentation
+func (t *SymbolTable) indexPythonClasses(content string) {
+	class, classIndent := "", -1
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if class != "" && indent <= classIndent {
+			class = ""
+		}
+		match := pythonClassMember.FindStringSubmatch(line)
+		switch {
+		case match == nil:
+		case match[2] != "":
+			class, classIndent = match[2], indent
+			if _, ok := t.Members[class]; !ok {
+				t.Members[class] = make(map[string]bool)
+			}
+		case class != "":
+			t.addMember(class, match[3])
+		}
+	}
+}
+
+// addMember records member under owner
+func (t *SymbolTable) addMember(owner string, member string) {
+	if owner == "" {
+		return
+	}
+	if t.Members[owner] == nil {
+		t.Members[owner] = make(map[string]bool)
+	}
+	t.Members[owner][member] = true
+}
+
+// receiverType returns the type name of a method receiver (*T, T[K])
+func receiverType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(e.X)
+	case *ast.IndexExpr:
+		return receiverType(e.X)
+	case *ast.IndexListExpr:
+		return receiverType(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// Verify reports whether a reference such as "Name", "pkg.Func" or
+// "Type.Method" exists. local holds names the chapter itself defines.
+// References into packages imported from outside the repository cannot be
+// checked and are accepted.
+func (t *SymbolTable) Verify(ref string, local map[string]bool) bool {
+	parts := strings.Split(ref, ".")
+	name := parts[len(parts)-1]
+	if len(parts) == 1 {
+		return t.Tokens[name] || local[name] || commonIdentifiers[name]
+	}
+
+	qualifier := parts[len(parts)-2]
+	switch {
+	case t.Members[qualifier] != nil && !t.Open[qualifier]:
+		return t.Members[qualifier][name] // Includes the repository's own packages
+	case t.External[qualifier] || commonIdentifiers[qualifier]:
+		return true
+	case t.Tokens[qualifier] || local[qualifier]:
+		return t.Tokens[name] || local[name]
+	default:
+		return true // Unknown package: nothing to check against
+	}
+}
+
+// Suggest returns declared names close to a reference, best first
+func (t *SymbolTable) Suggest(ref string) []string {
+	parts := strings.Split(ref, ".")
+	name := parts[len(parts)-1]
+	candidates := t.Declared
+	if len(parts) > 1 && t.Members[parts[len(parts)-2]] != nil {
+		candidates = t.Members[parts[len(parts)-2]]
+	}
+
+	type scored struct {
+		name     string
+		distance int
+	}
+	var matches []scored
+	lower := strings.ToLower(name)
+	for candidate := range candidates {
+		distance := levenshtein(lower, strings.ToLower(candidate))
+		if distance <= len(name)/3+1 || strings.Contains(strings.ToLower(candidate), lower) {
+			matches = append(matches, scored{candidate, distance})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var suggestions []string
+	for i := 0; i < len(matches) && i < 3; i++ {
+		suggestion := matches[i].name
+		if len(parts) > 1 {
+			suggestion = strings.Join(parts[:len(parts)-1], ".") + "." + suggestion
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// levenshtein is the edit distance between two strings
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
+
+// commonIdentifiers are keywords, builtins and standard library names of
+// the supported languages, which a repository need not mention itself
+var commonIdentifiers = toSet(
+	// Go
+	"append", "cap", "close", "copy", "delete", "len", "make", "new", "panic", "print", "println", "recover",
+	"min", "max", "clear", "error", "string", "int", "bool", "byte", "rune", "any", "nil", "true", "false",
+	"fmt", "os", "io", "errors", "strings", "strconv", "context", "time", "sync", "log", "http", "json", "sort", "bytes",
+	// Python
+	"self", "cls", "super", "range", "enumerate", "zip", "isinstance", "str", "list", "dict", "set", "tuple",
+	"open", "sorted", "map", "filter", "None", "True", "False", "__init__",
+	// JavaScript/TypeScript
+	"this", "console", "require", "Promise", "Object", "Array", "JSON", "Math", "Map", "Set", "undefined", "null",
+	"async", "await", "setTimeout", "module", "exports", "document", "window",
+	// Java, Ruby and Rust
+	"System", "String", "Integer", "List", "puts", "require_relative", "Vec", "Some", "None", "Ok", "Err", "Box", "println",
+	// Shared keywords that look like calls
+	"if", "for", "while", "switch", "return", "func", "function", "def", "catch", "main",
+)
+
+// toSet builds a lookup set
+func toSet(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
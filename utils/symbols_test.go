@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// symbolFixture is a small Go and Python repository
+var symbolFixture = []types.FileContent{
+	{Path: "store/store.go", Content: `package store
+
+import (
+	"sync"
+
+	lru "github.com/example/golang-lru"
+)
+
+// Store caches values
+type Store struct {
+	mu    sync.Mutex
+	cache *lru.Cache
+}
+
+// Reader reads values
+type Reader interface {
+	Get(key string) string
+}
+
+// Wrapped embeds a Store
+type Wrapped struct {
+	Store
+}
+
+// New returns a store
+func New(size int) *Store {
+	return &Store{cache: lru.New(size)}
+}
+
+// Get returns a value
+func (s *Store) Get(key string) string {
+	return ""
+}
+`},
+	{Path: "app/loader.py", Content: "class Loader:\n    def load(self, path):\n        pass\n\ndef run():\n    pass\n"},
+}
+
+func TestSymbolTableVerify(t *testing.T) {
+	table := BuildSymbolTable(symbolFixture)
+	local := map[string]bool{"helper": true}
+
+	tests := []struct {
+		ref  string
+		want bool
+	}{
+		{ref: "Store.Get", want: true},
+		{ref: "Store.Delete", want: false},
+		{ref: "store.New", want: true},
+		{ref: "store.Open", want: false},
+		{ref: "Reader.Get", want: true},
+		{ref: "Loader.load", want: true},
+		{ref: "Loader.save", want: false},
+		// Embedded fields make a type's members unknown; fall back to tokens
+		{ref: "Wrapped.Get", want: true},
+		{ref: "Wrapped.Missing", want: false},
+		// Variables are checked against every identifier in the source
+		{ref: "s.Get", want: true},
+		{ref: "s.Gte", want: false},
+		// Imported, standard and unknown packages cannot be checked
+		{ref: "lru.NewCache", want: true},
+		{ref: "fmt.Sprintf", want: true},
+		{ref: "requests.get", want: true},
+		{ref: "run", want: true},
+		{ref: "Run", want: false},
+		{ref: "helper", want: true},
+		{ref: "len", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := table.Verify(tt.ref, local); got != tt.want {
+				t.Errorf("Verify(%q) = %v, want %v", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSymbolTableSuggest(t *testing.T) {
+	table := BuildSymbolTable(symbolFixture)
+
+	tests := []struct {
+		ref  string
+		want []string
+	}{
+		{ref: "Store.Gett", want: []string{"Store.Get"}},
+		{ref: "Stor", want: []string{"Store"}},
+		{ref: "Nw", want: []string{"New"}},
+		{ref: "Store.Delete", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := table.Suggest(tt.ref); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Suggest(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
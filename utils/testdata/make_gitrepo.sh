@@ -0,0 +1,62 @@
+#!/bin/sh
+# Regenerates gitrepo.git, the fixture for gitobjects_test.go. Objects are
+# spread over a packfile with ref deltas (v1), a packfile with offset deltas
+# (v2, an annotated tag) and loose objects (main).
+set -e
+cd "$(dirname "$0")"
+rm -rf gitrepo.git work
+export GIT_AUTHOR_NAME=fixture GIT_AUTHOR_EMAIL=fixture@example.com
+export GIT_COMMITTER_NAME=fixture GIT_COMMITTER_EMAIL=fixture@example.com
+export GIT_AUTHOR_DATE="2024-01-01T00:00:00Z" GIT_COMMITTER_DATE="2024-01-01T00:00:00Z"
+
+git init -q --bare -b main gitrepo.git
+rm -rf gitrepo.git/hooks gitrepo.git/info gitrepo.git/description
+git init -q -b main work
+cd work
+
+# lines.txt has 100 lines; each commit changes one so packs store deltas
+lines() {
+	i=1
+	while [ $i -le 100 ]; do
+		case " $* " in
+		*" $i "*) echo "line $i changed" ;;
+		*) echo "line $i" ;;
+		esac
+		i=$((i + 1))
+	done
+}
+commit() {
+	git add -A
+	git commit -q -m "$1"
+	git push -q ../gitrepo.git main
+}
+
+lines >lines.txt
+echo "fixture" >README.md
+mkdir dir
+echo "package dir" >dir/nested.go
+ln -s lines.txt link
+commit A
+lines 10 >lines.txt
+commit B
+git tag v1
+git push -q ../gitrepo.git v1
+git -C ../gitrepo.git -c repack.useDeltaBaseOffset=false -c repack.writeBitmaps=false repack -q -a -d -f
+
+lines 10 50 >lines.txt
+mkdir dir/sub
+echo "deep" >dir/sub/deep.txt
+commit C
+lines 10 50 90 >lines.txt
+commit D
+git tag -a -m "second release" v2
+git push -q ../gitrepo.git v2
+git -C ../gitrepo.git -c repack.writeBitmaps=false repack -q -d
+git -C ../gitrepo.git pack-refs --all
+
+lines 1 10 50 90 >lines.txt
+echo "loose" >loose.txt
+commit E
+
+cd ..
+rm -rf work gitrepo.git/info gitrepo.git/objects/info
@@ -0,0 +1,16 @@
+package utils
+
+import "strings"
+
+// ExtractYAML returns the YAML in an LLM response: the first ```yaml
+// fenced block, else the first fenced block, else the whole response
+func ExtractYAML(response string) string {
+	if strings.Contains(response, "```yaml") {
+		parts := strings.Split(response, "```yaml")
+		return strings.Split(parts[1], "```")[0]
+	}
+	if strings.Contains(response, "```") {
+		return strings.Split(response, "```")[1]
+	}
+	return response
+}
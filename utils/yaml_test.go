@@ -0,0 +1,25 @@
+package utils
+
+import "testing"
+
+func TestExtractYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{name: "bare YAML", response: "name: Router\n", want: "name: Router\n"},
+		{name: "yaml fence", response: "Here you go:\n```yaml\nname: Router\n```\nDone.", want: "\nname: Router\n"},
+		{name: "plain fence", response: "```\nname: Router\n```", want: "\nname: Router\n"},
+		{name: "unterminated fence", response: "```yaml\nname: Router\n", want: "\nname: Router\n"},
+		{name: "yaml fence after another fence", response: "```go\nx := 1\n```\n```yaml\nname: Router\n```", want: "\nname: Router\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractYAML(tt.response); got != tt.want {
+				t.Errorf("ExtractYAML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
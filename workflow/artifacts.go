@@ -0,0 +1,76 @@
+package workflow
+
+import (
+	"github.com/pithomlabs/cb2utorial/types"
+	framework "github.com/pithomlabs/rea"
+	restate "github.com/restatedev/sdk-go"
+)
+
+const (
+	// tutorialStateKey holds the run's TutorialState (files by hash)
+	tutorialStateKey = "tutorial_state"
+	// completedStagesKey holds the list of completed pipeline stages
+	completedStagesKey = "completed_stages"
+	// revisionKey holds the revision of the last saved run artifact
+	revisionKey = "revision"
+)
+
+// GetArtifacts returns the stage outputs of an in-flight or finished run (shared handler)
+func (w TutorialWorkflow) GetArtifacts(ctx restate.WorkflowSharedContext, _ restate.Void) (types.TutorialArtifacts, error) {
+	state, err := framework.NewReadOnlyWorkflowState[types.TutorialState](ctx, tutorialStateKey).Get()
+	if err != nil {
+		return types.TutorialArtifacts{}, err
+	}
+	stages, err := framework.NewReadOnlyWorkflowState[[]string](ctx, completedStagesKey).Get()
+	if err != nil {
+		return types.TutorialArtifacts{}, err
+	}
+	revision, err := framework.NewReadOnlyWorkflowState[int](ctx, revisionKey).Get()
+	if err != nil {
+		return types.TutorialArtifacts{}, err
+	}
+
+	artifacts := types.TutorialArtifacts{
+		CompletedStages: stages,
+		Revision:        revision,
+		Commit:          state.Commit,
+		Abstractions:    state.Abstractions,
+		Relationships:   state.Relationships,
+		ChapterOrder:    state.ChapterOrder,
+		ChaptersWritten: state.ChaptersWritten,
+	}
+	for _, file := range state.Files {
+		artifacts.FilePaths = append(artifacts.FilePaths, file.Path)
+		if file.Hash != "" {
+			if artifacts.FileHashes == nil {
+				artifacts.FileHashes = make(map[string]string, len(state.Files))
+			}
+			artifacts.FileHashes[file.Path] = file.Hash
+		}
+	}
+	for _, chapter := range state.Chapters {
+		artifacts.Chapters = append(artifacts.Chapters, types.ChapterInfo{
+			ChapterNumber: chapter.ChapterNumber,
+			Title:         chapter.Title,
+		})
+	}
+	return artifacts, nil
+}
+
+// saveWorkflowState stores the run's progress in workflow K/V state. Files
+// are kept by hash only; their contents are in the content store.
+func saveWorkflowState(ctx restate.WorkflowContext, state types.TutorialState, stages []string, revision int) error {
+	files := make([]types.FileContent, len(state.Files))
+	for i, file := range state.Files {
+		files[i] = types.FileContent{Index: file.Index, Path: file.Path, Hash: file.Hash}
+	}
+	state.Files = files
+
+	if err := framework.NewMutableWorkflowState[types.TutorialState](ctx, tutorialStateKey).Set(state); err != nil {
+		return err
+	}
+	if err := framework.NewMutableWorkflowState[[]string](ctx, completedStagesKey).Set(stages); err != nil {
+		return err
+	}
+	return framework.NewMutableWorkflowState[int](ctx, revisionKey).Set(revision)
+}
@@ -0,0 +1,147 @@
+package workflow
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// runTracker checkpoints stage outputs into the run artifact and decides
+// which stages a resumed run can take from its predecessor
+type runTracker struct {
+	ctx      restate.WorkflowContext
+	artifact types.RunArtifact
+	reuse    map[string]bool
+}
+
+// newRunTracker starts tracking a run. For resumed runs it loads the previous
+// artifact, seeds the state from it and returns the original run's input.
+func newRunTracker(ctx restate.WorkflowContext, input types.TutorialWorkflowInput) (*runTracker, types.TutorialWorkflowInput, error) {
+	tracker := &runTracker{
+		ctx:   ctx,
+		reuse: make(map[string]bool),
+		artifact: types.RunArtifact{
+			RunID: restate.Key(ctx),
+			Input: input,
+		},
+	}
+	if input.ResumeRunID == "" {
+		return tracker, input, nil
+	}
+
+	previous, err := restate.Run(ctx, func(rc restate.RunContext) (types.RunArtifact, error) {
+		artifact, err := utils.LoadRunArtifact(input.ResumeRunID)
+		if err != nil {
+			return types.RunArtifact{}, restate.TerminalError(err, 404)
+		}
+		return artifact, nil
+	}, restate.WithName("load-run-artifact"))
+	if err != nil {
+		return nil, input, err
+	}
+
+	// Resume point: explicit stage, or the first stage that did not complete
+	fromIdx := len(types.Stages)
+	for i, stage := range types.Stages {
+		if (input.ResumeFrom == "" && !previous.IsCompleted(stage)) || stage == input.ResumeFrom {
+			fromIdx = i
+			break
+		}
+	}
+	if input.ResumeFrom != "" && fromIdx == len(types.Stages) {
+		return nil, input, restate.TerminalError(fmt.Errorf("unknown stage %q (expected one of %v)", input.ResumeFrom, types.Stages), 400)
+	}
+
+	for _, stage := range types.Stages[:fromIdx] {
+		if !previous.IsCompleted(stage) {
+			return nil, input, restate.TerminalError(fmt.Errorf("cannot resume from %s: stage %s did not complete in run %s",
+				types.Stages[fromIdx], stage, input.ResumeRunID), 400)
+		}
+		tracker.reuse[stage] = true
+		tracker.artifact.CompletedStages = append(tracker.artifact.CompletedStages, stage)
+	}
+
+	tracker.artifact.State = resumedState(previous, stageName(fromIdx))
+
+	// Run with the original configuration, allowing a different output dir
+	resumed := previous.Input
+	resumed.ResumeRunID = input.ResumeRunID
+	resumed.ResumeFrom = input.ResumeFrom
+	if input.OutputDir != "" {
+		resumed.OutputDir = input.OutputDir
+	}
+	tracker.artifact.Input = resumed
+
+	// Expose the seeded state right away through GetArtifacts
+	if err := saveWorkflowState(ctx, tracker.artifact.State, tracker.artifact.CompletedStages, tracker.artifact.Revision); err != nil {
+		return nil, input, fmt.Errorf("failed to save workflow state: %w", err)
+	}
+
+	fmt.Printf("♻️  Resuming run %s from stage %q (reusing %v)\n", input.ResumeRunID, stageName(fromIdx), tracker.artifact.CompletedStages)
+	return tracker, resumed, nil
+}
+
+// resumedState returns the state a run resumed at stage from starts with.
+// Chapters (and their translations) are kept when resuming after the chapter
+// stage, and those written before a failure when resuming at it. An explicit
+// re-run of finished chapters regenerates them, as does resuming earlier in
+// the pipeline.
+func resumedState(previous types.RunArtifact, from string) types.TutorialState {
+	state := previous.State
+	fromIdx, chaptersIdx := slices.Index(types.Stages, from), slices.Index(types.Stages, types.StageChapters)
+	if fromIdx == -1 {
+		return state // Every stage is reused
+	}
+	if fromIdx < chaptersIdx {
+		state.Localized = nil
+	}
+	if fromIdx < chaptersIdx || (fromIdx == chaptersIdx && previous.IsCompleted(types.StageChapters)) {
+		state.Chapters = nil
+		state.Variants = nil
+	}
+	state.ChaptersWritten = nil
+	return state
+}
+
+// reused reports whether a stage's output was taken from the resumed run
+func (t *runTracker) reused(stage string) bool {
+	return t.reuse[stage]
+}
+
+// state returns the run's accumulated stage outputs
+func (t *runTracker) state() *types.TutorialState {
+	return &t.artifact.State
+}
+
+// checkpoint marks a stage complete (if non-empty), then saves progress to
+// workflow state and the run artifact
+func (t *runTracker) checkpoint(stage string) error {
+	if stage != "" && !t.artifact.IsCompleted(stage) {
+		t.artifact.CompletedStages = append(t.artifact.CompletedStages, stage)
+	}
+	t.artifact.Revision++
+
+	if err := saveWorkflowState(t.ctx, t.artifact.State, t.artifact.CompletedStages, t.artifact.Revision); err != nil {
+		return fmt.Errorf("failed to save workflow state: %w", err)
+	}
+
+	artifact := t.artifact
+	name := fmt.Sprintf("checkpoint-%d", artifact.Revision)
+	if err := restate.RunVoid(t.ctx, func(rc restate.RunContext) error {
+		return utils.SaveRunArtifact(artifact)
+	}, restate.WithName(name)); err != nil {
+		return fmt.Errorf("failed to save run artifact: %w", err)
+	}
+	return nil
+}
+
+// stageName returns the stage at idx, or "done" past the last stage
+func stageName(idx int) string {
+	if idx < len(types.Stages) {
+		return types.Stages[idx]
+	}
+	return "done"
+}
@@ -0,0 +1,90 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// previousRun is a ten-chapter run that wrote written chapters and completed
+// the given stages
+func previousRun(written int, completed ...string) types.RunArtifact {
+	artifact := types.RunArtifact{RunID: "run-1", CompletedStages: completed}
+	for i := range 10 {
+		artifact.State.ChapterOrder = append(artifact.State.ChapterOrder, i)
+	}
+	for i := range written {
+		artifact.State.Chapters = append(artifact.State.Chapters, types.WriteChapterOutput{ChapterNumber: i + 1})
+	}
+	artifact.State.Localized = &types.LocalizedAnalysis{}
+	artifact.State.Variants = []types.LanguageVariant{{}}
+	artifact.State.ChaptersWritten = []string{"index.md"}
+	return artifact
+}
+
+func TestResumedState(t *testing.T) {
+	analysis := []string{types.StageFiles, types.StageAbstractions, types.StageRelationships, types.StageOrder}
+	tests := []struct {
+		name      string
+		previous  types.RunArtifact
+		from      string
+		chapters  int
+		localized bool
+		written   bool
+	}{
+		{
+			name:      "chapter 7 of 10 failed",
+			previous:  previousRun(6, analysis...),
+			from:      types.StageChapters,
+			chapters:  6,
+			localized: true,
+		},
+		{
+			name:      "explicit re-run of completed chapters",
+			previous:  previousRun(10, append(analysis, types.StageChapters)...),
+			from:      types.StageChapters,
+			localized: true,
+		},
+		{
+			name:     "re-run of the analysis",
+			previous: previousRun(10, append(analysis, types.StageChapters)...),
+			from:     types.StageOrder,
+		},
+		{
+			name:      "writing files failed",
+			previous:  previousRun(10, append(analysis, types.StageChapters)...),
+			from:      types.StageWrite,
+			chapters:  10,
+			localized: true,
+		},
+		{
+			name:      "everything completed",
+			previous:  previousRun(10, append(analysis, types.StageChapters, types.StageWrite)...),
+			from:      stageName(len(types.Stages)),
+			chapters:  10,
+			localized: true,
+			written:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := resumedState(tt.previous, tt.from)
+			if len(state.Chapters) != tt.chapters {
+				t.Errorf("kept %d chapters, want %d", len(state.Chapters), tt.chapters)
+			}
+			if len(state.Variants) > 0 != (tt.chapters > 0) {
+				t.Errorf("kept variants = %v, want %v", len(state.Variants) > 0, tt.chapters > 0)
+			}
+			if localized := state.Localized != nil; localized != tt.localized {
+				t.Errorf("kept translated analysis = %v, want %v", localized, tt.localized)
+			}
+			if written := len(state.ChaptersWritten) > 0; written != tt.written {
+				t.Errorf("kept written files = %v, want %v", written, tt.written)
+			}
+			if len(state.ChapterOrder) != 10 {
+				t.Errorf("chapter order has %d entries, want 10", len(state.ChapterOrder))
+			}
+		})
+	}
+}
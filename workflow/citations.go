@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// resolveSourceURL fills {commit} of the citation link template. Files read
+// from a working tree are linked at its HEAD commit; without a commit,
+// citations are written without links.
+func resolveSourceURL(ctx restate.WorkflowContext, input types.TutorialWorkflowInput, commit string) (string, error) {
+	if input.SourceURL == "" || !strings.Contains(input.SourceURL, "{commit}") {
+		return input.SourceURL, nil
+	}
+	if commit == "" {
+		var err error
+		commit, err = restate.Run(ctx, func(rc restate.RunContext) (string, error) {
+			if info, err := os.Stat(input.LocalRepoPath); err != nil || !info.IsDir() {
+				return "", nil // Archives and module specs have no commit
+			}
+			head, err := utils.ResolveHead(input.LocalRepoPath)
+			if err != nil {
+				fmt.Printf("⚠️  Failed to resolve HEAD for citation links: %v\n", err)
+				return "", nil
+			}
+			return head, nil
+		}, restate.WithName("resolve-citation-commit"))
+		if err != nil {
+			return "", err
+		}
+	}
+	if commit == "" {
+		fmt.Printf("⚠️  No commit to link citations to; writing them without links\n")
+		return "", nil
+	}
+	return strings.ReplaceAll(input.SourceURL, "{commit}", commit), nil
+}
+
+// citationIssues collects the code blocks that did not match the source as
+// quoted, logging each one for the run report
+func citationIssues(chapters []types.WriteChapterOutput) []types.CitationIssue {
+	var issues []types.CitationIssue
+	for _, chapter := range chapters {
+		for _, citation := range chapter.Citations {
+			switch citation.Status {
+			case utils.CitationCorrected:
+				fmt.Printf("  🔧 Chapter %d (%s): code labelled %s differed from the source; replaced with %s:%d-%d\n",
+					chapter.ChapterNumber, chapter.Title, citation.Label, citation.Path, citation.StartLine, citation.EndLine)
+			case utils.CitationInvented:
+				fmt.Printf("  ⚠️  Chapter %d (%s): code labelled %s was not found in the source\n",
+					chapter.ChapterNumber, chapter.Title, citation.Label)
+			default:
+				continue
+			}
+			issues = append(issues, types.CitationIssue{
+				ChapterNumber: chapter.ChapterNumber,
+				Title:         chapter.Title,
+				Citation:      citation,
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,30 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/utils"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// restoreOutputStep is the saga step that undoes chapter files written by a run
+const restoreOutputStep = "restore_output"
+
+// outputCleanup is the saga payload for restoreOutputStep
+type outputCleanup struct {
+	OutputDir string   `json:"output_dir"`
+	RunID     string   `json:"run_id"`
+	Files     []string `json:"files"` // Filenames relative to OutputDir
+}
+
+// restoreOutput removes files written by the run and restores any it replaced
+func restoreOutput(rc restate.RunContext, payload []byte) error {
+	var cleanup outputCleanup
+	if err := json.Unmarshal(payload, &cleanup); err != nil {
+		return restate.TerminalError(fmt.Errorf("invalid cleanup payload: %w", err), 400)
+	}
+
+	fmt.Printf("🧹 Cleaning up %d output files from failed run %s\n", len(cleanup.Files), cleanup.RunID)
+	return utils.RestoreOutput(cleanup.OutputDir, cleanup.RunID, cleanup.Files)
+}
@@ -0,0 +1,94 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/types"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// critiqueChapter has the critic score a new chapter and revises it while
+// the score is below the threshold, up to the revision limit. Every
+// critique is recorded on the chapter.
+func critiqueChapter(ctx restate.WorkflowContext, input types.TutorialWorkflowInput, chapterInput types.WriteChapterInput, chapter types.WriteChapterOutput) (types.WriteChapterOutput, error) {
+	if input.Critic == nil {
+		return chapter, nil
+	}
+	threshold, maxRevisions := config.CriticThreshold(input.Critic), config.CriticMaxRevisions(input.Critic)
+	model := input.Models.ForStage(types.ModelReview)
+
+	chapter.Critiques, chapter.Unrevised = nil, false
+	for revision := 0; ; revision++ {
+		critique, err := CritiqueChapterClient.Call(ctx, types.CritiqueChapterInput{
+			Chapter:          chapter,
+			Abstraction:      chapterInput.Abstraction,
+			Files:            chapterInput.Files,
+			PreviousChapters: chapterInput.PreviousChapters,
+			ProjectName:      chapterInput.ProjectName,
+			Model:            model,
+			PromptsDir:       input.PromptsDir,
+		})
+		if err != nil {
+			return chapter, fmt.Errorf("failed to critique chapter %d: %w", chapter.ChapterNumber, err)
+		}
+		chapter.Critiques = append(chapter.Critiques, critique)
+		if critique.Score >= threshold || revision == maxRevisions {
+			return chapter, nil
+		}
+
+		fmt.Printf("  🧐 Chapter %d scored %.2f (below %.2f); revising (%d/%d)...\n",
+			chapter.ChapterNumber, critique.Score, threshold, revision+1, maxRevisions)
+		revised, err := ReviseChapterClient.Call(ctx, types.ReviseChapterInput{
+			Chapter:     chapter,
+			Critique:    critique,
+			Abstraction: chapterInput.Abstraction,
+			Files:       chapterInput.Files,
+			ProjectName: chapterInput.ProjectName,
+			Model:       model,
+			PromptsDir:  input.PromptsDir,
+			SourceURL:   chapterInput.SourceURL,
+		})
+		if err != nil {
+			return chapter, fmt.Errorf("failed to revise chapter %d: %w", chapter.ChapterNumber, err)
+		}
+		if revised.Unrevised {
+			fmt.Printf("  ⚠️  Revisions of chapter %d kept dropping code blocks; keeping it as written\n", chapter.ChapterNumber)
+			return revised, nil
+		}
+		chapter = revised
+	}
+}
+
+// chapterScores collects the critic's scores per chapter for the run report,
+// logging chapters that stayed below the threshold
+func chapterScores(critic *types.CriticSettings, chapters []types.WriteChapterOutput) []types.ChapterScore {
+	if critic == nil {
+		return nil
+	}
+	threshold := config.CriticThreshold(critic)
+	var scores []types.ChapterScore
+	for _, chapter := range chapters {
+		if len(chapter.Critiques) == 0 {
+			continue
+		}
+		final := chapter.Critiques[len(chapter.Critiques)-1]
+		score := types.ChapterScore{
+			ChapterNumber:  chapter.ChapterNumber,
+			Title:          chapter.Title,
+			Critiques:      chapter.Critiques,
+			Revisions:      len(chapter.Critiques) - 1,
+			Passed:         final.Score >= threshold,
+			RevisionFailed: chapter.Unrevised,
+		}
+		if score.RevisionFailed {
+			fmt.Printf("  ⚠️  Chapter %d (%s) could not be revised: revisions kept dropping code blocks\n", chapter.ChapterNumber, chapter.Title)
+		}
+		if !score.Passed {
+			fmt.Printf("  ⚠️  Chapter %d (%s) scored %.2f after %d revisions, below %.2f\n",
+				chapter.ChapterNumber, chapter.Title, final.Score, score.Revisions, threshold)
+		}
+		scores = append(scores, score)
+	}
+	return scores
+}
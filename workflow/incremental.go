@@ -0,0 +1,289 @@
+package workflow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// defaultRegenerateThreshold is the fraction of changed files above which
+// abstractions are re-analyzed instead of reused from the manifest
+const defaultRegenerateThreshold = 0.3
+
+// loadPreviousManifest reads the manifest left in the output directory by the
+// previous run. Returns nil if there is none.
+func loadPreviousManifest(ctx restate.WorkflowContext, input types.TutorialWorkflowInput) (*types.TutorialManifest, error) {
+	return restate.Run(ctx, func(rc restate.RunContext) (*types.TutorialManifest, error) {
+		return utils.LoadManifest(input.OutputDir)
+	}, restate.WithName("load-manifest"))
+}
+
+// fileChangeRatio returns the fraction of files added, removed or modified
+// since the manifest was written
+func fileChangeRatio(manifest *types.TutorialManifest, files []types.FileContent) float64 {
+	current := make(map[string]string, len(files))
+	for _, file := range files {
+		current[file.Path] = file.Hash
+	}
+
+	changed := 0
+	total := len(current)
+	for path, hash := range current {
+		if previous, ok := manifest.FileHashes[path]; !ok || previous != hash {
+			changed++
+		}
+	}
+	for path := range manifest.FileHashes {
+		if _, ok := current[path]; !ok {
+			changed++
+			total++
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(changed) / float64(total)
+}
+
+// abstractionsFromManifest restores the manifest's abstractions against the
+// current file list, re-resolving file paths to indices. Files that no longer
+// exist are dropped from each abstraction.
+func abstractionsFromManifest(manifest *types.TutorialManifest, files []types.FileContent) []types.Abstraction {
+	abstractions := make([]types.Abstraction, len(manifest.Abstractions))
+	for i, ma := range manifest.Abstractions {
+		abs := ma.Abstraction
+		if len(abs.Files) == 0 {
+			for _, path := range ma.FilePaths {
+				abs.Files = append(abs.Files, types.FileRef{Path: path})
+			}
+		}
+		abs.FileIndices = nil // Stale; the previous run read a different list
+		abstractions[i] = resolveAbstractionFiles(abs, files)
+	}
+	return abstractions
+}
+
+// resolveAbstractionFiles re-resolves an abstraction's file references
+// against files, logging and dropping the ones that match no file
+func resolveAbstractionFiles(abs types.Abstraction, files []types.FileContent) types.Abstraction {
+	resolved, missing := utils.ResolveAbstractionFiles(abs, files)
+	for _, ref := range missing {
+		fmt.Printf("  ⚠️  Abstraction %q references %s, which was not read; dropping the reference\n", abs.Name, ref.Path)
+	}
+	return resolved
+}
+
+// manifestSettingsChanged describes why nothing from the previous run can be
+// reused (a different audience or language), or returns ""
+func manifestSettingsChanged(manifest *types.TutorialManifest, input types.TutorialWorkflowInput) string {
+	if manifest == nil {
+		return ""
+	}
+	if previous, current := config.ResolvePersona(manifest.Audience).Name, config.ResolvePersona(input.Audience).Name; previous != current {
+		return fmt.Sprintf("Previous run was written for audience %q", previous)
+	}
+	if previous := config.LanguageOrDefault(manifest.Language); previous != config.LanguageOrDefault(input.Language) {
+		return fmt.Sprintf("Previous run was written in %s", previous)
+	}
+	return ""
+}
+
+// manifestHonorsSelection reports whether the manifest's abstractions still
+// include every pinned abstraction and none of the blocked ones
+func manifestHonorsSelection(manifest *types.TutorialManifest, pinned []types.PinnedAbstraction, blocked []string) bool {
+	names := make(map[string]bool, len(manifest.Abstractions))
+	for _, ma := range manifest.Abstractions {
+		if utils.IsBlockedConcept(ma.Abstraction.Name, blocked) {
+			return false
+		}
+		names[utils.ConceptKey(ma.Abstraction.Name)] = true
+	}
+	for _, p := range pinned {
+		if !names[utils.ConceptKey(p.Name)] {
+			return false
+		}
+	}
+	return true
+}
+
+// generationSettings hashes the run settings every chapter is generated
+// with: prompt templates, style guide, model routing, critic and checks. A
+// change to any of them invalidates all chapters of the previous run.
+func generationSettings(input types.TutorialWorkflowInput, promptVersions []types.PromptVersion) string {
+	// Only the template text matters, not where it was loaded from
+	digests := make([]string, 0, len(promptVersions))
+	for _, pv := range promptVersions {
+		digests = append(digests, pv.Name+"@"+pv.Digest)
+	}
+	sort.Strings(digests)
+
+	settings, _ := json.Marshal(struct {
+		Prompts      []string              `json:"prompts"`
+		Style        *types.StyleGuide     `json:"style"`
+		Models       types.ModelRouting    `json:"models"`
+		Critic       *types.CriticSettings `json:"critic"`
+		Verification string                `json:"verification"`
+		GoSnippets   string                `json:"go_snippets"`
+		SourceURL    string                `json:"source_url"`
+	}{
+		Prompts:      digests,
+		Style:        input.Style,
+		Models:       input.Models,
+		Critic:       input.Critic,
+		Verification: input.Verification,
+		GoSnippets:   input.GoSnippets,
+		SourceURL:    input.SourceURL,
+	})
+	hash := sha256.Sum256(settings)
+	return hex.EncodeToString(hash[:])
+}
+
+// chapterFingerprint hashes everything a chapter is generated from: its
+// position, the abstraction, the content hashes of its referenced files and
+// the run's generation settings
+func chapterFingerprint(chapterNumber int, abstraction types.Abstraction, files []types.FileContent, settings string) string {
+	var refs []string
+	referenced, _ := utils.LookupFiles(abstraction, files)
+	for _, rf := range referenced {
+		ref := rf.File.Path + "@" + rf.File.Hash
+		if rf.Ref.StartLine > 0 || rf.Ref.Symbol != "" {
+			ref += "#" + utils.DescribeFileRef(rf.Ref)
+		}
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%d\x00%s\x00%s\x00", settings, chapterNumber, abstraction.Name, abstraction.Description)
+	for _, ref := range refs {
+		fmt.Fprintf(hash, "%s\x00", ref)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// reusableChapter returns the previously written content of a chapter whose
+// fingerprint is unchanged, or false if it must be regenerated
+func reusableChapter(
+	ctx restate.WorkflowContext,
+	manifest *types.TutorialManifest,
+	outputDir string,
+	chapterNumber int,
+	fingerprint string,
+) (types.WriteChapterOutput, bool) {
+	if manifest == nil {
+		return types.WriteChapterOutput{}, false
+	}
+
+	for _, mc := range manifest.Chapters {
+		if mc.ChapterNumber != chapterNumber || mc.Fingerprint != fingerprint {
+			continue
+		}
+
+		// A missing or unreadable file just means the chapter is regenerated
+		content, err := restate.Run(ctx, func(rc restate.RunContext) (string, error) {
+			content, err := utils.ReadOutputFile(outputDir, mc.Filename)
+			if err != nil {
+				return "", nil
+			}
+			return content, nil
+		}, restate.WithName(fmt.Sprintf("read-previous-chapter-%d", chapterNumber)))
+		if err != nil || content == "" {
+			return types.WriteChapterOutput{}, false
+		}
+
+		return restoreChapter(mc, content), true
+	}
+	return types.WriteChapterOutput{}, false
+}
+
+// manifestChapter records a chapter and the results of its checks, which
+// are not rerun when the chapter is reused
+func manifestChapter(chapter types.WriteChapterOutput, fingerprint string) types.ManifestChapter {
+	return types.ManifestChapter{
+		ChapterNumber: chapter.ChapterNumber,
+		Title:         chapter.Title,
+		Filename:      utils.ChapterFilename(chapter.ChapterNumber, chapter.Title),
+		Fingerprint:   fingerprint,
+		Summary:       chapter.Summary,
+		Concepts:      chapter.Concepts,
+		Citations:     chapter.Citations,
+		Unverified:    chapter.Unverified,
+		Critiques:     chapter.Critiques,
+		Uncorrected:   chapter.Uncorrected,
+		Unrevised:     chapter.Unrevised,
+	}
+}
+
+// restoreChapter rebuilds a chapter from its manifest entry and the content
+// of its file
+func restoreChapter(mc types.ManifestChapter, content string) types.WriteChapterOutput {
+	return types.WriteChapterOutput{
+		ChapterNumber: mc.ChapterNumber,
+		Title:         mc.Title,
+		Content:       content,
+		Summary:       mc.Summary,
+		Concepts:      mc.Concepts,
+		Citations:     mc.Citations,
+		Unverified:    mc.Unverified,
+		Critiques:     mc.Critiques,
+		Uncorrected:   mc.Uncorrected,
+		Unrevised:     mc.Unrevised,
+	}
+}
+
+// staleChapterFiles returns the chapter files of the previous run that this
+// run does not write, because chapters were renumbered, retitled or dropped
+func staleChapterFiles(previous *types.TutorialManifest, written []string) []string {
+	if previous == nil {
+		return nil
+	}
+	var stale []string
+	for _, mc := range previous.Chapters {
+		if mc.Filename != "" && !slices.Contains(written, mc.Filename) && !slices.Contains(stale, mc.Filename) {
+			stale = append(stale, mc.Filename)
+		}
+	}
+	return stale
+}
+
+// buildManifest records the inputs and outputs of this run for the next one
+func buildManifest(projectName string, input types.TutorialWorkflowInput, state *types.TutorialState) *types.TutorialManifest {
+	settings := generationSettings(input, state.Report.Prompts)
+	manifest := &types.TutorialManifest{
+		ProjectName:   projectName,
+		Commit:        state.Commit,
+		Audience:      config.ResolvePersona(input.Audience).Name,
+		Language:      input.Language,
+		FileHashes:    make(map[string]string, len(state.Files)),
+		Relationships: state.Relationships,
+		ChapterOrder:  state.ChapterOrder,
+	}
+
+	for _, file := range state.Files {
+		manifest.FileHashes[file.Path] = file.Hash
+	}
+
+	for _, abs := range state.Abstractions {
+		abs = resolveAbstractionFiles(abs, state.Files)
+		ma := types.ManifestAbstraction{Abstraction: abs}
+		for _, ref := range abs.Files {
+			ma.FilePaths = append(ma.FilePaths, ref.Path)
+		}
+		manifest.Abstractions = append(manifest.Abstractions, ma)
+	}
+
+	for i, chapter := range state.Chapters {
+		fingerprint := chapterFingerprint(chapter.ChapterNumber, state.Abstractions[state.ChapterOrder[i]], state.Files, settings)
+		manifest.Chapters = append(manifest.Chapters, manifestChapter(chapter, fingerprint))
+	}
+	return manifest
+}
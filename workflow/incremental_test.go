@@ -0,0 +1,143 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// incrementalRun is the state of a finished one-chapter run
+func incrementalRun() (types.TutorialWorkflowInput, *types.TutorialState) {
+	input := types.TutorialWorkflowInput{
+		Verification: "report",
+		GoSnippets:   "report",
+	}
+	state := &types.TutorialState{
+		Report: types.RunReport{
+			Prompts: []types.PromptVersion{
+				{Name: "abstractions", Version: "1", Source: "embedded", Digest: "aaaa"},
+				{Name: "chapter", Version: "1", Source: "embedded", Digest: "bbbb"},
+			},
+		},
+		Files: []types.FileContent{{Index: 0, Path: "router.go", Hash: "f1"}},
+		Abstractions: []types.Abstraction{
+			{Index: 0, Name: "Router", Description: "Dispatches requests", Files: []types.FileRef{{Path: "router.go"}}},
+		},
+		ChapterOrder: []int{0},
+		Chapters:     []types.WriteChapterOutput{{ChapterNumber: 1, Title: "Router", Content: "# Router"}},
+	}
+	return input, state
+}
+
+func TestChapterFingerprintTracksGenerationSettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(input *types.TutorialWorkflowInput, state *types.TutorialState)
+		reuse  bool
+	}{
+		{
+			name:   "nothing changed",
+			change: func(*types.TutorialWorkflowInput, *types.TutorialState) {},
+			reuse:  true,
+		},
+		{
+			name: "prompt override moved but not edited",
+			change: func(_ *types.TutorialWorkflowInput, state *types.TutorialState) {
+				state.Report.Prompts[1].Source = "prompts/chapter.tmpl"
+			},
+			reuse: true,
+		},
+		{
+			name: "chapter prompt edited",
+			change: func(_ *types.TutorialWorkflowInput, state *types.TutorialState) {
+				state.Report.Prompts[1] = types.PromptVersion{Name: "chapter", Version: "1", Source: "prompts/chapter.tmpl", Digest: "cccc"}
+			},
+		},
+		{
+			name: "style guide",
+			change: func(input *types.TutorialWorkflowInput, _ *types.TutorialState) {
+				input.Style = &types.StyleGuide{Tone: "formal"}
+			},
+		},
+		{
+			name: "chapter model",
+			change: func(input *types.TutorialWorkflowInput, _ *types.TutorialState) {
+				input.Models.Chapters = "another/model"
+			},
+		},
+		{
+			name: "critic enabled",
+			change: func(input *types.TutorialWorkflowInput, _ *types.TutorialState) {
+				input.Critic = &types.CriticSettings{Threshold: 4}
+			},
+		},
+		{
+			name: "verification mode",
+			change: func(input *types.TutorialWorkflowInput, _ *types.TutorialState) {
+				input.Verification = "correct"
+			},
+		},
+		{
+			name: "referenced file",
+			change: func(_ *types.TutorialWorkflowInput, state *types.TutorialState) {
+				state.Files[0].Hash = "f2"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, state := incrementalRun()
+			manifest := buildManifest("Project", input, state)
+
+			input, state = incrementalRun()
+			tt.change(&input, state)
+			settings := generationSettings(input, state.Report.Prompts)
+			fingerprint := chapterFingerprint(1, state.Abstractions[0], state.Files, settings)
+
+			if reuse := fingerprint == manifest.Chapters[0].Fingerprint; reuse != tt.reuse {
+				t.Errorf("chapter reused = %v, want %v", reuse, tt.reuse)
+			}
+		})
+	}
+}
+
+func TestReusedChapterKeepsCheckResults(t *testing.T) {
+	chapter := types.WriteChapterOutput{
+		ChapterNumber: 2,
+		Title:         "Router",
+		Content:       "# Router",
+		Citations:     []types.Citation{{Label: "router.go", Status: "invented"}},
+		Unverified:    []types.SymbolReference{{Name: "Dispatch", Where: "prose"}},
+		Critiques:     []types.Critique{{Accuracy: 2, Clarity: 3, Coverage: 3, Novelty: 4, Score: 3}},
+		Summary:       "Routes requests",
+		Concepts:      []string{"route"},
+		Uncorrected:   true,
+		Unrevised:     true,
+	}
+
+	mc := manifestChapter(chapter, "fingerprint")
+	if mc.Filename != "02_router.md" {
+		t.Errorf("filename = %q, want 02_router.md", mc.Filename)
+	}
+	if got := restoreChapter(mc, chapter.Content); !reflect.DeepEqual(got, chapter) {
+		t.Errorf("restored chapter = %+v, want %+v", got, chapter)
+	}
+}
+
+func TestStaleChapterFiles(t *testing.T) {
+	previous := &types.TutorialManifest{Chapters: []types.ManifestChapter{
+		{ChapterNumber: 1, Filename: "01_router.md"},
+		{ChapterNumber: 2, Filename: "02_handler.md"},
+		{ChapterNumber: 3, Filename: "03_store.md"},
+	}}
+	written := []string{"01_router.md", "02_store.md", "index.md", ".cb2utorial-manifest.json"}
+
+	if got, want := staleChapterFiles(previous, written), []string{"02_handler.md", "03_store.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stale files = %q, want %q", got, want)
+	}
+	if got := staleChapterFiles(nil, written); got != nil {
+		t.Errorf("stale files without a previous manifest = %q, want none", got)
+	}
+}
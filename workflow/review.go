@@ -0,0 +1,279 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+	framework "github.com/pithomlabs/rea"
+	restate "github.com/restatedev/sdk-go"
+)
+
+const (
+	// reviewStateKey holds the ReviewProposal exposed to reviewers
+	reviewStateKey = "review"
+	// reviewPromiseName is the durable promise resolved by SubmitReview
+	reviewPromiseName = "review-decision"
+)
+
+// GetReview returns the proposal awaiting review (shared handler)
+func (w TutorialWorkflow) GetReview(ctx restate.WorkflowSharedContext, _ restate.Void) (types.ReviewProposal, error) {
+	return framework.NewReadOnlyWorkflowState[types.ReviewProposal](ctx, reviewStateKey).Get()
+}
+
+// SubmitReview resolves the pending review with a reviewer's decision (shared handler)
+func (w TutorialWorkflow) SubmitReview(ctx restate.WorkflowSharedContext, decision types.ReviewDecision) (restate.Void, error) {
+	proposal, err := framework.NewReadOnlyWorkflowState[types.ReviewProposal](ctx, reviewStateKey).Get()
+	if err != nil {
+		return restate.Void{}, err
+	}
+	if proposal.Status != types.ReviewStatusPending {
+		return restate.Void{}, restate.TerminalError(fmt.Errorf("no review pending (status: %q)", proposal.Status), 409)
+	}
+
+	// Validate edits up front so the reviewer gets the error, not the workflow
+	switch decision.Action {
+	case types.ReviewActionApprove, types.ReviewActionReject:
+	case types.ReviewActionEdit:
+		if _, _, _, err := applyReviewEdits(proposal, decision.Edits); err != nil {
+			return restate.Void{}, restate.TerminalError(fmt.Errorf("invalid edits: %w", err), 400)
+		}
+	default:
+		return restate.Void{}, restate.TerminalError(fmt.Errorf("unknown review action %q", decision.Action), 400)
+	}
+
+	if err := framework.GetInternalSignal[types.ReviewDecision](ctx, reviewPromiseName).Resolve(decision); err != nil {
+		return restate.Void{}, err
+	}
+	return restate.Void{}, nil
+}
+
+// awaitReview publishes the proposal and blocks until a reviewer decides.
+// Returns the (possibly edited) abstractions, relationships and chapter order.
+func awaitReview(
+	ctx restate.WorkflowContext,
+	input types.TutorialWorkflowInput,
+	proposal types.ReviewProposal,
+) ([]types.Abstraction, types.RelationshipData, []int, error) {
+	state := framework.NewMutableWorkflowState[types.ReviewProposal](ctx, reviewStateKey)
+
+	proposal.Status = types.ReviewStatusPending
+	if err := state.Set(proposal); err != nil {
+		return nil, types.RelationshipData{}, nil, err
+	}
+
+	timeout := config.ReviewTimeout(input.ReviewTimeoutMinutes)
+	result, err := framework.RacePromiseWithTimeout[types.ReviewDecision](ctx, reviewPromiseName, timeout)
+	if err != nil {
+		return nil, types.RelationshipData{}, nil, err
+	}
+	if result.TimedOut {
+		proposal.Status = types.ReviewStatusRejected
+		_ = state.Set(proposal)
+		return nil, types.RelationshipData{}, nil, restate.TerminalError(fmt.Errorf("review timed out after %v", timeout), 408)
+	}
+
+	decision := result.Value
+	switch decision.Action {
+	case types.ReviewActionReject:
+		proposal.Status = types.ReviewStatusRejected
+		_ = state.Set(proposal)
+		return nil, types.RelationshipData{}, nil, restate.TerminalError(fmt.Errorf("abstractions rejected by reviewer: %s", decision.Reason), 400)
+
+	case types.ReviewActionEdit:
+		abstractions, relationships, order, err := applyReviewEdits(proposal, decision.Edits)
+		if err != nil {
+			return nil, types.RelationshipData{}, nil, restate.TerminalError(fmt.Errorf("invalid review edits: %w", err), 400)
+		}
+		proposal = types.ReviewProposal{
+			Status:        types.ReviewStatusApproved,
+			Abstractions:  abstractions,
+			Relationships: relationships,
+			ChapterOrder:  order,
+		}
+
+	default:
+		proposal.Status = types.ReviewStatusApproved
+	}
+
+	if err := state.Set(proposal); err != nil {
+		return nil, types.RelationshipData{}, nil, err
+	}
+	return proposal.Abstractions, proposal.Relationships, proposal.ChapterOrder, nil
+}
+
+// applyReviewEdits applies rename/merge/drop/reorder edits to a proposal and
+// re-indexes the surviving abstractions. All edit indices refer to the
+// proposal as shown to the reviewer.
+func applyReviewEdits(
+	proposal types.ReviewProposal,
+	edits []types.ReviewEdit,
+) ([]types.Abstraction, types.RelationshipData, []int, error) {
+	count := len(proposal.Abstractions)
+	working := make([]types.Abstraction, count)
+	copy(working, proposal.Abstractions)
+
+	// target[i] is the proposal index that abstraction i ends up as (-1 = dropped)
+	target := make([]int, count)
+	for i := range target {
+		target[i] = i
+	}
+
+	checkIndex := func(idx int) error {
+		if idx < 0 || idx >= count {
+			return fmt.Errorf("index %d out of range", idx)
+		}
+		if target[idx] != idx {
+			return fmt.Errorf("abstraction %d was already dropped or merged", idx)
+		}
+		return nil
+	}
+
+	var order []int
+	for n, edit := range edits {
+		switch edit.Op {
+		case types.ReviewEditRename:
+			if err := checkIndex(edit.Index); err != nil {
+				return nil, types.RelationshipData{}, nil, fmt.Errorf("edit %d: %w", n, err)
+			}
+			if edit.Name == "" && edit.Description == "" {
+				return nil, types.RelationshipData{}, nil, fmt.Errorf("edit %d: rename requires a name or description", n)
+			}
+			if edit.Name != "" {
+				working[edit.Index].Name = edit.Name
+			}
+			if edit.Description != "" {
+				working[edit.Index].Description = edit.Description
+			}
+
+		case types.ReviewEditDrop:
+			if err := checkIndex(edit.Index); err != nil {
+				return nil, types.RelationshipData{}, nil, fmt.Errorf("edit %d: %w", n, err)
+			}
+			target[edit.Index] = -1
+
+		case types.ReviewEditMerge:
+			if len(edit.Indices) < 2 {
+				return nil, types.RelationshipData{}, nil, fmt.Errorf("edit %d: merge requires at least two indices", n)
+			}
+			keep := edit.Indices[0]
+			for _, idx := range edit.Indices {
+				if err := checkIndex(idx); err != nil {
+					return nil, types.RelationshipData{}, nil, fmt.Errorf("edit %d: %w", n, err)
+				}
+			}
+			for _, idx := range edit.Indices[1:] {
+				if idx == keep {
+					return nil, types.RelationshipData{}, nil, fmt.Errorf("edit %d: duplicate index %d in merge", n, idx)
+				}
+				working[keep].FileIndices = mergeFileIndices(working[keep].FileIndices, working[idx].FileIndices)
+				working[keep].Files = utils.MergeFileRefs(working[keep].Files, working[idx].Files)
+				// Redirect anything already merged into idx as well
+				for i := range target {
+					if target[i] == idx {
+						target[i] = keep
+					}
+				}
+			}
+			if edit.Name != "" {
+				working[keep].Name = edit.Name
+			}
+			if edit.Description != "" {
+				working[keep].Description = edit.Description
+			}
+
+		case types.ReviewEditReorder:
+			order = edit.Indices
+
+		default:
+			return nil, types.RelationshipData{}, nil, fmt.Errorf("edit %d: unknown op %q", n, edit.Op)
+		}
+	}
+
+	// Re-index survivors in their original relative order
+	newIndex := make(map[int]int)
+	var abstractions []types.Abstraction
+	for i := range working {
+		if target[i] != i {
+			continue
+		}
+		newIndex[i] = len(abstractions)
+		abs := working[i]
+		abs.Index = len(abstractions)
+		abstractions = append(abstractions, abs)
+	}
+	if len(abstractions) == 0 {
+		return nil, types.RelationshipData{}, nil, fmt.Errorf("edits leave no abstractions")
+	}
+
+	// resolve maps a proposal index to its new index (false if dropped)
+	resolve := func(idx int) (int, bool) {
+		if idx < 0 || idx >= count || target[idx] < 0 {
+			return 0, false
+		}
+		ni, ok := newIndex[target[idx]]
+		return ni, ok
+	}
+
+	// Chapter order: explicit reorder must list every survivor exactly once
+	var newOrder []int
+	if order != nil {
+		seen := make(map[int]bool)
+		for _, idx := range order {
+			if idx < 0 || idx >= count || target[idx] != idx {
+				return nil, types.RelationshipData{}, nil, fmt.Errorf("reorder: index %d is not a remaining abstraction", idx)
+			}
+			if seen[idx] {
+				return nil, types.RelationshipData{}, nil, fmt.Errorf("reorder: duplicate index %d", idx)
+			}
+			seen[idx] = true
+			newOrder = append(newOrder, newIndex[idx])
+		}
+		if len(newOrder) != len(abstractions) {
+			return nil, types.RelationshipData{}, nil, fmt.Errorf("reorder: expected %d indices, got %d", len(abstractions), len(newOrder))
+		}
+	} else {
+		seen := make(map[int]bool)
+		for _, idx := range proposal.ChapterOrder {
+			if ni, ok := resolve(idx); ok && !seen[ni] {
+				seen[ni] = true
+				newOrder = append(newOrder, ni)
+			}
+		}
+	}
+
+	// Relationships: remap endpoints, dropping self-loops and duplicates
+	relationships := types.RelationshipData{Summary: proposal.Relationships.Summary}
+	seenRel := make(map[[2]int]bool)
+	for _, rel := range proposal.Relationships.Details {
+		from, okFrom := resolve(rel.FromIndex)
+		to, okTo := resolve(rel.ToIndex)
+		if !okFrom || !okTo || from == to || seenRel[[2]int{from, to}] {
+			continue
+		}
+		seenRel[[2]int{from, to}] = true
+		relationships.Details = append(relationships.Details, types.Relationship{
+			FromIndex: from,
+			ToIndex:   to,
+			Label:     rel.Label,
+		})
+	}
+
+	return abstractions, relationships, newOrder, nil
+}
+
+// mergeFileIndices returns the sorted union of two file index lists
+func mergeFileIndices(a, b []int) []int {
+	seen := make(map[int]bool)
+	var merged []int
+	for _, idx := range append(append([]int{}, a...), b...) {
+		if !seen[idx] {
+			seen[idx] = true
+			merged = append(merged, idx)
+		}
+	}
+	sort.Ints(merged)
+	return merged
+}
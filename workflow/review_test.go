@@ -0,0 +1,200 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+)
+
+// reviewProposal has four abstractions in a cycle of relationships, ordered
+// last to first
+func reviewProposal() types.ReviewProposal {
+	abstraction := func(i int, name string, path string) types.Abstraction {
+		return types.Abstraction{Index: i, Name: name, FileIndices: []int{i}, Files: []types.FileRef{{Path: path}}}
+	}
+	return types.ReviewProposal{
+		Abstractions: []types.Abstraction{
+			abstraction(0, "Router", "router.go"),
+			abstraction(1, "Handler", "handler.go"),
+			abstraction(2, "Middleware", "middleware.go"),
+			abstraction(3, "Store", "store.go"),
+		},
+		Relationships: types.RelationshipData{
+			Summary: "summary",
+			Details: []types.Relationship{
+				{FromIndex: 0, ToIndex: 1, Label: "dispatches"},
+				{FromIndex: 1, ToIndex: 2, Label: "wraps"},
+				{FromIndex: 2, ToIndex: 3, Label: "reads"},
+				{FromIndex: 3, ToIndex: 0, Label: "configures"},
+			},
+		},
+		ChapterOrder: []int{3, 2, 1, 0},
+	}
+}
+
+func TestApplyReviewEdits(t *testing.T) {
+	tests := []struct {
+		name          string
+		edits         []types.ReviewEdit
+		names         []string
+		relationships []types.Relationship
+		order         []int
+	}{
+		{
+			name:  "no edits",
+			names: []string{"Router", "Handler", "Middleware", "Store"},
+			relationships: []types.Relationship{
+				{FromIndex: 0, ToIndex: 1, Label: "dispatches"},
+				{FromIndex: 1, ToIndex: 2, Label: "wraps"},
+				{FromIndex: 2, ToIndex: 3, Label: "reads"},
+				{FromIndex: 3, ToIndex: 0, Label: "configures"},
+			},
+			order: []int{3, 2, 1, 0},
+		},
+		{
+			name:  "rename",
+			edits: []types.ReviewEdit{{Op: types.ReviewEditRename, Index: 2, Name: "Chain"}},
+			names: []string{"Router", "Handler", "Chain", "Store"},
+			relationships: []types.Relationship{
+				{FromIndex: 0, ToIndex: 1, Label: "dispatches"},
+				{FromIndex: 1, ToIndex: 2, Label: "wraps"},
+				{FromIndex: 2, ToIndex: 3, Label: "reads"},
+				{FromIndex: 3, ToIndex: 0, Label: "configures"},
+			},
+			order: []int{3, 2, 1, 0},
+		},
+		{
+			name:  "drop remaps later indices",
+			edits: []types.ReviewEdit{{Op: types.ReviewEditDrop, Index: 1}},
+			names: []string{"Router", "Middleware", "Store"},
+			relationships: []types.Relationship{
+				{FromIndex: 1, ToIndex: 2, Label: "reads"},
+				{FromIndex: 2, ToIndex: 0, Label: "configures"},
+			},
+			order: []int{2, 1, 0},
+		},
+		{
+			name:  "merge rewrites relationships onto the kept abstraction",
+			edits: []types.ReviewEdit{{Op: types.ReviewEditMerge, Indices: []int{0, 2}, Name: "Routing"}},
+			names: []string{"Routing", "Handler", "Store"},
+			relationships: []types.Relationship{
+				{FromIndex: 0, ToIndex: 1, Label: "dispatches"},
+				{FromIndex: 1, ToIndex: 0, Label: "wraps"},
+				{FromIndex: 0, ToIndex: 2, Label: "reads"},
+				{FromIndex: 2, ToIndex: 0, Label: "configures"},
+			},
+			order: []int{2, 0, 1},
+		},
+		{
+			name: "merge drops self-loops and duplicates",
+			edits: []types.ReviewEdit{
+				{Op: types.ReviewEditMerge, Indices: []int{0, 1}},
+				{Op: types.ReviewEditMerge, Indices: []int{2, 3}},
+			},
+			names: []string{"Router", "Middleware"},
+			relationships: []types.Relationship{
+				{FromIndex: 0, ToIndex: 1, Label: "wraps"},
+				{FromIndex: 1, ToIndex: 0, Label: "configures"},
+			},
+			order: []int{1, 0},
+		},
+		{
+			name: "reorder uses proposal indices",
+			edits: []types.ReviewEdit{
+				{Op: types.ReviewEditDrop, Index: 0},
+				{Op: types.ReviewEditReorder, Indices: []int{1, 3, 2}},
+			},
+			names: []string{"Handler", "Middleware", "Store"},
+			relationships: []types.Relationship{
+				{FromIndex: 0, ToIndex: 1, Label: "wraps"},
+				{FromIndex: 1, ToIndex: 2, Label: "reads"},
+			},
+			order: []int{0, 2, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			abstractions, relationships, order, err := applyReviewEdits(reviewProposal(), tt.edits)
+			if err != nil {
+				t.Fatalf("applyReviewEdits: %v", err)
+			}
+			var names []string
+			for i, abs := range abstractions {
+				if abs.Index != i {
+					t.Errorf("abstraction %q has index %d, want %d", abs.Name, abs.Index, i)
+				}
+				names = append(names, abs.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("names = %q, want %q", names, tt.names)
+			}
+			if relationships.Summary != "summary" {
+				t.Errorf("summary = %q, want it kept", relationships.Summary)
+			}
+			if !reflect.DeepEqual(relationships.Details, tt.relationships) {
+				t.Errorf("relationships = %+v, want %+v", relationships.Details, tt.relationships)
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("order = %v, want %v", order, tt.order)
+			}
+		})
+	}
+}
+
+func TestApplyReviewEditsMergesFiles(t *testing.T) {
+	edits := []types.ReviewEdit{{Op: types.ReviewEditMerge, Indices: []int{3, 0}}}
+	abstractions, _, _, err := applyReviewEdits(reviewProposal(), edits)
+	if err != nil {
+		t.Fatalf("applyReviewEdits: %v", err)
+	}
+	store := abstractions[2]
+	if want := []int{0, 3}; !reflect.DeepEqual(store.FileIndices, want) {
+		t.Errorf("file indices = %v, want %v", store.FileIndices, want)
+	}
+	if want := []types.FileRef{{Path: "router.go"}, {Path: "store.go"}}; !reflect.DeepEqual(store.Files, want) {
+		t.Errorf("files = %+v, want %+v", store.Files, want)
+	}
+}
+
+func TestApplyReviewEditsRejectsInvalidEdits(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []types.ReviewEdit
+	}{
+		{name: "index out of range", edits: []types.ReviewEdit{{Op: types.ReviewEditDrop, Index: 4}}},
+		{name: "rename without name", edits: []types.ReviewEdit{{Op: types.ReviewEditRename, Index: 0}}},
+		{name: "drop twice", edits: []types.ReviewEdit{
+			{Op: types.ReviewEditDrop, Index: 1},
+			{Op: types.ReviewEditDrop, Index: 1},
+		}},
+		{name: "rename merged", edits: []types.ReviewEdit{
+			{Op: types.ReviewEditMerge, Indices: []int{0, 1}},
+			{Op: types.ReviewEditRename, Index: 1, Name: "Gone"},
+		}},
+		{name: "merge one index", edits: []types.ReviewEdit{{Op: types.ReviewEditMerge, Indices: []int{2}}}},
+		{name: "merge duplicate index", edits: []types.ReviewEdit{{Op: types.ReviewEditMerge, Indices: []int{2, 2}}}},
+		{name: "reorder missing survivor", edits: []types.ReviewEdit{{Op: types.ReviewEditReorder, Indices: []int{0, 1, 2}}}},
+		{name: "reorder dropped index", edits: []types.ReviewEdit{
+			{Op: types.ReviewEditDrop, Index: 3},
+			{Op: types.ReviewEditReorder, Indices: []int{0, 1, 3}},
+		}},
+		{name: "reorder duplicate", edits: []types.ReviewEdit{{Op: types.ReviewEditReorder, Indices: []int{0, 1, 2, 2}}}},
+		{name: "drop everything", edits: []types.ReviewEdit{
+			{Op: types.ReviewEditDrop, Index: 0},
+			{Op: types.ReviewEditDrop, Index: 1},
+			{Op: types.ReviewEditDrop, Index: 2},
+			{Op: types.ReviewEditDrop, Index: 3},
+		}},
+		{name: "unknown op", edits: []types.ReviewEdit{{Op: "split", Index: 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := applyReviewEdits(reviewProposal(), tt.edits); err == nil {
+				t.Error("applyReviewEdits succeeded, want an error")
+			}
+		})
+	}
+}
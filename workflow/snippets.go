@@ -0,0 +1,30 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+)
+
+// snippetIssues parses every chapter's Go code blocks, logging and returning
+// those that do not parse for the run report
+func snippetIssues(mode string, chapters []types.WriteChapterOutput) []types.SnippetIssue {
+	if config.GoSnippetsMode(mode) == config.GoSnippetsOff {
+		return nil
+	}
+	var issues []types.SnippetIssue
+	for _, chapter := range chapters {
+		for _, problem := range utils.CheckGoSnippets(chapter.Content) {
+			fmt.Printf("  ⚠️  Chapter %d (%s): go code block %d does not parse (line %d: %s)\n",
+				chapter.ChapterNumber, chapter.Title, problem.Block, problem.Line, problem.Error)
+			issues = append(issues, types.SnippetIssue{
+				ChapterNumber: chapter.ChapterNumber,
+				Title:         chapter.Title,
+				SnippetError:  problem,
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,66 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+)
+
+// needsRewrite reports whether a chapter kept from the previous run must be
+// rewritten because it breaks the style guide (as localized by chapterStyle)
+// or has Go code that does not parse, and rewrites are enabled for that problem
+func needsRewrite(input types.TutorialWorkflowInput, style *types.StyleGuide, chapter types.WriteChapterOutput) bool {
+	if config.MaxRegenerations(style) > 0 && len(utils.CheckStyle(chapter.Content, *style)) > 0 {
+		return true
+	}
+	return config.SnippetRegenerations(input.GoSnippets) > 0 && len(utils.CheckGoSnippets(chapter.Content)) > 0
+}
+
+// styleSections returns the required headings of a style guide, if any
+func styleSections(style *types.StyleGuide) []string {
+	if style == nil {
+		return nil
+	}
+	return style.Sections
+}
+
+// chapterStyle returns the style guide chapters are written and checked
+// against. Chapters in another language use headings in that language, so
+// the required sections are replaced by their translations.
+func chapterStyle(style *types.StyleGuide, localized *types.LocalizedAnalysis) *types.StyleGuide {
+	if style == nil || localized == nil || len(style.Sections) == 0 {
+		return style
+	}
+	if len(localized.Sections) != len(style.Sections) {
+		// Analysis translated without the headings; they cannot be matched
+		fmt.Printf("  ⚠️  Section headings were not translated into %s; not checking sections\n", localized.Language)
+		unchecked := *style
+		unchecked.Sections = nil
+		return &unchecked
+	}
+	translated := *style
+	translated.Sections = localized.Sections
+	return &translated
+}
+
+// styleIssues checks every chapter against the style guide, logging and
+// returning the violations for the run report
+func styleIssues(style *types.StyleGuide, chapters []types.WriteChapterOutput) []types.StyleIssue {
+	if style == nil {
+		return nil
+	}
+	var issues []types.StyleIssue
+	for _, chapter := range chapters {
+		for _, violation := range utils.CheckStyle(chapter.Content, *style) {
+			fmt.Printf("  ⚠️  Chapter %d (%s) breaks the style guide: %s\n", chapter.ChapterNumber, chapter.Title, violation.Detail)
+			issues = append(issues, types.StyleIssue{
+				ChapterNumber:  chapter.ChapterNumber,
+				Title:          chapter.Title,
+				StyleViolation: violation,
+			})
+		}
+	}
+	return issues
+}
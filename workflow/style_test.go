@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
+)
+
+func TestChapterStyleMatchesTranslatedSections(t *testing.T) {
+	style := &types.StyleGuide{Sections: []string{"What It Does", "Common Pitfalls"}}
+	chapter := "# Enrutador\n\nIntroducción.\n\n## Qué hace\n\nTexto.\n\n## Errores comunes\n\nTexto.\n"
+
+	tests := []struct {
+		name      string
+		localized *types.LocalizedAnalysis
+		rules     []string
+	}{
+		{
+			name:  "English headings against a Spanish chapter",
+			rules: []string{utils.StyleMissingSection, utils.StyleMissingSection},
+		},
+		{
+			name:      "translated headings",
+			localized: &types.LocalizedAnalysis{Language: "Spanish", Sections: []string{"Qué hace", "Errores comunes"}},
+		},
+		{
+			name:      "analysis translated without headings skips the section check",
+			localized: &types.LocalizedAnalysis{Language: "Spanish"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []string
+			for _, violation := range utils.CheckStyle(chapter, *chapterStyle(style, tt.localized)) {
+				rules = append(rules, violation.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("violations = %q, want %q", rules, tt.rules)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(style.Sections, []string{"What It Does", "Common Pitfalls"}) {
+		t.Errorf("chapterStyle modified the configured style guide: %q", style.Sections)
+	}
+}
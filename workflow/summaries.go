@@ -0,0 +1,38 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// summarizeChapter records the summary and concepts later chapters receive
+// as context. Chapters that already have a summary keep it.
+func summarizeChapter(ctx restate.WorkflowContext, input types.TutorialWorkflowInput, projectName string, chapter types.WriteChapterOutput) (types.WriteChapterOutput, error) {
+	if chapter.Summary != "" {
+		return chapter, nil
+	}
+	summary, err := SummarizeChapterClient.Call(ctx, types.SummarizeChapterInput{
+		Chapter:     chapter,
+		ProjectName: projectName,
+		Model:       input.Models.ForStage(types.StageChapters),
+		PromptsDir:  input.PromptsDir,
+	})
+	if err != nil {
+		return chapter, fmt.Errorf("failed to summarize chapter %d: %w", chapter.ChapterNumber, err)
+	}
+	chapter.Summary = summary.Summary
+	chapter.Concepts = summary.Concepts
+	return chapter, nil
+}
+
+// chapterSummary is the context later chapters get about a written chapter
+func chapterSummary(chapter types.WriteChapterOutput) types.ChapterSummary {
+	return types.ChapterSummary{
+		ChapterNumber: chapter.ChapterNumber,
+		Name:          chapter.Title,
+		Summary:       chapter.Summary,
+		Concepts:      chapter.Concepts,
+	}
+}
@@ -0,0 +1,91 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/types"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// translateVariants produces each additional language of the tutorial: the
+// analysis is translated once, then every written chapter. Progress is
+// checkpointed per chapter, so a resumed run continues where it stopped.
+func translateVariants(ctx restate.WorkflowContext, tracker *runTracker, input types.TutorialWorkflowInput, projectName string) error {
+	state := tracker.state()
+	model := input.Models.ForStage(types.ModelTranslation)
+
+	for i, language := range input.Languages {
+		if i == len(state.Variants) {
+			fmt.Printf("🌐 Translating analysis into %s (calling LLM)...\n", language)
+			localized, err := TranslateAnalysisClient.Call(ctx, types.TranslateAnalysisInput{
+				Abstractions:  state.Abstractions,
+				Relationships: state.Relationships,
+				ProjectName:   projectName,
+				Language:      language,
+				Model:         model,
+				PromptsDir:    input.PromptsDir,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to translate analysis into %s: %w", language, err)
+			}
+			state.Variants = append(state.Variants, types.LanguageVariant{LocalizedAnalysis: localized})
+			if err := tracker.checkpoint(""); err != nil {
+				return err
+			}
+		}
+
+		variant := &state.Variants[i]
+		for j := len(variant.Chapters); j < len(state.Chapters); j++ {
+			fmt.Printf("  🌐 Translating chapter %d/%d into %s...\n", j+1, len(state.Chapters), language)
+			chapter, err := TranslateChapterClient.Call(ctx, types.TranslateChapterInput{
+				Chapter:    state.Chapters[j],
+				Title:      variant.Abstractions[state.ChapterOrder[j]].Name,
+				Language:   language,
+				Model:      model,
+				PromptsDir: input.PromptsDir,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to translate chapter %d into %s: %w", j+1, language, err)
+			}
+			if chapter.Untranslated {
+				fmt.Printf("  ⚠️  Translations of chapter %d kept dropping code blocks; leaving it untranslated\n", j+1)
+			}
+			variant.Chapters = append(variant.Chapters, chapter)
+			if err := tracker.checkpoint(""); err != nil {
+				return err
+			}
+		}
+	}
+	state.Report.Untranslated = untranslatedIssues(state.Variants)
+	return nil
+}
+
+// untranslatedIssues collects the variant chapters published in the original
+// language for the run report
+func untranslatedIssues(variants []types.LanguageVariant) []types.UntranslatedIssue {
+	var issues []types.UntranslatedIssue
+	for _, variant := range variants {
+		for _, chapter := range variant.Chapters {
+			if chapter.Untranslated {
+				issues = append(issues, types.UntranslatedIssue{
+					Language:      variant.Language,
+					ChapterNumber: chapter.ChapterNumber,
+					Title:         chapter.Title,
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// variantsWritten maps each language variant to the files written for it
+func variantsWritten(state *types.TutorialState) map[string][]string {
+	if len(state.Variants) == 0 {
+		return nil
+	}
+	written := make(map[string][]string, len(state.Variants))
+	for _, variant := range state.Variants {
+		written[variant.Language] = variant.FilesWritten
+	}
+	return written
+}
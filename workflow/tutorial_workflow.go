@@ -2,10 +2,13 @@ package workflow
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
-	"strings"
 
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/prompts"
 	"github.com/pithomlabs/cb2utorial/types"
+	"github.com/pithomlabs/cb2utorial/utils"
 	framework "github.com/pithomlabs/rea"
 	restate "github.com/restatedev/sdk-go"
 )
@@ -37,6 +40,41 @@ var (
 		HandlerName: "WriteChapter",
 	}
 
+	TranslateAnalysisClient = framework.ServiceClient[types.TranslateAnalysisInput, types.LocalizedAnalysis]{
+		ServiceName: "Translator",
+		HandlerName: "TranslateAnalysis",
+	}
+
+	TranslateChapterClient = framework.ServiceClient[types.TranslateChapterInput, types.WriteChapterOutput]{
+		ServiceName: "Translator",
+		HandlerName: "TranslateChapter",
+	}
+
+	SummarizeChapterClient = framework.ServiceClient[types.SummarizeChapterInput, types.ChapterSummary]{
+		ServiceName: "ChapterWriter",
+		HandlerName: "SummarizeChapter",
+	}
+
+	CritiqueChapterClient = framework.ServiceClient[types.CritiqueChapterInput, types.Critique]{
+		ServiceName: "ChapterCritic",
+		HandlerName: "CritiqueChapter",
+	}
+
+	ReviseChapterClient = framework.ServiceClient[types.ReviseChapterInput, types.WriteChapterOutput]{
+		ServiceName: "ChapterCritic",
+		HandlerName: "ReviseChapter",
+	}
+
+	VerifyChapterClient = framework.ServiceClient[types.VerifyChapterInput, types.VerifyChapterOutput]{
+		ServiceName: "Verifier",
+		HandlerName: "VerifyChapter",
+	}
+
+	CorrectChapterClient = framework.ServiceClient[types.CorrectChapterInput, types.WriteChapterOutput]{
+		ServiceName: "Verifier",
+		HandlerName: "CorrectChapter",
+	}
+
 	FileWriterClient = framework.ServiceClient[types.WriteMarkdownFilesInput, types.WriteMarkdownFilesOutput]{
 		ServiceName: "FileWriter",
 		HandlerName: "WriteMarkdownFiles",
@@ -52,142 +90,517 @@ func (w TutorialWorkflow) ServiceName() string {
 }
 
 // Run executes the complete workflow using rea framework service clients
-func (w TutorialWorkflow) Run(ctx restate.WorkflowContext, input types.TutorialWorkflowInput) (types.WriteMarkdownFilesOutput, error) {
+func (w TutorialWorkflow) Run(ctx restate.WorkflowContext, input types.TutorialWorkflowInput) (output types.TutorialWorkflowOutput, err error) {
+	// Saga removes (or restores) output written by this run on terminal failure
+	saga := framework.NewSaga(ctx, "tutorial-output", nil)
+	saga.Register(restoreOutputStep, framework.ValidateCompensationIdempotent(restoreOutputStep, restoreOutput))
+	defer func() {
+		if restate.IsTerminalError(err) {
+			saga.CompensateIfNeeded(&err)
+		}
+	}()
+
+	// Track stage outputs; a resumed run is seeded from its predecessor
+	tracker, input, err := newRunTracker(ctx, input)
+	if err != nil {
+		return types.TutorialWorkflowOutput{}, err
+	}
+	state := tracker.state()
+
 	// Log workflow start
 	fmt.Printf("🚀 Starting TutorialWorkflow for repo: %s\n", input.LocalRepoPath)
+	if input.GitRef != "" {
+		fmt.Printf("📌 Reading files at git ref %s\n", input.GitRef)
+	}
+
+	// Fill unset fields from the repository's cb2utorial.yaml; hooks are
+	// not part of the input and stay with the CLI
+	projectFile, err := restate.Run(ctx, func(rc restate.RunContext) (*config.File, error) {
+		if info, err := os.Stat(input.LocalRepoPath); err != nil || !info.IsDir() {
+			return nil, nil // Archives and module specs carry no config file
+		}
+		file, err := config.Discover(input.LocalRepoPath)
+		if err != nil {
+			return nil, restate.TerminalError(err, 400)
+		}
+		return file, nil
+	}, restate.WithName("load-project-config"))
+	if err != nil {
+		return types.TutorialWorkflowOutput{}, err
+	}
+	if projectFile != nil {
+		fmt.Printf("⚙️  Using %s from the repository\n", config.FileName)
+		config.ApplyFile(&input, projectFile)
+	}
 
 	// Derive project name from path if not provided
 	projectName := input.ProjectName
 	if projectName == "" {
-		projectName = filepath.Base(input.LocalRepoPath)
+		projectName = utils.SourceName(input.LocalRepoPath)
 		if projectName == "." || projectName == "/" {
 			projectName = "Project"
 		}
 	}
+	tracker.artifact.ProjectName = projectName
 
-	// Get configuration from environment or use defaults
-	maxFiles := input.MaxFiles
-	if maxFiles == 0 {
-		maxFiles = 100
+	// Fill unset file selection fields from the preset and built-in defaults
+	if err := config.ApplyDefaults(&input); err != nil {
+		return types.TutorialWorkflowOutput{}, restate.TerminalError(err, 400)
 	}
-
-	// Step 1: Read Files
-	fmt.Printf("📁 Step 1/6: Reading files from %s...\n", input.LocalRepoPath)
-	fileReaderInput := types.ReadFilesInput{
-		RepoPath:        input.LocalRepoPath,
-		IncludePatterns: []string{"*.go", "*.py", "*.js", "*.ts", "*.java", "*.rb", "*.md"},
-		ExcludePatterns: []string{"*_test.go", "vendor/*", "node_modules/*", ".git/*", "*.min.js"},
-		MaxFileSize:     1048576, // 1MB
-		MaxFiles:        maxFiles,
+	if err := config.Validate(input); err != nil {
+		return types.TutorialWorkflowOutput{}, restate.TerminalError(err, 400)
 	}
 
-	filesOutput, err := FileReaderClient.Call(ctx, fileReaderInput)
+	// Check the prompt templates up front and record their versions; a
+	// broken override fails the run before any LLM call
+	promptVersions, err := restate.Run(ctx, func(rc restate.RunContext) ([]types.PromptVersion, error) {
+		versions, err := prompts.Versions(input.PromptsDir)
+		if err != nil {
+			return nil, restate.TerminalError(err, 400)
+		}
+		return versions, nil
+	}, restate.WithName("load-prompts"))
 	if err != nil {
-		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("failed to read files: %w", err)
+		return types.TutorialWorkflowOutput{}, err
 	}
-
-	if len(filesOutput.Files) == 0 {
-		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("no files found in repository")
+	if input.PromptsDir != "" {
+		fmt.Printf("📝 Using prompt templates from %s\n", input.PromptsDir)
 	}
-	fmt.Printf("✅ Found %d files\n", len(filesOutput.Files))
+	state.Report.Prompts = promptVersions
 
-	// Step 2: Identify Abstractions
-	fmt.Printf("🔍 Step 2/6: Analyzing code abstractions (calling LLM)...\n")
-	abstractionInput := types.AnalyzeAbstractionsInput{
-		Files:           filesOutput.Files,
-		ProjectName:     projectName,
-		MaxAbstractions: 10,
+	// Step 1: Read Files
+	if tracker.reused(types.StageFiles) {
+		fmt.Printf("📁 Step 1/6: Reusing %d files from run %s\n", len(state.Files), input.ResumeRunID)
+	} else {
+		fmt.Printf("📁 Step 1/6: Reading files from %s...\n", input.LocalRepoPath)
+		fileReaderInput := types.ReadFilesInput{
+			RepoPath:        input.LocalRepoPath,
+			IncludePatterns: input.IncludePatterns,
+			ExcludePatterns: input.ExcludePatterns,
+			MaxFileSize:     input.MaxFileSize,
+			MaxFiles:        input.MaxFiles,
+			GitRef:          input.GitRef,
+			RedactionRules:  input.RedactionRules,
+		}
+
+		filesOutput, err := FileReaderClient.Call(ctx, fileReaderInput)
+		if err != nil {
+			return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to read files: %w", err)
+		}
+
+		if len(filesOutput.Files) == 0 {
+			return types.TutorialWorkflowOutput{}, fmt.Errorf("no files found in repository")
+		}
+		fmt.Printf("✅ Found %d files\n", len(filesOutput.Files))
+		if len(filesOutput.SkipTotals) > 0 {
+			fmt.Printf("⏭️  Skipped files: %s\n", utils.FormatSkipTotals(filesOutput.SkipTotals))
+		}
+		if filesOutput.Commit != "" {
+			fmt.Printf("📌 Resolved %s to commit %s\n", input.GitRef, filesOutput.Commit)
+		}
+
+		state.Files = filesOutput.Files
+		state.Commit = filesOutput.Commit
+		state.Report.FilesRead = len(filesOutput.Files)
+		state.Report.Skipped = filesOutput.Skipped
+		state.Report.SkipTotals = filesOutput.SkipTotals
+		state.Report.Redactions = filesOutput.Redactions
+		if len(filesOutput.Redactions) > 0 {
+			fmt.Printf("🔒 Redacted %d secrets before sending content to the LLM\n", len(filesOutput.Redactions))
+		}
+		if err := tracker.checkpoint(types.StageFiles); err != nil {
+			return types.TutorialWorkflowOutput{}, err
+		}
 	}
 
-	abstractionsOutput, err := AbstractionAnalyzerClient.Call(ctx, abstractionInput)
+	// Compare against the previous run's manifest: if few files changed,
+	// keep its abstractions, relationships and order
+	previousManifest, err := loadPreviousManifest(ctx, input)
 	if err != nil {
-		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("failed to analyze abstractions: %w", err)
+		return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to load manifest: %w", err)
+	}
+	manifest := previousManifest
+	if input.ForceRegenerate {
+		manifest = nil
+	}
+	// A tutorial for another audience or language shares nothing with this one
+	if reason := manifestSettingsChanged(manifest, input); reason != "" {
+		fmt.Printf("🔎 %s; regenerating everything\n", reason)
+		manifest = nil
+	}
+	reuseAnalysis := false
+	if manifest != nil && len(manifest.Abstractions) > 0 && !tracker.reused(types.StageAbstractions) {
+		threshold := input.RegenerateThreshold
+		if threshold <= 0 {
+			threshold = defaultRegenerateThreshold
+		}
+		ratio := fileChangeRatio(manifest, state.Files)
+		reuseAnalysis = ratio <= threshold
+		fmt.Printf("🔎 %.0f%% of files changed since the last run (threshold %.0f%%)\n", ratio*100, threshold*100)
+		if reuseAnalysis && !manifestHonorsSelection(manifest, input.PinnedAbstractions, input.BlockedAbstractions) {
+			fmt.Printf("🔎 Pinned or blocked abstractions changed; re-analyzing\n")
+			reuseAnalysis = false
+		}
 	}
 
-	if len(abstractionsOutput.Abstractions) == 0 {
-		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("no abstractions identified")
+	// Step 2: Identify Abstractions
+	if tracker.reused(types.StageAbstractions) {
+		fmt.Printf("🔍 Step 2/6: Reusing %d abstractions\n", len(state.Abstractions))
+		// Artifacts from before path references get them from their indices
+		for i, abs := range state.Abstractions {
+			state.Abstractions[i] = resolveAbstractionFiles(abs, state.Files)
+		}
+	} else if reuseAnalysis {
+		state.Abstractions = abstractionsFromManifest(manifest, state.Files)
+		fmt.Printf("🔍 Step 2/6: Keeping %d abstractions from the previous run\n", len(state.Abstractions))
+		if err := tracker.checkpoint(types.StageAbstractions); err != nil {
+			return types.TutorialWorkflowOutput{}, err
+		}
+	} else {
+		fmt.Printf("🔍 Step 2/6: Analyzing code abstractions (calling LLM)...\n")
+		abstractionInput := types.AnalyzeAbstractionsInput{
+			Files:           state.Files,
+			ProjectName:     projectName,
+			MaxAbstractions: input.MaxAbstractions,
+			Pinned:          input.PinnedAbstractions,
+			Blocked:         input.BlockedAbstractions,
+			Audience:        input.Audience,
+			Model:           input.Models.ForStage(types.StageAbstractions),
+			PromptsDir:      input.PromptsDir,
+		}
+
+		abstractionsOutput, err := AbstractionAnalyzerClient.Call(ctx, abstractionInput)
+		if err != nil {
+			return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to analyze abstractions: %w", err)
+		}
+
+		if len(abstractionsOutput.Abstractions) == 0 {
+			return types.TutorialWorkflowOutput{}, fmt.Errorf("no abstractions identified")
+		}
+		fmt.Printf("✅ Identified %d abstractions\n", len(abstractionsOutput.Abstractions))
+
+		state.Abstractions = abstractionsOutput.Abstractions
+		if err := tracker.checkpoint(types.StageAbstractions); err != nil {
+			return types.TutorialWorkflowOutput{}, err
+		}
 	}
-	fmt.Printf("✅ Identified %d abstractions\n", len(abstractionsOutput.Abstractions))
 
 	// Step 3: Analyze Relationships
-	fmt.Printf("🔗 Step 3/6: Analyzing relationships (calling LLM)...\n")
-	relationshipInput := types.AnalyzeRelationshipsInput{
-		Abstractions: abstractionsOutput.Abstractions,
-		Files:        filesOutput.Files,
-		ProjectName:  projectName,
-	}
+	if tracker.reused(types.StageRelationships) {
+		fmt.Printf("🔗 Step 3/6: Reusing relationships\n")
+	} else if reuseAnalysis {
+		fmt.Printf("🔗 Step 3/6: Keeping relationships from the previous run\n")
+		state.Relationships = manifest.Relationships
+		if err := tracker.checkpoint(types.StageRelationships); err != nil {
+			return types.TutorialWorkflowOutput{}, err
+		}
+	} else {
+		fmt.Printf("🔗 Step 3/6: Analyzing relationships (calling LLM)...\n")
+		relationshipInput := types.AnalyzeRelationshipsInput{
+			Abstractions: state.Abstractions,
+			Files:        state.Files,
+			ProjectName:  projectName,
+			Model:        input.Models.ForStage(types.StageRelationships),
+			PromptsDir:   input.PromptsDir,
+		}
 
-	relationships, err := RelationshipAnalyzerClient.Call(ctx, relationshipInput)
-	if err != nil {
-		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("failed to analyze relationships: %w", err)
+		relationships, err := RelationshipAnalyzerClient.Call(ctx, relationshipInput)
+		if err != nil {
+			return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to analyze relationships: %w", err)
+		}
+		fmt.Printf("✅ Mapped relationships\n")
+
+		state.Relationships = relationships
+		if err := tracker.checkpoint(types.StageRelationships); err != nil {
+			return types.TutorialWorkflowOutput{}, err
+		}
 	}
-	fmt.Printf("✅ Mapped relationships\n")
 
 	// Step 4: Order Chapters
-	fmt.Printf("📋 Step 4/6: Ordering chapters (calling LLM)...\n")
-	orderInput := types.OrderChaptersInput{
-		Abstractions:  abstractionsOutput.Abstractions,
-		Relationships: relationships,
-		ProjectName:   projectName,
-	}
+	if tracker.reused(types.StageOrder) {
+		fmt.Printf("📋 Step 4/6: Reusing chapter order\n")
+	} else if reuseAnalysis {
+		fmt.Printf("📋 Step 4/6: Keeping chapter order from the previous run\n")
+		state.ChapterOrder = manifest.ChapterOrder
+	} else {
+		fmt.Printf("📋 Step 4/6: Ordering chapters (calling LLM)...\n")
+		orderInput := types.OrderChaptersInput{
+			Abstractions:  state.Abstractions,
+			Relationships: state.Relationships,
+			ProjectName:   projectName,
+			Audience:      input.Audience,
+			Model:         input.Models.ForStage(types.StageOrder),
+			PromptsDir:    input.PromptsDir,
+		}
 
-	orderOutput, err := ChapterOrdererClient.Call(ctx, orderInput)
-	if err != nil {
-		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("failed to order chapters: %w", err)
+		orderOutput, err := ChapterOrdererClient.Call(ctx, orderInput)
+		if err != nil {
+			return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to order chapters: %w", err)
+		}
+		fmt.Printf("✅ Chapter order determined\n")
+		state.ChapterOrder = orderOutput.OrderedIndices
 	}
-	fmt.Printf("✅ Chapter order determined\n")
-
-	// Step 5: Write Chapters (sequentially - parallel can use RequestFuture later)
-	fmt.Printf("✍️  Step 5/6: Generating %d chapters (calling LLM for each)...\n", len(orderOutput.OrderedIndices))
-	chapters := make([]types.WriteChapterOutput, len(orderOutput.OrderedIndices))
-	previousChapters := []types.ChapterSummary{}
-
-	for i, absIndex := range orderOutput.OrderedIndices {
-		abstraction := abstractionsOutput.Abstractions[absIndex]
+	if !tracker.reused(types.StageOrder) {
+		// Optional human review gate before spending LLM calls on chapters,
+		// whether the analysis is new or kept from the previous run
+		if input.ReviewAbstractions {
+			var err error
+			fmt.Printf("🧑 Awaiting human review of %d abstractions (workflow %s)...\n", len(state.Abstractions), restate.Key(ctx))
+			state.Abstractions, state.Relationships, state.ChapterOrder, err = awaitReview(ctx, input, types.ReviewProposal{
+				Abstractions:  state.Abstractions,
+				Relationships: state.Relationships,
+				ChapterOrder:  state.ChapterOrder,
+			})
+			if err != nil {
+				return types.TutorialWorkflowOutput{}, fmt.Errorf("abstraction review failed: %w", err)
+			}
+			fmt.Printf("✅ Review approved: %d abstractions\n", len(state.Abstractions))
+		}
 
-		fmt.Printf("  📝 Writing chapter %d/%d: %s...\n", i+1, len(orderOutput.OrderedIndices), abstraction.Name)
-		chapterInput := types.WriteChapterInput{
-			Abstraction:      abstraction,
-			Files:            filesOutput.Files,
-			PreviousChapters: previousChapters,
-			ProjectName:      projectName,
-			ChapterNumber:    i + 1,
+		if err := tracker.checkpoint(types.StageOrder); err != nil {
+			return types.TutorialWorkflowOutput{}, err
 		}
+	}
 
-		chapterOutput, err := ChapterWriterClient.Call(ctx, chapterInput)
+	// Chapters in another language are written from a translated analysis
+	if !config.IsDefaultLanguage(input.Language) && state.Localized == nil && !tracker.reused(types.StageChapters) {
+		fmt.Printf("🌐 Translating analysis into %s (calling LLM)...\n", input.Language)
+		localized, err := TranslateAnalysisClient.Call(ctx, types.TranslateAnalysisInput{
+			Abstractions:  state.Abstractions,
+			Relationships: state.Relationships,
+			Sections:      styleSections(input.Style),
+			ProjectName:   projectName,
+			Language:      input.Language,
+			Model:         input.Models.ForStage(types.ModelTranslation),
+			PromptsDir:    input.PromptsDir,
+		})
 		if err != nil {
-			return types.WriteMarkdownFilesOutput{}, fmt.Errorf("failed to write chapter %d: %w", i+1, err)
+			return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to translate analysis: %w", err)
 		}
+		state.Localized = &localized
+		if err := tracker.checkpoint(""); err != nil {
+			return types.TutorialWorkflowOutput{}, err
+		}
+	}
+	chapterAbstractions := state.Abstractions
+	if state.Localized != nil {
+		chapterAbstractions = state.Localized.Abstractions
+	}
+	style := chapterStyle(input.Style, state.Localized)
 
-		chapters[i] = chapterOutput
+	// Citations link to the source at the commit the files were read from
+	sourceURL, err := resolveSourceURL(ctx, input, state.Commit)
+	if err != nil {
+		return types.TutorialWorkflowOutput{}, err
+	}
 
-		// Add to previous chapters context (summary = first 200 chars)
-		summary := chapterOutput.Content
-		if len(summary) > 200 {
-			summary = summary[:200] + "..."
+	// Step 5: Write Chapters (sequentially - parallel can use RequestFuture later)
+	if tracker.reused(types.StageChapters) {
+		fmt.Printf("✍️  Step 5/6: Reusing %d chapters\n", len(state.Chapters))
+	} else {
+		fmt.Printf("✍️  Step 5/6: Generating %d chapters (calling LLM for each)...\n", len(state.ChapterOrder))
+		previousChapters := []types.ChapterSummary{}
+		settings := generationSettings(input, state.Report.Prompts)
+
+		for i, absIndex := range state.ChapterOrder {
+			abstraction := state.Abstractions[absIndex]
+
+			// Chapters completed before a resume are kept as-is
+			var chapterOutput types.WriteChapterOutput
+			fingerprint := chapterFingerprint(i+1, abstraction, state.Files, settings)
+			if i < len(state.Chapters) {
+				fmt.Printf("  📝 Reusing chapter %d/%d: %s\n", i+1, len(state.ChapterOrder), abstraction.Name)
+				chapterOutput = state.Chapters[i]
+			} else if previous, ok := reusableChapter(ctx, manifest, input.OutputDir, i+1, fingerprint); ok && !needsRewrite(input, style, previous) {
+				// Referenced files are unchanged since the last run
+				fmt.Printf("  📝 Unchanged chapter %d/%d: %s\n", i+1, len(state.ChapterOrder), abstraction.Name)
+				chapterOutput = previous
+				state.Chapters = append(state.Chapters, chapterOutput)
+				if err := tracker.checkpoint(""); err != nil {
+					return types.TutorialWorkflowOutput{}, err
+				}
+			} else {
+				fmt.Printf("  📝 Writing chapter %d/%d: %s...\n", i+1, len(state.ChapterOrder), abstraction.Name)
+				chapterInput := types.WriteChapterInput{
+					Abstraction:      chapterAbstractions[absIndex],
+					Files:            state.Files,
+					PreviousChapters: previousChapters,
+					ProjectName:      projectName,
+					ChapterNumber:    i + 1,
+					Audience:         input.Audience,
+					Language:         input.Language,
+					Model:            input.Models.ForStage(types.StageChapters),
+					PromptsDir:       input.PromptsDir,
+					Style:            style,
+					SourceURL:        sourceURL,
+				}
+
+				chapterOutput, err = ChapterWriterClient.Call(ctx, chapterInput)
+				if err != nil {
+					return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to write chapter %d: %w", i+1, err)
+				}
+
+				// Rewrite a chapter that breaks the style guide or has Go code
+				// that does not parse, telling the writer what was wrong
+				styleAttempts, snippetAttempts := config.MaxRegenerations(style), config.SnippetRegenerations(input.GoSnippets)
+				for attempt := 1; attempt <= max(styleAttempts, snippetAttempts); attempt++ {
+					chapterInput.StyleViolations, chapterInput.SnippetErrors = nil, nil
+					if attempt <= styleAttempts {
+						chapterInput.StyleViolations = utils.CheckStyle(chapterOutput.Content, *style)
+					}
+					if attempt <= snippetAttempts {
+						chapterInput.SnippetErrors = utils.CheckGoSnippets(chapterOutput.Content)
+					}
+					if len(chapterInput.StyleViolations) == 0 && len(chapterInput.SnippetErrors) == 0 {
+						break
+					}
+					if len(chapterInput.StyleViolations) > 0 {
+						fmt.Printf("  🎨 Chapter %d breaks the style guide (%d problems); rewriting (%d/%d)...\n",
+							i+1, len(chapterInput.StyleViolations), attempt, styleAttempts)
+					}
+					if len(chapterInput.SnippetErrors) > 0 {
+						fmt.Printf("  🧩 Chapter %d has %d Go code blocks that do not parse; rewriting (%d/%d)...\n",
+							i+1, len(chapterInput.SnippetErrors), attempt, snippetAttempts)
+					}
+					chapterOutput, err = ChapterWriterClient.Call(ctx, chapterInput)
+					if err != nil {
+						return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to rewrite chapter %d: %w", i+1, err)
+					}
+				}
+
+				// Have the critic score the chapter and revise it if it falls short
+				chapterOutput, err = critiqueChapter(ctx, input, chapterInput, chapterOutput)
+				if err != nil {
+					return types.TutorialWorkflowOutput{}, err
+				}
+
+				// Check the symbols the chapter mentions exist in the repository
+				chapterOutput, err = verifyChapter(ctx, input, projectName, state.Files, sourceURL, chapterOutput)
+				if err != nil {
+					return types.TutorialWorkflowOutput{}, err
+				}
+
+				// Summarize the final chapter for the ones that follow
+				chapterOutput, err = summarizeChapter(ctx, input, projectName, chapterOutput)
+				if err != nil {
+					return types.TutorialWorkflowOutput{}, err
+				}
+
+				state.Chapters = append(state.Chapters, chapterOutput)
+				if err := tracker.checkpoint(""); err != nil {
+					return types.TutorialWorkflowOutput{}, err
+				}
+			}
+
+			// Reused chapters written before summaries were recorded get one now
+			if chapterOutput.Summary == "" {
+				chapterOutput, err = summarizeChapter(ctx, input, projectName, chapterOutput)
+				if err != nil {
+					return types.TutorialWorkflowOutput{}, err
+				}
+				state.Chapters[i] = chapterOutput
+				if err := tracker.checkpoint(""); err != nil {
+					return types.TutorialWorkflowOutput{}, err
+				}
+			}
+
+			// Add to previous chapters context
+			previousChapters = append(previousChapters, chapterSummary(chapterOutput))
 		}
-		// Remove markdown headers and extra whitespace
-		summary = strings.ReplaceAll(summary, "#", "")
-		summary = strings.TrimSpace(summary)
 
-		previousChapters = append(previousChapters, types.ChapterSummary{
-			Name:    chapterOutput.Title,
-			Summary: summary,
-		})
+		// Chapters that still break the style guide are flagged, not dropped
+		state.Report.Style = styleIssues(style, state.Chapters)
+		state.Report.Citations = citationIssues(state.Chapters)
+		state.Report.Unverified = unverifiedIssues(state.Chapters)
+		state.Report.Snippets = snippetIssues(input.GoSnippets, state.Chapters)
+		state.Report.Scores = chapterScores(input.Critic, state.Chapters)
+
+		// Additional languages are translated from the finished chapters
+		if err := translateVariants(ctx, tracker, input, projectName); err != nil {
+			return types.TutorialWorkflowOutput{}, err
+		}
+
+		if err := tracker.checkpoint(types.StageChapters); err != nil {
+			return types.TutorialWorkflowOutput{}, err
+		}
 	}
 
 	// Step 6: Write Files
+	if tracker.reused(types.StageWrite) {
+		fmt.Printf("💾 Step 6/6: Files already written by run %s\n", input.ResumeRunID)
+		return types.TutorialWorkflowOutput{FilesWritten: state.ChaptersWritten, Commit: state.Commit, Report: state.Report, Variants: variantsWritten(state)}, nil
+	}
+
 	fmt.Printf("💾 Step 6/6: Writing markdown files...\n")
+	summary := state.Relationships.Summary
+	if state.Localized != nil {
+		summary = state.Localized.Relationships.Summary
+	}
+	runID := restate.Key(ctx)
 	writerInput := types.WriteMarkdownFilesInput{
 		OutputDir: input.OutputDir,
-		Chapters:  chapters,
+		Chapters:  state.Chapters,
+		RunID:     runID,
+		Manifest:  buildManifest(projectName, input, state),
+
+		Format:      input.OutputFormat,
+		ProjectName: projectName,
+		Summary:     summary,
+		Variants:    state.Variants,
+	}
+
+	// Register compensation BEFORE writing so a partial write is undone too
+	cleanup := outputCleanup{OutputDir: input.OutputDir, RunID: runID}
+	for _, chapter := range state.Chapters {
+		cleanup.Files = append(cleanup.Files, utils.ChapterFilename(chapter.ChapterNumber, chapter.Title))
+	}
+	if input.OutputFormat == config.FormatMarkdownIndex {
+		cleanup.Files = append(cleanup.Files, utils.IndexFilename)
+	}
+	for _, variant := range state.Variants {
+		dir := utils.VariantDir(variant.Language)
+		for _, chapter := range variant.Chapters {
+			cleanup.Files = append(cleanup.Files, filepath.Join(dir, utils.ChapterFilename(chapter.ChapterNumber, chapter.Title)))
+		}
+		if input.OutputFormat == config.FormatMarkdownIndex {
+			cleanup.Files = append(cleanup.Files, filepath.Join(dir, utils.IndexFilename))
+		}
+	}
+	cleanup.Files = append(cleanup.Files, utils.ManifestFilename)
+
+	// Files of renumbered or retitled chapters are backed up with the rest,
+	// so a failed run restores them too
+	writerInput.Stale = staleChapterFiles(previousManifest, cleanup.Files)
+	cleanup.Files = append(cleanup.Files, writerInput.Stale...)
+	if err := saga.Add(restoreOutputStep, cleanup, true); err != nil {
+		return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to register output cleanup: %w", err)
 	}
 
 	result, err := FileWriterClient.Call(ctx, writerInput)
 	if err != nil {
-		return types.WriteMarkdownFilesOutput{}, fmt.Errorf("failed to write markdown files: %w", err)
+		return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to write markdown files: %w", err)
+	}
+
+	state.ChaptersWritten = result.FilesWritten
+	for i := range state.Variants {
+		state.Variants[i].FilesWritten = result.VariantsWritten[state.Variants[i].Language]
+	}
+	if err := tracker.checkpoint(types.StageWrite); err != nil {
+		return types.TutorialWorkflowOutput{}, err
+	}
+
+	// Run succeeded: the previous version is no longer needed
+	if err := restate.RunVoid(ctx, func(rc restate.RunContext) error {
+		return utils.DiscardBackup(input.OutputDir, runID)
+	}, restate.WithName("discard-output-backup")); err != nil {
+		return types.TutorialWorkflowOutput{}, fmt.Errorf("failed to discard output backup: %w", err)
 	}
 	fmt.Printf("🎉 Tutorial generation complete! %d files written.\n", len(result.FilesWritten))
 
-	return result, nil
+	return types.TutorialWorkflowOutput{
+		FilesWritten: result.FilesWritten,
+		Commit:       state.Commit,
+		Report:       state.Report,
+		Variants:     result.VariantsWritten,
+	}, nil
 }
@@ -0,0 +1,69 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/pithomlabs/cb2utorial/config"
+	"github.com/pithomlabs/cb2utorial/types"
+	restate "github.com/restatedev/sdk-go"
+)
+
+// verifyChapter checks the symbols a new chapter mentions against the
+// repository. In correct mode the LLM fixes unverified references once and
+// the chapter is checked again; whatever remains is recorded on it. Code the
+// correction changes is cited against files, linked with sourceURL.
+func verifyChapter(ctx restate.WorkflowContext, input types.TutorialWorkflowInput, projectName string, files []types.FileContent, sourceURL string, chapter types.WriteChapterOutput) (types.WriteChapterOutput, error) {
+	mode := config.VerificationMode(input.Verification)
+	if mode == config.VerificationOff {
+		return chapter, nil
+	}
+
+	verified, err := VerifyChapterClient.Call(ctx, types.VerifyChapterInput{Chapter: chapter, Files: files})
+	if err != nil {
+		return chapter, fmt.Errorf("failed to verify chapter %d: %w", chapter.ChapterNumber, err)
+	}
+
+	if mode == config.VerificationCorrect && len(verified.Unverified) > 0 {
+		fmt.Printf("  🔎 Chapter %d mentions %d unknown symbols; correcting...\n", chapter.ChapterNumber, len(verified.Unverified))
+		chapter, err = CorrectChapterClient.Call(ctx, types.CorrectChapterInput{
+			Chapter:     chapter,
+			Unverified:  verified.Unverified,
+			Files:       files,
+			ProjectName: projectName,
+			Model:       input.Models.ForStage(types.StageChapters),
+			PromptsDir:  input.PromptsDir,
+			SourceURL:   sourceURL,
+		})
+		if err != nil {
+			return chapter, fmt.Errorf("failed to correct chapter %d: %w", chapter.ChapterNumber, err)
+		}
+		if chapter.Uncorrected {
+			fmt.Printf("  ⚠️  Corrections of chapter %d kept dropping code blocks; keeping it as written\n", chapter.ChapterNumber)
+		}
+		verified, err = VerifyChapterClient.Call(ctx, types.VerifyChapterInput{Chapter: chapter, Files: files})
+		if err != nil {
+			return chapter, fmt.Errorf("failed to verify chapter %d: %w", chapter.ChapterNumber, err)
+		}
+	}
+
+	chapter.Unverified = verified.Unverified
+	return chapter, nil
+}
+
+// unverifiedIssues collects the symbol references left unverified in the
+// chapters, logging each one for the run report
+func unverifiedIssues(chapters []types.WriteChapterOutput) []types.UnverifiedIssue {
+	var issues []types.UnverifiedIssue
+	for _, chapter := range chapters {
+		for _, ref := range chapter.Unverified {
+			fmt.Printf("  ⚠️  Chapter %d (%s) mentions %s, which the repository does not declare\n", chapter.ChapterNumber, chapter.Title, ref.Name)
+			issues = append(issues, types.UnverifiedIssue{
+				ChapterNumber:   chapter.ChapterNumber,
+				Title:           chapter.Title,
+				Uncorrected:     chapter.Uncorrected,
+				SymbolReference: ref,
+			})
+		}
+	}
+	return issues
+}